@@ -1,14 +1,32 @@
 package main
 
 import (
+	"context"
+	"flag"
+	"fmt"
 	"log"
 	"os"
 	"os/signal"
+	"sync"
+	"sync/atomic"
 	"syscall"
+	"time"
 
+	_ "shopease/internal/alerts" // Subscribes to item events for price and stock alerts
+	"shopease/internal/cache"
 	"shopease/internal/config"
 	"shopease/internal/database"
+	"shopease/internal/events"
+	"shopease/internal/health"
+	"shopease/internal/jobs"
+	"shopease/internal/logging"
+	"shopease/internal/notify"
+	"shopease/internal/pricing"
+	"shopease/internal/privacy"
 	"shopease/internal/routes"
+	"shopease/internal/seed"
+	"shopease/internal/server"
+	"shopease/internal/tracing"
 )
 
 // @title ShopEase API
@@ -24,7 +42,36 @@ func main() {
 	log.Println("✅ ShopEase API starting...")
 
 	// Load configuration
-	config.LoadConfig()
+	flags := flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	printConfig := flags.Bool("print-config", false, "print the effective configuration, with secrets redacted, and exit")
+	if err := config.LoadConfig(flags, os.Args[1:]); err != nil {
+		log.Fatalf("Invalid configuration:\n%v", err)
+	}
+	if *printConfig {
+		dump, err := config.AppConfig.Dump()
+		if err != nil {
+			log.Fatalf("Failed to render configuration: %v", err)
+		}
+		fmt.Print(dump)
+		return
+	}
+
+	// Switch to structured logging; log.Printf output goes through the same handler
+	if _, err := logging.Setup(config.AppConfig.LogLevel, config.AppConfig.LogFormat, os.Stdout); err != nil {
+		log.Fatalf("Failed to configure logging: %v", err)
+	}
+
+	// Tracing must be set up before the database so query spans have a provider
+	shutdownTracing, err := tracing.Setup(context.Background(), tracing.Options{
+		Exporter:    config.AppConfig.TracingExporter,
+		FilePath:    config.AppConfig.TracingFile,
+		SampleRatio: config.AppConfig.TracingSampleRatio,
+		ServiceName: "shopease-api",
+		Version:     "1.0",
+	})
+	if err != nil {
+		log.Fatalf("Failed to configure tracing: %v", err)
+	}
 
 	// Connect to database
 	if err := database.Connect(); err != nil {
@@ -38,36 +85,121 @@ func main() {
 	}
 	log.Println("✅ Database migrations completed")
 
-	// Seed initial data
-	if err := database.SeedItems(); err != nil {
-		log.Printf("Warning: Failed to seed items: %v", err)
-	} else {
-		log.Println("✅ Initial items seeded")
+	// Seed a fresh database
+	if result, err := seed.Bootstrap(context.Background(), config.AppConfig.SeedProfile); err != nil {
+		log.Printf("Warning: Failed to seed the database: %v", err)
+	} else if result != nil {
+		log.Printf("✅ Seeded the %s profile (%s)", config.AppConfig.SeedProfile, result)
 	}
 
+	// Start background workers. Emails are sent by the job workers, so the
+	// mailer is set first.
+	mailer, err := notify.NewMailer(config.AppConfig.MailDriver, notify.SMTPMailer{
+		Host:     config.AppConfig.SMTPHost,
+		Port:     config.AppConfig.SMTPPort,
+		Username: config.AppConfig.SMTPUsername,
+		Password: config.AppConfig.SMTPPassword,
+		From:     config.AppConfig.MailFrom,
+	}, config.AppConfig.MailDir)
+	if err != nil {
+		log.Fatalf("Failed to configure mailer: %v", err)
+	}
+	notify.SetMailer(mailer)
+
+	pricing.Start()
+	privacy.Start()
+	jobs.Start(config.AppConfig.JobWorkers)
+	events.Start()
+
+	registerHealthChecks()
+
+	cache.Catalogue = cache.NewLRU(config.AppConfig.CatalogueCacheSize)
+
 	// Setup router
 	router := routes.SetupRouter()
 	log.Println("✅ Routes configured")
 
-	// Graceful shutdown handling
+	// Stop on SIGINT/SIGTERM. Readiness fails straight away so load balancers
+	// stop routing here while in-flight requests drain.
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
 	go func() {
-		quit := make(chan os.Signal, 1)
-		signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
-		<-quit
-
-		log.Println("\n🛑 Shutting down server...")
-		if err := database.Close(); err != nil {
-			log.Printf("Error closing database: %v", err)
-		}
-		log.Println("👋 Server stopped gracefully")
-		os.Exit(0)
+		<-ctx.Done()
+		log.Println("🛑 Shutting down server...")
+		health.SetShuttingDown(true)
 	}()
 
+	// Serve metrics on their own address if configured, so they needn't be exposed publicly
+	// A listener failing before shutdown was asked for, e.g. a port already in
+	// use, stops the other server too and makes the process exit non-zero
+	drain := server.ShutdownTimeout()
+	var servers sync.WaitGroup
+	var failed atomic.Bool
+	if addr := config.AppConfig.MetricsAddr; addr != "" {
+		servers.Add(1)
+		go func() {
+			defer servers.Done()
+			log.Printf("📈 Metrics available on %s/metrics", addr)
+			if err := server.ListenAndServe(ctx, server.New(addr, routes.SetupMetricsRouter()), drain); err != nil {
+				log.Printf("Error serving metrics: %v", err)
+				if ctx.Err() == nil {
+					failed.Store(true)
+					stop()
+				}
+			}
+		}()
+	}
+
 	// Start server
 	addr := ":" + config.AppConfig.Port
 	log.Printf("🚀 Server running on http://localhost%s", addr)
 
-	if err := router.Run(addr); err != nil {
-		log.Fatalf("Failed to start server: %v", err)
+	if err := server.ListenAndServe(ctx, server.New(addr, router), drain); err != nil {
+		log.Printf("Error serving API: %v", err)
+		if ctx.Err() == nil {
+			failed.Store(true)
+		}
+	}
+	stop()
+	servers.Wait()
+
+	// Requests have finished, so nothing can enqueue more work. Stop the
+	// workers, letting jobs in progress finish and flushing in-memory queues,
+	// then export the last spans and close the database.
+	pricing.Stop()
+	privacy.Stop()
+	jobs.Stop()
+	events.Stop()
+
+	flushCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	if err := shutdownTracing(flushCtx); err != nil {
+		log.Printf("Error flushing traces: %v", err)
+	}
+	cancel()
+	if err := database.Close(); err != nil {
+		log.Printf("Error closing database: %v", err)
+	}
+
+	if failed.Load() {
+		log.Println("❌ Server stopped after a listener failed")
+		os.Exit(1)
+	}
+	log.Println("👋 Server stopped gracefully")
+}
+
+// registerHealthChecks wires the readiness probes for the database and background workers
+func registerHealthChecks() {
+	sqlDB, err := database.DB.DB()
+	if err != nil {
+		log.Fatalf("Failed to get database handle: %v", err)
+	}
+
+	health.Register("database", health.DatabaseCheck(sqlDB), 2*time.Second)
+	health.Register("schema", health.SchemaVersionCheck(database.CurrentSchemaVersion, database.SchemaVersion), 2*time.Second)
+	if config.AppConfig.DBPath != ":memory:" {
+		minFree := uint64(config.AppConfig.HealthMinFreeMB) << 20
+		health.Register("disk", health.DiskSpaceCheck(config.AppConfig.DBPath, minFree), time.Second)
 	}
+	health.Register("event_dispatcher", health.HeartbeatCheck(&events.Heartbeat, time.Minute), 0)
+	health.Register("job_worker", health.HeartbeatCheck(&jobs.Heartbeat, time.Minute), 0)
 }
@@ -0,0 +1,560 @@
+// Command shopctl runs operational tasks against the ShopEase database.
+//
+// It reads the same configuration as the server (config file, environment and
+// flags), so it operates on whatever database the server is configured for:
+//
+//	shopctl [config flags] <command> [arguments]
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"shopease/internal/admin"
+	"shopease/internal/audit"
+	"shopease/internal/catalog"
+	"shopease/internal/config"
+	"shopease/internal/database"
+	"shopease/internal/logging"
+	"shopease/internal/models"
+	"shopease/internal/privacy"
+	"shopease/internal/seed"
+)
+
+// command is a shopctl subcommand
+type command struct {
+	usage   string
+	summary string
+	run     func(args []string) error
+
+	offline  bool // Doesn't need the database
+	migrated bool // Refuses to run against a database this build hasn't migrated
+}
+
+var commands = map[string]command{
+	"create-admin": {
+		usage:    "create-admin -username NAME [-email EMAIL] [-password PASS]",
+		summary:  "Create an administrator account",
+		run:      createAdmin,
+		migrated: true,
+	},
+	"reset-session": {
+		usage:    "reset-session (USERNAME | -all)",
+		summary:  "Clear session tokens so users can log in again",
+		run:      resetSession,
+		migrated: true,
+	},
+	"reset-password": {
+		usage:    "reset-password USERNAME [-password PASS]",
+		summary:  "Set a user's password and end their session",
+		run:      resetPassword,
+		migrated: true,
+	},
+	"orders": {
+		usage:    "orders (list [-status S] [-user NAME] [-limit N] | set-status ID STATUS)",
+		summary:  "List orders or change an order's status",
+		run:      orders,
+		migrated: true,
+	},
+	"purge-accounts": {
+		usage:    "purge-accounts",
+		summary:  "Permanently remove deleted accounts whose grace period has ended",
+		run:      purgeAccounts,
+		migrated: true,
+	},
+	"seed": {
+		usage:    "seed [-profile P] [-fake-users N] [-fake-items N] [-fake-orders N] [-fake-seed S] [FILE...]",
+		summary:  "Upsert users, items and orders from a seed profile, fixture files or generated data",
+		run:      seedCmd,
+		migrated: true,
+	},
+	"catalog": {
+		usage:    "catalog (export [-o FILE] [-format F] | import FILE [-format F] [-mode M] [-dry-run])",
+		summary:  "Export or import the catalogue as JSON, CSV or NDJSON",
+		run:      catalogCmd,
+		migrated: true,
+	},
+	"migrate": {
+		usage:   "migrate",
+		summary: "Run database migrations",
+		run:     migrate,
+	},
+	"audit": {
+		usage:    "audit verify",
+		summary:  "Check the audit log's hash chain for modified or removed events",
+		run:      auditCmd,
+		migrated: true,
+	},
+	"schema": {
+		usage:   "schema status",
+		summary: "Show schema version, applied migrations and table sizes",
+		run:     schema,
+	},
+	"config": {
+		usage:   "config",
+		summary: "Print the effective configuration with secrets redacted",
+		run:     printConfig,
+		offline: true,
+	},
+}
+
+func main() {
+	flags := flag.NewFlagSet("shopctl", flag.ExitOnError)
+	flags.Usage = func() { usage(flags) }
+	if err := config.LoadConfig(flags, os.Args[1:]); err != nil {
+		log.Fatalf("Invalid configuration:\n%v", err)
+	}
+
+	args := flags.Args()
+	if len(args) == 0 {
+		usage(flags)
+		os.Exit(2)
+	}
+	cmd, ok := commands[args[0]]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "shopctl: unknown command %q\n\n", args[0])
+		usage(flags)
+		os.Exit(2)
+	}
+
+	// Keep stdout for command output; logs go to stderr
+	if _, err := logging.Setup(config.AppConfig.LogLevel, "text", os.Stderr); err != nil {
+		log.Fatalf("Failed to configure logging: %v", err)
+	}
+
+	// Attribute audited changes to whoever ran shopctl
+	actor := audit.System("shopctl")
+	if name := os.Getenv("USER"); name != "" {
+		actor = audit.System("shopctl:" + name)
+	}
+	admin.Actor, seed.Actor, privacy.Actor = actor, actor, actor
+
+	if err := run(cmd, args[1:]); err != nil {
+		fmt.Fprintf(os.Stderr, "shopctl %s: %v\n", args[0], err)
+		os.Exit(1)
+	}
+}
+
+// run connects to the database and runs cmd
+func run(cmd command, args []string) error {
+	if cmd.offline {
+		return cmd.run(args)
+	}
+
+	if err := database.Connect(); err != nil {
+		return fmt.Errorf("connecting to database: %w", err)
+	}
+	defer database.Close()
+
+	if cmd.migrated {
+		version, err := database.CurrentSchemaVersion(context.Background())
+		if err != nil || version != database.SchemaVersion {
+			return fmt.Errorf("database schema is at version %d, this build needs %d; run \"shopctl migrate\" first", version, database.SchemaVersion)
+		}
+	}
+
+	return cmd.run(args)
+}
+
+func usage(flags *flag.FlagSet) {
+	fmt.Fprintln(os.Stderr, "Usage: shopctl [config flags] <command> [arguments]\n\nCommands:")
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(os.Stderr, "  %-16s %s\n      shopctl %s\n", name, commands[name].summary, commands[name].usage)
+	}
+	fmt.Fprintln(os.Stderr, "\nConfig flags (each also settable via environment or --config file):")
+	flags.PrintDefaults()
+}
+
+func createAdmin(args []string) error {
+	fs := flag.NewFlagSet("create-admin", flag.ExitOnError)
+	username := fs.String("username", "", "username (required)")
+	email := fs.String("email", "", "email address")
+	password := fs.String("password", "", "password (prompted for if omitted)")
+	fs.Parse(args)
+
+	if *username == "" {
+		return errors.New("-username is required")
+	}
+	if *password == "" {
+		var err error
+		if *password, err = readPassword(); err != nil {
+			return err
+		}
+	}
+
+	user, err := admin.CreateAdmin(*username, *email, *password)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stdout, "Created admin %s (id %d)\n", user.Username, user.ID)
+	return nil
+}
+
+func resetSession(args []string) error {
+	fs := flag.NewFlagSet("reset-session", flag.ExitOnError)
+	all := fs.Bool("all", false, "clear every user's session")
+	fs.Parse(args)
+
+	if *all {
+		count, err := admin.ResetAllSessions()
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stdout, "Cleared %d active session(s)\n", count)
+		return nil
+	}
+
+	if fs.NArg() != 1 {
+		return errors.New("expected a username or -all")
+	}
+	if err := admin.ResetSession(fs.Arg(0)); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stdout, "Cleared session for %s\n", fs.Arg(0))
+	return nil
+}
+
+func resetPassword(args []string) error {
+	fs := flag.NewFlagSet("reset-password", flag.ExitOnError)
+	password := fs.String("password", "", "new password (prompted for if omitted)")
+	fs.Parse(reorder(args))
+
+	if fs.NArg() != 1 {
+		return errors.New("expected a username")
+	}
+	if *password == "" {
+		var err error
+		if *password, err = readPassword(); err != nil {
+			return err
+		}
+	}
+
+	if err := admin.ResetPassword(fs.Arg(0), *password); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stdout, "Password reset for %s; their session has been ended\n", fs.Arg(0))
+	return nil
+}
+
+func orders(args []string) error {
+	if len(args) == 0 {
+		return errors.New("expected list or set-status")
+	}
+
+	switch args[0] {
+	case "list":
+		fs := flag.NewFlagSet("orders list", flag.ExitOnError)
+		status := fs.String("status", "", "only orders with this status")
+		username := fs.String("user", "", "only orders placed by this username")
+		limit := fs.Int("limit", 50, "maximum number of orders (0 for all)")
+		fs.Parse(args[1:])
+
+		filter := admin.OrderFilter{Username: *username, Limit: *limit}
+		if *status != "" {
+			s, ok := models.ParseOrderStatus(*status)
+			if !ok {
+				return fmt.Errorf("invalid order status %q", *status)
+			}
+			filter.Status = s
+		}
+
+		list, err := admin.ListOrders(filter)
+		if err != nil {
+			return err
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "ID\tUSER\tSTATUS\tITEMS\tTOTAL\tCREATED")
+		for _, order := range list {
+			username := ""
+			if order.User != nil {
+				username = order.User.Username
+			}
+			fmt.Fprintf(w, "%d\t%s\t%s\t%d\t%.2f\t%s\n", order.ID, username, order.Status, len(order.OrderItems), order.TotalAmount, order.CreatedAt.Format("2006-01-02 15:04"))
+		}
+		return w.Flush()
+
+	case "set-status":
+		if len(args) != 3 {
+			return errors.New("expected set-status ID STATUS")
+		}
+		id, err := strconv.ParseUint(args[1], 10, 32)
+		if err != nil {
+			return fmt.Errorf("invalid order ID %q", args[1])
+		}
+
+		order, err := admin.SetOrderStatus(uint(id), args[2])
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stdout, "Order %d is now %s\n", order.ID, args[2])
+		return nil
+	}
+
+	return fmt.Errorf("unknown orders subcommand %q", args[0])
+}
+
+// seedCmd is the seed command; the name avoids shadowing the seed package
+func seedCmd(args []string) error {
+	fs := flag.NewFlagSet("seed", flag.ExitOnError)
+	profile := fs.String("profile", "", "built-in profile: "+strings.Join(seed.ProfileNames(), ", ")+" (default SEED_PROFILE, unless files or fake data are given)")
+	fake := seed.FakeOptions{}
+	fs.IntVar(&fake.Users, "fake-users", 0, "generate this many users")
+	fs.IntVar(&fake.Items, "fake-items", 0, "generate this many items")
+	fs.IntVar(&fake.Orders, "fake-orders", 0, "generate this many orders from the generated users and items")
+	fs.Int64Var(&fake.Seed, "fake-seed", 1, "random seed; the same seed and counts give the same data")
+	fs.Parse(reorder(args))
+
+	generate := fake.Users > 0 || fake.Items > 0 || fake.Orders > 0
+	if *profile == "" && fs.NArg() == 0 && !generate {
+		*profile = config.AppConfig.SeedProfile
+		if *profile == "none" {
+			return errors.New("SEED_PROFILE is none; pass -profile, fixture files or -fake-* counts")
+		}
+	}
+
+	var fixtures []*seed.Fixture
+	if *profile != "" {
+		p, ok := seed.Profiles[*profile]
+		if !ok {
+			return fmt.Errorf("unknown profile %q (want one of %s)", *profile, strings.Join(seed.ProfileNames(), ", "))
+		}
+		loaded, err := p.Fixtures()
+		if err != nil {
+			return err
+		}
+		fixtures = append(fixtures, loaded...)
+	}
+	for _, path := range fs.Args() {
+		fixture, err := seed.LoadFile(path)
+		if err != nil {
+			return err
+		}
+		fixtures = append(fixtures, fixture)
+	}
+	if generate {
+		fixture, err := seed.Fake(fake)
+		if err != nil {
+			return err
+		}
+		fixtures = append(fixtures, fixture)
+	}
+
+	result, err := seed.Apply(context.Background(), fixtures...)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stdout, "Seeded %s\n", result)
+	return nil
+}
+
+// catalogCmd is the catalog command; the name avoids shadowing the catalog package
+func catalogCmd(args []string) error {
+	if len(args) == 0 {
+		return errors.New("expected export or import")
+	}
+
+	switch args[0] {
+	case "export":
+		fs := flag.NewFlagSet("catalog export", flag.ExitOnError)
+		output := fs.String("o", "-", "output file, or - for stdout")
+		formatName := fs.String("format", "", "json, csv or ndjson (default from the -o extension, else json)")
+		fs.Parse(args[1:])
+
+		format, err := fileFormat(*formatName, *output)
+		if err != nil {
+			return err
+		}
+
+		w := io.Writer(os.Stdout)
+		if *output != "-" {
+			f, err := os.Create(*output)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			w = f
+		}
+
+		count, err := catalog.Export(context.Background(), w, format)
+		if err != nil {
+			return err
+		}
+		log.Printf("Exported %d item(s)", count)
+		return nil
+
+	case "import":
+		fs := flag.NewFlagSet("catalog import", flag.ExitOnError)
+		formatName := fs.String("format", "", "json, csv or ndjson (default from the file extension, else json)")
+		mode := fs.String("mode", string(catalog.ModeAtomic), "all_or_nothing or best_effort")
+		dryRun := fs.Bool("dry-run", false, "validate without saving")
+		fs.Parse(reorder(args[1:]))
+
+		if fs.NArg() != 1 {
+			return errors.New("expected import FILE (or - for stdin)")
+		}
+		format, err := fileFormat(*formatName, fs.Arg(0))
+		if err != nil {
+			return err
+		}
+
+		r := io.Reader(os.Stdin)
+		if fs.Arg(0) != "-" {
+			f, err := os.Open(fs.Arg(0))
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			r = f
+		}
+
+		report, err := catalog.Import(context.Background(), r, catalog.Options{Format: format, Mode: catalog.Mode(*mode), DryRun: *dryRun, Actor: admin.Actor})
+		if err != nil {
+			if report != nil {
+				fmt.Fprintln(os.Stderr, "Import stopped part way; rows before the failing batch were committed")
+			}
+			return err
+		}
+		for _, rowErr := range report.Errors {
+			fmt.Fprintf(os.Stderr, "row %d: %s\n", rowErr.Row, strings.Join(rowErr.Errors, "; "))
+		}
+
+		state := "Imported"
+		switch {
+		case report.DryRun:
+			state = "Dry run:"
+		case !report.Applied:
+			state = "Nothing imported:"
+		}
+		fmt.Fprintf(os.Stdout, "%s %d row(s), %d created, %d updated, %d failed\n", state, report.Rows, report.Created, report.Updated, report.Failed)
+		if report.Failed > 0 && !report.Applied && !report.DryRun {
+			return errors.New("import rejected; fix the rows above or use -mode best_effort")
+		}
+		return nil
+	}
+
+	return fmt.Errorf("unknown catalog subcommand %q", args[0])
+}
+
+// fileFormat returns the named catalogue format, or guesses it from the file extension
+func fileFormat(name, path string) (catalog.Format, error) {
+	if name == "" {
+		switch ext := filepath.Ext(path); ext {
+		case ".csv", ".ndjson", ".jsonl":
+			name = ext[1:]
+		default:
+			name = "json"
+		}
+	}
+	return catalog.ParseFormat(name)
+}
+
+func purgeAccounts(args []string) error {
+	purged, err := privacy.Purge(context.Background(), time.Now())
+	fmt.Fprintf(os.Stdout, "Purged %d deleted accounts\n", purged)
+	return err
+}
+
+func migrate(args []string) error {
+	if err := database.Migrate(); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stdout, "Database is at schema version %d\n", database.SchemaVersion)
+	return nil
+}
+
+func schema(args []string) error {
+	if len(args) != 1 || args[0] != "status" {
+		return errors.New("expected schema status")
+	}
+
+	status, err := admin.GetSchemaStatus(context.Background())
+	if err != nil {
+		return err
+	}
+
+	state := "up to date"
+	if !status.UpToDate() {
+		state = "needs migration"
+	}
+	fmt.Fprintf(os.Stdout, "Schema version: %d (this build expects %d, %s)\n", status.Current, status.Expected, state)
+	for _, applied := range status.Applied {
+		fmt.Fprintf(os.Stdout, "  applied v%d at %s\n", applied.Version, applied.AppliedAt.Format("2006-01-02 15:04:05"))
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "\nTABLE\tROWS")
+	for _, name := range status.TableNames() {
+		fmt.Fprintf(w, "%s\t%d\n", name, status.Tables[name])
+	}
+	return w.Flush()
+}
+
+func auditCmd(args []string) error {
+	if len(args) != 1 || args[0] != "verify" {
+		return errors.New("expected audit verify")
+	}
+
+	result, err := audit.Verify(database.DB)
+	if err != nil {
+		return err
+	}
+	if !result.Valid {
+		return fmt.Errorf("event %d: %s (%d events checked)", *result.BrokenAt, result.Problem, result.Events)
+	}
+	fmt.Fprintf(os.Stdout, "Audit log intact: %d events, head %s\n", result.Events, result.Head)
+	return nil
+}
+
+func printConfig(args []string) error {
+	dump, err := config.AppConfig.Dump()
+	if err != nil {
+		return err
+	}
+	fmt.Fprint(os.Stdout, dump)
+	return nil
+}
+
+// readPassword reads a password from the first line of stdin
+func readPassword() (string, error) {
+	fmt.Fprint(os.Stderr, "Password: ")
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && line == "" {
+		return "", errors.New("no password given")
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+// reorder moves flags ahead of positional arguments so "USERNAME -password x" parses
+func reorder(args []string) []string {
+	var flags, positional []string
+	for i := 0; i < len(args); i++ {
+		if strings.HasPrefix(args[i], "-") {
+			flags = append(flags, args[i])
+			if !strings.Contains(args[i], "=") && i+1 < len(args) {
+				flags = append(flags, args[i+1])
+				i++
+			}
+			continue
+		}
+		positional = append(positional, args[i])
+	}
+	return append(flags, positional...)
+}
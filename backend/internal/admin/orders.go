@@ -0,0 +1,88 @@
+package admin
+
+import (
+	"errors"
+	"fmt"
+
+	"shopease/internal/audit"
+	"shopease/internal/database"
+	"shopease/internal/events"
+	"shopease/internal/models"
+	"shopease/internal/notify"
+
+	"gorm.io/gorm"
+)
+
+// OrderFilter narrows ListOrders; zero values match everything
+type OrderFilter struct {
+	Status   models.OrderStatus
+	Username string
+	Limit    int
+}
+
+// ListOrders returns matching orders with their owners, newest first
+func ListOrders(filter OrderFilter) ([]models.Order, error) {
+	query := database.DB.Preload("User").Preload("OrderItems").Order("created_at DESC")
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+	if filter.Username != "" {
+		query = query.Where("user_id IN (?)", database.DB.Model(&models.User{}).Select("id").Where("username = ?", filter.Username))
+	}
+	if filter.Limit > 0 {
+		query = query.Limit(filter.Limit)
+	}
+
+	var orders []models.Order
+	err := query.Find(&orders).Error
+	return orders, err
+}
+
+// SetOrderStatus moves an order to a new status, emailing the customer on
+// shipment or cancellation just as the admin API does
+func SetOrderStatus(orderID uint, status string) (*models.Order, error) {
+	newStatus, ok := models.ParseOrderStatus(status)
+	if !ok {
+		return nil, fmt.Errorf("invalid order status %q", status)
+	}
+
+	var order models.Order
+	if err := database.DB.Preload("OrderItems").Preload("User").First(&order, orderID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrOrderNotFound
+		}
+		return nil, err
+	}
+	if order.Status == newStatus {
+		return &order, nil
+	}
+
+	previousStatus := order.Status
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&order).Updates(map[string]interface{}{"status": newStatus, "version": models.NextVersion}).Error; err != nil {
+			return err
+		}
+		if newStatus == models.OrderStatusCancelled {
+			if err := events.Publish(tx, events.OrderCancelled{OrderID: order.ID, UserID: order.UserID, PreviousStatus: previousStatus}); err != nil {
+				return err
+			}
+		}
+		if newStatus == models.OrderStatusShipped {
+			if err := notify.Send(tx, notify.Mail{Template: notify.TemplateOrderShipped, UserID: order.UserID, OrderID: order.ID}); err != nil {
+				return err
+			}
+		}
+		return audit.Record(tx, Actor, audit.Event{
+			Action:     audit.ActionOrderStatusChanged,
+			TargetType: audit.TargetOrder,
+			TargetID:   order.ID,
+			Before:     map[string]interface{}{"status": previousStatus},
+			After:      map[string]interface{}{"status": newStatus},
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &order, nil
+}
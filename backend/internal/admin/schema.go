@@ -0,0 +1,60 @@
+package admin
+
+import (
+	"context"
+	"sort"
+
+	"shopease/internal/database"
+)
+
+// SchemaStatus describes the database schema compared with this build
+type SchemaStatus struct {
+	Expected int                        `json:"expected_version"`
+	Current  int                        `json:"current_version"`
+	Applied  []database.SchemaMigration `json:"applied"`
+	Tables   map[string]int64           `json:"tables"` // Row count per table
+}
+
+// UpToDate reports whether the database has been migrated for this build
+func (s SchemaStatus) UpToDate() bool {
+	return s.Current == s.Expected
+}
+
+// TableNames returns the table names in alphabetical order
+func (s SchemaStatus) TableNames() []string {
+	names := make([]string, 0, len(s.Tables))
+	for name := range s.Tables {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// GetSchemaStatus reports the applied migrations and table sizes. It works on
+// databases that have never been migrated.
+func GetSchemaStatus(ctx context.Context) (SchemaStatus, error) {
+	status := SchemaStatus{Expected: database.SchemaVersion, Tables: map[string]int64{}}
+
+	db := database.DB.WithContext(ctx)
+	tables, err := db.Migrator().GetTables()
+	if err != nil {
+		return status, err
+	}
+
+	for _, table := range tables {
+		var count int64
+		if err := db.Table(table).Count(&count).Error; err != nil {
+			return status, err
+		}
+		status.Tables[table] = count
+	}
+
+	if _, ok := status.Tables[database.SchemaMigration{}.TableName()]; !ok {
+		return status, nil
+	}
+	if status.Current, err = database.CurrentSchemaVersion(ctx); err != nil {
+		return status, err
+	}
+	status.Applied, err = database.AppliedMigrations(ctx)
+	return status, err
+}
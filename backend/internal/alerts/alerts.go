@@ -0,0 +1,181 @@
+package alerts
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"shopease/internal/database"
+	"shopease/internal/events"
+	"shopease/internal/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// DeliveryHook is called for each newly recorded notification, e.g. to push or email it.
+// Errors are logged; the in-app notification is kept either way.
+type DeliveryHook func(notification *models.Notification) error
+
+var (
+	hookMu sync.RWMutex
+	hook   DeliveryHook
+)
+
+// Price drops and restocks reach the alerts through the outbox, so a change
+// is matched against subscriptions exactly when it commits, even across a
+// restart. Redeliveries are harmless: each notification is recorded once per
+// event key.
+func init() {
+	events.Subscribe("alerts", priceChanged)
+	events.Subscribe("alerts", restocked)
+}
+
+// SetDeliveryHook registers the hook used to deliver notifications outside the app
+func SetDeliveryHook(h DeliveryHook) {
+	hookMu.Lock()
+	defer hookMu.Unlock()
+	hook = h
+}
+
+// priceChanged notifies price-drop subscribers when an active item gets cheaper
+func priceChanged(ctx context.Context, env events.Envelope, event events.ItemPriceChanged) error {
+	if event.NewPrice >= event.OldPrice {
+		return nil
+	}
+	item, err := activeItem(ctx, event.ItemID)
+	if err != nil || item == nil {
+		return err
+	}
+	return processPriceDrop(ctx, item, event, env.OccurredAt)
+}
+
+// restocked notifies back-in-stock subscribers when an item is available again
+func restocked(ctx context.Context, env events.Envelope, event events.ItemRestocked) error {
+	item, err := activeItem(ctx, event.ItemID)
+	if err != nil || item == nil {
+		return err
+	}
+	return processBackInStock(ctx, item, event, env)
+}
+
+// activeItem loads an item, or returns nil if it has since been deleted or deactivated
+func activeItem(ctx context.Context, id uint) (*models.Item, error) {
+	var item models.Item
+	if err := database.DB.WithContext(ctx).First(&item, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if !item.IsActive {
+		return nil, nil
+	}
+	return &item, nil
+}
+
+// processPriceDrop notifies price-drop subscribers whose target the new price meets
+func processPriceDrop(ctx context.Context, item *models.Item, change events.ItemPriceChanged, at time.Time) error {
+	var subscriptions []models.ItemAlert
+	if err := database.DB.WithContext(ctx).Where("item_id = ? AND type = ?", item.ID, models.AlertTypePriceDrop).Find(&subscriptions).Error; err != nil {
+		return err
+	}
+
+	for _, subscription := range subscriptions {
+		if subscription.TargetPrice != nil && change.NewPrice > *subscription.TargetPrice {
+			continue
+		}
+		// Only notify again once the price falls below what we last told the user
+		if subscription.LastNotifiedPrice != nil && change.NewPrice >= *subscription.LastNotifiedPrice {
+			continue
+		}
+
+		notification := models.Notification{
+			UserID:  subscription.UserID,
+			Type:    models.NotificationTypePriceDrop,
+			Title:   fmt.Sprintf("Price drop: %s", item.Name),
+			Message: fmt.Sprintf("%s is now $%.2f (was $%.2f).", item.Name, change.NewPrice, change.OldPrice),
+		}
+		eventKey := fmt.Sprintf("price:%.2f", change.NewPrice)
+		price := change.NewPrice
+
+		if err := record(ctx, &subscription, &notification, eventKey, at, func(tx *gorm.DB) error {
+			return tx.Model(&models.ItemAlert{}).Where("id = ?", subscription.ID).Update("last_notified_price", price).Error
+		}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// processBackInStock notifies back-in-stock subscribers that an item is available again
+func processBackInStock(ctx context.Context, item *models.Item, change events.ItemRestocked, env events.Envelope) error {
+	var subscriptions []models.ItemAlert
+	if err := database.DB.WithContext(ctx).Where("item_id = ? AND type = ?", item.ID, models.AlertTypeBackInStock).Find(&subscriptions).Error; err != nil {
+		return err
+	}
+
+	for _, subscription := range subscriptions {
+		notification := models.Notification{
+			UserID:  subscription.UserID,
+			Type:    models.NotificationTypeBackInStock,
+			Title:   fmt.Sprintf("Back in stock: %s", item.Name),
+			Message: fmt.Sprintf("%s is available again for $%.2f.", item.Name, change.Price),
+		}
+		eventKey := fmt.Sprintf("restock:%d", env.ID)
+
+		if err := record(ctx, &subscription, &notification, eventKey, env.OccurredAt, nil); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// record stores a notification for a subscription exactly once per event key,
+// applies any subscription bookkeeping in the same transaction, then calls the delivery hook
+func record(ctx context.Context, subscription *models.ItemAlert, notification *models.Notification, eventKey string, at time.Time, update func(tx *gorm.DB) error) error {
+	notification.ItemID = &subscription.ItemID
+	notification.AlertID = &subscription.ID
+	notification.EventKey = &eventKey
+
+	created := false
+	err := database.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(notification)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			// Already delivered for this event
+			return nil
+		}
+		created = true
+
+		if err := tx.Model(&models.ItemAlert{}).Where("id = ?", subscription.ID).Update("last_notified_at", at).Error; err != nil {
+			return err
+		}
+		if update != nil {
+			return update(tx)
+		}
+		return nil
+	})
+	if err != nil || !created {
+		return err
+	}
+
+	hookMu.RLock()
+	deliver := hook
+	hookMu.RUnlock()
+
+	if deliver != nil {
+		if err := deliver(notification); err != nil {
+			log.Printf("Error delivering notification %d: %v", notification.ID, err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,273 @@
+// Package audit records administrative and security-relevant actions in the
+// append-only, hash-chained audit_events table.
+package audit
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"time"
+
+	"shopease/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// Actions recorded in the audit log
+const (
+	ActionLogin                    = "auth.login"
+	ActionLoginFailed              = "auth.login_failed"
+	ActionLogout                   = "auth.logout"
+	ActionPasswordResetRequested   = "auth.password_reset_requested"
+	ActionPasswordReset            = "auth.password_reset"
+	ActionEmailVerified            = "auth.email_verified"
+	ActionTwoFactorEnabled         = "auth.2fa_enabled"
+	ActionTwoFactorDisabled        = "auth.2fa_disabled"
+	ActionRecoveryCodesRegenerated = "auth.recovery_codes_regenerated"
+
+	ActionUserUnlocked    = "user.unlock"
+	ActionAdminCreated    = "user.create_admin"
+	ActionSessionReset    = "user.session_reset"
+	ActionPasswordChanged = "user.password_set" // By an operator rather than the user
+	ActionDataExported    = "user.data_export"
+	ActionAccountDeleted  = "user.delete"
+	ActionAccountPurged   = "user.purge"
+
+	ActionItemCreated     = "item.create"
+	ActionItemUpdated     = "item.update"
+	ActionItemDeleted     = "item.delete"
+	ActionPriceScheduled  = "item.price_schedule"
+	ActionPriceCancelled  = "item.price_cancel"
+	ActionCatalogImported = "catalog.import"
+	ActionSeedApplied     = "seed.apply"
+
+	ActionOrderStatusChanged = "order.status_change"
+	ActionReviewModerated    = "review.moderate"
+
+	ActionJobRetried   = "job.retry"
+	ActionJobCancelled = "job.cancel"
+)
+
+// Target types recorded in the audit log
+const (
+	TargetUser   = "user"
+	TargetItem   = "item"
+	TargetOrder  = "order"
+	TargetReview = "review"
+	TargetJob    = "job"
+)
+
+// Actor identifies who performed an action and where the request came from
+type Actor struct {
+	UserID    *uint
+	Username  string
+	RequestID string
+	IP        string
+}
+
+// System returns an actor for work done outside an HTTP request, such as by shopctl
+func System(name string) Actor {
+	return Actor{Username: name}
+}
+
+// Event describes one action. Before and After are snapshots of the target
+// (any JSON-serialisable value); only the fields that differ are stored.
+type Event struct {
+	Action     string
+	TargetType string
+	TargetID   uint
+	Before     interface{}
+	After      interface{}
+	Details    map[string]interface{}
+}
+
+// Change is the old and new value of one field
+type Change struct {
+	From interface{} `json:"from"`
+	To   interface{} `json:"to"`
+}
+
+// ignoredFields change on every write and would only add noise
+var ignoredFields = map[string]bool{"created_at": true, "updated_at": true, "version": true}
+
+// Record appends an event to the audit log. Pass the transaction that makes
+// the change, so the event is committed or rolled back with it.
+func Record(tx *gorm.DB, actor Actor, event Event) error {
+	entry := models.AuditEvent{
+		CreatedAt:  time.Now().UTC().Truncate(time.Microsecond),
+		ActorID:    actor.UserID,
+		ActorName:  actor.Username,
+		Action:     event.Action,
+		TargetType: event.TargetType,
+		TargetID:   event.TargetID,
+		RequestID:  actor.RequestID,
+		IP:         actor.IP,
+	}
+
+	if event.Before != nil || event.After != nil {
+		changes, err := Diff(event.Before, event.After)
+		if err != nil {
+			return err
+		}
+		if entry.Changes, err = json.Marshal(changes); err != nil {
+			return err
+		}
+	}
+	if len(event.Details) > 0 {
+		details, err := json.Marshal(event.Details)
+		if err != nil {
+			return err
+		}
+		entry.Details = details
+	}
+
+	// Insert first: once this transaction has written, SQLite holds the write
+	// lock until commit, so the previous event read below can't change under us
+	if err := tx.Create(&entry).Error; err != nil {
+		return err
+	}
+
+	var previous models.AuditEvent
+	if err := tx.Select("hash").Where("id < ?", entry.ID).Order("id DESC").Limit(1).Find(&previous).Error; err != nil {
+		return err
+	}
+	entry.PrevHash = previous.Hash
+	entry.Hash = Hash(entry)
+
+	return tx.Model(&entry).UpdateColumns(map[string]interface{}{
+		"prev_hash": entry.PrevHash,
+		"hash":      entry.Hash,
+	}).Error
+}
+
+// Diff returns the fields whose JSON values differ between before and after.
+// Either may be nil, e.g. for a created or deleted record.
+func Diff(before, after interface{}) (map[string]Change, error) {
+	from, err := fields(before)
+	if err != nil {
+		return nil, err
+	}
+	to, err := fields(after)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := map[string]bool{}
+	for key := range from {
+		keys[key] = true
+	}
+	for key := range to {
+		keys[key] = true
+	}
+
+	changes := map[string]Change{}
+	for key := range keys {
+		if ignoredFields[key] || reflect.DeepEqual(from[key], to[key]) {
+			continue
+		}
+		changes[key] = Change{From: from[key], To: to[key]}
+	}
+	return changes, nil
+}
+
+// fields flattens v into its top-level JSON fields
+func fields(v interface{}) (map[string]interface{}, error) {
+	if v == nil {
+		return nil, nil
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var out map[string]interface{}
+	if err := json.Unmarshal(data, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// Hash computes an event's chain hash from its contents and PrevHash
+func Hash(event models.AuditEvent) string {
+	// Canonical form: fixed field order, UTC timestamp, compact JSON
+	canonical, _ := json.Marshal(struct {
+		ID         uint            `json:"id"`
+		CreatedAt  string          `json:"created_at"`
+		ActorID    *uint           `json:"actor_id"`
+		ActorName  string          `json:"actor_name"`
+		Action     string          `json:"action"`
+		TargetType string          `json:"target_type"`
+		TargetID   uint            `json:"target_id"`
+		Changes    json.RawMessage `json:"changes"`
+		Details    json.RawMessage `json:"details"`
+		RequestID  string          `json:"request_id"`
+		IP         string          `json:"ip"`
+		PrevHash   string          `json:"prev_hash"`
+	}{
+		event.ID, event.CreatedAt.UTC().Format(time.RFC3339Nano), event.ActorID, event.ActorName,
+		event.Action, event.TargetType, event.TargetID, nullIfEmpty(event.Changes), nullIfEmpty(event.Details),
+		event.RequestID, event.IP, event.PrevHash,
+	})
+
+	sum := sha256.Sum256(canonical)
+	return hex.EncodeToString(sum[:])
+}
+
+// nullIfEmpty treats a missing and an empty JSON column alike
+func nullIfEmpty(raw json.RawMessage) json.RawMessage {
+	if len(raw) == 0 {
+		return json.RawMessage("null")
+	}
+	return raw
+}
+
+// Verification is the result of checking the hash chain
+type Verification struct {
+	Valid    bool   `json:"valid"`
+	Events   int    `json:"events"`              // Events checked, up to and including any broken one
+	Head     string `json:"head,omitempty"`      // Hash of the newest event; record it elsewhere to detect truncation
+	BrokenAt *uint  `json:"broken_at,omitempty"` // First event that fails verification
+	Problem  string `json:"problem,omitempty"`
+}
+
+// verifyBatchSize bounds how many events are held in memory while verifying
+const verifyBatchSize = 500
+
+// errChainBroken stops Verify reading further batches once a problem is found
+var errChainBroken = errors.New("audit chain broken")
+
+// Verify walks the whole chain, oldest first, and reports the first event
+// whose hash or link to its predecessor doesn't match.
+func Verify(db *gorm.DB) (*Verification, error) {
+	result := &Verification{Valid: true}
+	previous := ""
+
+	var batch []models.AuditEvent
+	err := db.Model(&models.AuditEvent{}).FindInBatches(&batch, verifyBatchSize, func(tx *gorm.DB, _ int) error {
+		for _, event := range batch {
+			result.Events++
+
+			problem := ""
+			switch {
+			case event.PrevHash != previous:
+				problem = "previous hash does not match the preceding event; an event was removed or reordered"
+			case event.Hash != Hash(event):
+				problem = "hash does not match the event's contents; the event was modified"
+			}
+			if problem != "" {
+				id := event.ID
+				result.Valid, result.BrokenAt, result.Problem = false, &id, problem
+				return errChainBroken
+			}
+
+			previous = event.Hash
+			result.Head = event.Hash
+		}
+		return nil
+	}).Error
+	if err != nil && !errors.Is(err, errChainBroken) {
+		return nil, err
+	}
+	return result, nil
+}
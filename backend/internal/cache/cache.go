@@ -0,0 +1,65 @@
+// Package cache keeps copies of responses that are expensive to build and
+// rarely change, such as the public catalogue.
+package cache
+
+import (
+	"net/url"
+	"strconv"
+	"time"
+
+	"shopease/internal/models"
+)
+
+// Cache stores values by key until they expire or are evicted.
+// Implementations must be safe for concurrent use.
+type Cache interface {
+	Get(key string) ([]byte, bool)
+	Set(key string, value []byte, ttl time.Duration)
+	Delete(key string)
+	DeletePrefix(prefix string) // Drops every key starting with prefix
+}
+
+// Scopes of cached catalogue responses, the first part of their keys
+const (
+	ScopeItem       = "item"       // GET /items/:id
+	ScopeItems      = "items"      // GET /items
+	ScopeCategories = "categories" // GET /items/categories
+)
+
+// Catalogue caches public catalogue responses. The server replaces it with
+// one sized from the configuration at start-up.
+var Catalogue Cache = NewLRU(1000)
+
+// ItemKey is the key of the item's own page
+func ItemKey(id uint) string {
+	return ScopeItem + ":" + strconv.FormatUint(uint64(id), 10)
+}
+
+// ItemsKey is the key of a page of the item list. It is built from the parsed
+// query rather than the raw one, so parameters the handler ignores or spells
+// differently can't store one page under another's key.
+func ItemsKey(query models.ItemListQuery) string {
+	return ScopeItems + ":" + url.Values{
+		"page":      {strconv.Itoa(query.Page)},
+		"page_size": {strconv.Itoa(query.PageSize)},
+		"category":  {query.Category},
+	}.Encode()
+}
+
+// CategoriesKey is the key of the category list, which takes no parameters
+func CategoriesKey() string {
+	return ScopeCategories + ":"
+}
+
+// InvalidateAll drops every cached catalogue response, for bulk changes
+func InvalidateAll() {
+	Catalogue.DeletePrefix("")
+}
+
+// InvalidateItem drops every cached response that may show the item: its own
+// page, every page of the item list and the category list
+func InvalidateItem(id uint) {
+	Catalogue.Delete(ItemKey(id))
+	Catalogue.DeletePrefix(ScopeItems + ":")
+	Catalogue.Delete(CategoriesKey())
+}
@@ -0,0 +1,80 @@
+package catalog
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"shopease/internal/database"
+	"shopease/internal/models"
+)
+
+const (
+	// ExportChunkSize is how many items Export reads per query
+	ExportChunkSize = 500
+
+	// ExportChunkTimeout is the write deadline granted to each chunk when
+	// exporting to an HTTP response, in place of the server's write timeout
+	ExportChunkTimeout = 30 * time.Second
+)
+
+// Export streams every item, including inactive ones, to w in ID order and
+// returns how many were written. Items are read in chunks by ID, so memory use
+// doesn't grow with the catalogue and no read transaction stays open while
+// the output is written. When w is an HTTP response, each chunk extends the
+// write deadline, so large exports aren't cut off by the server's timeout.
+func Export(ctx context.Context, w io.Writer, format Format) (int, error) {
+	enc, err := newEncoder(format, w)
+	if err != nil {
+		return 0, err
+	}
+
+	var rc *http.ResponseController
+	if rw, ok := w.(http.ResponseWriter); ok {
+		rc = http.NewResponseController(rw)
+	}
+
+	db := database.DB.WithContext(ctx)
+	count := 0
+	var lastID uint
+	for {
+		var items []models.Item
+		if err := db.Where("id > ?", lastID).Order("id").Limit(ExportChunkSize).Find(&items).Error; err != nil {
+			return count, err
+		}
+		if len(items) == 0 {
+			break
+		}
+
+		if rc != nil {
+			if err := rc.SetWriteDeadline(time.Now().Add(ExportChunkTimeout)); err != nil && !errors.Is(err, http.ErrNotSupported) {
+				return count, err
+			}
+		}
+
+		for _, item := range items {
+			active := item.IsActive
+			if err := enc.encode(Record{
+				ID:          item.ID,
+				SKU:         item.SKUValue(),
+				Name:        item.Name,
+				Description: item.Description,
+				Price:       item.Price,
+				ImageURL:    item.ImageURL,
+				Category:    item.Category,
+				IsActive:    &active,
+			}); err != nil {
+				return count, err
+			}
+			count++
+		}
+		lastID = items[len(items)-1].ID
+
+		if len(items) < ExportChunkSize {
+			break
+		}
+	}
+	return count, enc.close()
+}
@@ -0,0 +1,355 @@
+// Package catalog implements bulk import and export of catalogue items as
+// CSV, newline-delimited JSON or a JSON array.
+package catalog
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Format is a bulk catalogue file format
+type Format string
+
+const (
+	FormatCSV    Format = "csv"
+	FormatNDJSON Format = "ndjson"
+	FormatJSON   Format = "json" // A single JSON array, as used by shopctl
+)
+
+// ParseFormat returns the Format named by s, accepting common aliases and MIME types
+func ParseFormat(s string) (Format, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if i := strings.IndexByte(s, ';'); i >= 0 {
+		s = strings.TrimSpace(s[:i])
+	}
+
+	switch s {
+	case "csv", "text/csv", "application/csv":
+		return FormatCSV, nil
+	case "ndjson", "jsonl", "application/x-ndjson", "application/jsonl", "application/x-jsonlines":
+		return FormatNDJSON, nil
+	case "json", "application/json":
+		return FormatJSON, nil
+	}
+	return "", fmt.Errorf("unsupported catalogue format %q (want csv, ndjson or json)", s)
+}
+
+// ContentType returns the MIME type for the format
+func (f Format) ContentType() string {
+	switch f {
+	case FormatCSV:
+		return "text/csv; charset=utf-8"
+	case FormatNDJSON:
+		return "application/x-ndjson"
+	default:
+		return "application/json"
+	}
+}
+
+// Record is one catalogue row. Rows are matched to existing items by SKU,
+// falling back to ID; see Import.
+type Record struct {
+	ID          uint    `json:"id,omitempty"`
+	SKU         string  `json:"sku,omitempty"`
+	Name        string  `json:"name"`
+	Description string  `json:"description,omitempty"`
+	Price       float64 `json:"price"`
+	ImageURL    string  `json:"image_url,omitempty"`
+	Category    string  `json:"category,omitempty"`
+	IsActive    *bool   `json:"is_active,omitempty"` // Defaults to true for new items
+}
+
+// Columns are the CSV header names, in export order
+var Columns = []string{"sku", "id", "name", "description", "price", "image_url", "category", "is_active"}
+
+// errSkipRow wraps problems confined to one input row; reading can continue
+type errSkipRow struct {
+	row int
+	err error
+}
+
+func (e *errSkipRow) Error() string { return e.err.Error() }
+
+// decoder reads records one at a time. next returns the record and its row
+// number (data rows count from 1), io.EOF at the end, an *errSkipRow for a
+// malformed row, or any other error if the input can't be read further.
+type decoder interface {
+	next() (Record, int, error)
+}
+
+func newDecoder(format Format, r io.Reader) (decoder, error) {
+	switch format {
+	case FormatCSV:
+		return newCSVDecoder(r)
+	case FormatNDJSON:
+		return &ndjsonDecoder{r: bufio.NewReader(r)}, nil
+	case FormatJSON:
+		return newJSONDecoder(r)
+	}
+	return nil, fmt.Errorf("unsupported catalogue format %q", format)
+}
+
+type csvDecoder struct {
+	r       *csv.Reader
+	columns []string
+	row     int
+}
+
+func newCSVDecoder(r io.Reader) (*csvDecoder, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+	reader.ReuseRecord = true
+
+	header, err := reader.Read()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, errors.New("CSV file is empty")
+		}
+		return nil, fmt.Errorf("reading CSV header: %w", err)
+	}
+
+	known := map[string]bool{}
+	for _, column := range Columns {
+		known[column] = true
+	}
+	columns := make([]string, len(header))
+	seen := map[string]bool{}
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		if !known[name] {
+			return nil, fmt.Errorf("unknown CSV column %q (allowed: %s)", name, strings.Join(Columns, ", "))
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("duplicate CSV column %q", name)
+		}
+		seen[name] = true
+		columns[i] = name
+	}
+	for _, required := range []string{"name", "price"} {
+		if !seen[required] {
+			return nil, fmt.Errorf("CSV header is missing the %q column", required)
+		}
+	}
+
+	reader.FieldsPerRecord = len(columns)
+	return &csvDecoder{r: reader, columns: columns}, nil
+}
+
+func (d *csvDecoder) next() (Record, int, error) {
+	fields, err := d.r.Read()
+	if errors.Is(err, io.EOF) {
+		return Record{}, 0, io.EOF
+	}
+	d.row++
+	if err != nil {
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			return Record{}, d.row, &errSkipRow{d.row, parseErr.Err}
+		}
+		return Record{}, d.row, err
+	}
+
+	var record Record
+	var problems []string
+	for i, value := range fields {
+		value = strings.TrimSpace(value)
+		switch d.columns[i] {
+		case "sku":
+			record.SKU = value
+		case "id":
+			if value != "" {
+				id, err := strconv.ParseUint(value, 10, 32)
+				if err != nil {
+					problems = append(problems, fmt.Sprintf("id: %q is not a valid ID", value))
+				}
+				record.ID = uint(id)
+			}
+		case "name":
+			record.Name = value
+		case "description":
+			record.Description = value
+		case "price":
+			price, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				problems = append(problems, fmt.Sprintf("price: %q is not a number", value))
+			}
+			record.Price = price
+		case "image_url":
+			record.ImageURL = value
+		case "category":
+			record.Category = value
+		case "is_active":
+			if value != "" {
+				active, err := strconv.ParseBool(value)
+				if err != nil {
+					problems = append(problems, fmt.Sprintf("is_active: %q is not true or false", value))
+				}
+				record.IsActive = &active
+			}
+		}
+	}
+	if len(problems) > 0 {
+		return record, d.row, &errSkipRow{d.row, errors.New(strings.Join(problems, "; "))}
+	}
+	return record, d.row, nil
+}
+
+type ndjsonDecoder struct {
+	r   *bufio.Reader
+	row int
+}
+
+func (d *ndjsonDecoder) next() (Record, int, error) {
+	for {
+		line, err := d.r.ReadBytes('\n')
+		if len(bytes.TrimSpace(line)) == 0 {
+			if err != nil {
+				return Record{}, 0, err // io.EOF, or a read error
+			}
+			continue // Blank lines don't count as rows
+		}
+		if err != nil && !errors.Is(err, io.EOF) {
+			return Record{}, d.row, err
+		}
+		d.row++
+		record, decodeErr := decodeRecord(line)
+		if decodeErr != nil {
+			return record, d.row, &errSkipRow{d.row, decodeErr}
+		}
+		return record, d.row, nil
+	}
+}
+
+// decodeRecord strictly decodes one JSON object
+func decodeRecord(data []byte) (Record, error) {
+	var record Record
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&record); err != nil {
+		return record, fmt.Errorf("invalid JSON: %w", err)
+	}
+	return record, nil
+}
+
+type jsonDecoder struct {
+	d   *json.Decoder
+	row int
+}
+
+func newJSONDecoder(r io.Reader) (*jsonDecoder, error) {
+	d := json.NewDecoder(r)
+	token, err := d.Token()
+	if err != nil {
+		return nil, fmt.Errorf("reading JSON: %w", err)
+	}
+	if delim, ok := token.(json.Delim); !ok || delim != '[' {
+		return nil, errors.New("JSON catalogue must be an array of items")
+	}
+	return &jsonDecoder{d: d}, nil
+}
+
+func (d *jsonDecoder) next() (Record, int, error) {
+	if !d.d.More() {
+		return Record{}, 0, io.EOF
+	}
+	d.row++
+
+	var raw json.RawMessage
+	if err := d.d.Decode(&raw); err != nil {
+		return Record{}, d.row, fmt.Errorf("reading JSON: %w", err)
+	}
+	record, err := decodeRecord(raw)
+	if err != nil {
+		return record, d.row, &errSkipRow{d.row, err}
+	}
+	return record, d.row, nil
+}
+
+// encoder writes records in one of the formats
+type encoder interface {
+	encode(Record) error
+	close() error
+}
+
+func newEncoder(format Format, w io.Writer) (encoder, error) {
+	switch format {
+	case FormatCSV:
+		writer := csv.NewWriter(w)
+		if err := writer.Write(Columns); err != nil {
+			return nil, err
+		}
+		return &csvEncoder{w: writer}, nil
+	case FormatNDJSON:
+		return &ndjsonEncoder{e: json.NewEncoder(w)}, nil
+	case FormatJSON:
+		return &jsonEncoder{w: w}, nil
+	}
+	return nil, fmt.Errorf("unsupported catalogue format %q", format)
+}
+
+type csvEncoder struct {
+	w *csv.Writer
+}
+
+func (e *csvEncoder) encode(r Record) error {
+	id := ""
+	if r.ID != 0 {
+		id = strconv.FormatUint(uint64(r.ID), 10)
+	}
+	active := ""
+	if r.IsActive != nil {
+		active = strconv.FormatBool(*r.IsActive)
+	}
+	return e.w.Write([]string{
+		r.SKU, id, r.Name, r.Description,
+		strconv.FormatFloat(r.Price, 'f', -1, 64),
+		r.ImageURL, r.Category, active,
+	})
+}
+
+func (e *csvEncoder) close() error {
+	e.w.Flush()
+	return e.w.Error()
+}
+
+type ndjsonEncoder struct {
+	e *json.Encoder
+}
+
+func (e *ndjsonEncoder) encode(r Record) error { return e.e.Encode(r) }
+func (e *ndjsonEncoder) close() error          { return nil }
+
+type jsonEncoder struct {
+	w     io.Writer
+	count int
+}
+
+func (e *jsonEncoder) encode(r Record) error {
+	data, err := json.MarshalIndent(r, "  ", "  ")
+	if err != nil {
+		return err
+	}
+	separator := ",\n  "
+	if e.count == 0 {
+		separator = "[\n  "
+	}
+	e.count++
+	_, err = fmt.Fprintf(e.w, "%s%s", separator, data)
+	return err
+}
+
+func (e *jsonEncoder) close() error {
+	closing := "\n]\n"
+	if e.count == 0 {
+		closing = "[]\n"
+	}
+	_, err := io.WriteString(e.w, closing)
+	return err
+}
@@ -0,0 +1,337 @@
+package catalog
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+	"time"
+
+	"shopease/internal/audit"
+	"shopease/internal/cache"
+	"shopease/internal/database"
+	"shopease/internal/events"
+	"shopease/internal/models"
+	"shopease/internal/pricing"
+
+	"gorm.io/gorm"
+)
+
+// Mode controls what happens to valid rows when other rows fail
+type Mode string
+
+const (
+	ModeAtomic     Mode = "all_or_nothing" // Apply nothing if any row fails
+	ModeBestEffort Mode = "best_effort"    // Apply every valid row and report the rest
+)
+
+// MaxReportedErrors bounds the row errors kept in a report
+const MaxReportedErrors = 1000
+
+// BatchSize is how many rows a best-effort import commits at a time, so a
+// large file doesn't hold the database's write lock for the whole import
+const BatchSize = 500
+
+// Options configure an import
+type Options struct {
+	Format Format
+	Mode   Mode
+	DryRun bool // Validate and match every row, then roll back
+
+	// Actor is recorded in the audit log against each item changed
+	Actor audit.Actor
+
+	// Progress, if set, is called after each row with the number of rows processed
+	Progress func(rows int)
+}
+
+// RowError describes why one input row was rejected
+type RowError struct {
+	Row    int      `json:"row"` // 1-based data row, not counting a CSV header
+	SKU    string   `json:"sku,omitempty"`
+	Errors []string `json:"errors"`
+}
+
+// Report summarises an import
+type Report struct {
+	Mode    Mode       `json:"mode"`
+	DryRun  bool       `json:"dry_run"`
+	Applied bool       `json:"applied"` // Whether any changes were committed
+	Rows    int        `json:"rows"`
+	Created int        `json:"created"`
+	Updated int        `json:"updated"`
+	Failed  int        `json:"failed"`
+	Errors  []RowError `json:"errors"`
+
+	// ErrorsTruncated is set when more than MaxReportedErrors rows failed
+	ErrorsTruncated bool `json:"errors_truncated,omitempty"`
+}
+
+func (r *Report) reject(row int, sku string, problems ...string) {
+	r.Failed++
+	if len(r.Errors) >= MaxReportedErrors {
+		r.ErrorsTruncated = true
+		return
+	}
+	r.Errors = append(r.Errors, RowError{Row: row, SKU: sku, Errors: problems})
+}
+
+// Import reads items from r and upserts them. Each row is matched to an
+// existing item by SKU (reviving a deleted item with that SKU), otherwise by
+// ID; unmatched rows create new items, keeping any ID given.
+//
+// Invalid rows are reported rather than aborting the import. In ModeAtomic
+// the import runs in one transaction and nothing is committed if any row
+// fails; in ModeBestEffort the valid rows are committed every BatchSize rows.
+// A dry run reports what would happen and commits nothing. The returned error
+// is for failures that stop the import as a whole, such as unreadable input or
+// a database error; if batches were already committed by then, the report so
+// far is returned with it, with Applied set.
+func Import(ctx context.Context, r io.Reader, opts Options) (*Report, error) {
+	if opts.Mode == "" {
+		opts.Mode = ModeAtomic
+	}
+	if opts.Mode != ModeAtomic && opts.Mode != ModeBestEffort {
+		return nil, fmt.Errorf("unknown import mode %q (want %s or %s)", opts.Mode, ModeAtomic, ModeBestEffort)
+	}
+
+	dec, err := newDecoder(opts.Format, r)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &Report{Mode: opts.Mode, DryRun: opts.DryRun, Errors: []RowError{}}
+	batched := opts.Mode == ModeBestEffort && !opts.DryRun
+
+	// partial is what to return with an error: nothing, unless batches were committed
+	partial := func() *Report {
+		if report.Applied {
+			return report
+		}
+		return nil
+	}
+
+	tx := database.DB.WithContext(ctx).Begin()
+	if tx.Error != nil {
+		return nil, tx.Error
+	}
+
+	for {
+		record, row, err := dec.next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		var skip *errSkipRow
+		switch {
+		case errors.As(err, &skip):
+			report.Rows++
+			report.reject(row, record.SKU, skip.Error())
+		case err != nil:
+			tx.Rollback()
+			return partial(), fmt.Errorf("row %d: %w", row, err)
+		default:
+			report.Rows++
+			if problems := record.validate(); len(problems) > 0 {
+				report.reject(row, record.SKU, problems...)
+				break
+			}
+
+			created, err := upsert(tx, record, opts.Actor)
+			if err != nil {
+				report.reject(row, record.SKU, err.Error())
+				break
+			}
+			if created {
+				report.Created++
+			} else {
+				report.Updated++
+			}
+		}
+
+		if opts.Progress != nil {
+			opts.Progress(report.Rows)
+		}
+		if err := ctx.Err(); err != nil {
+			tx.Rollback()
+			return partial(), err
+		}
+
+		if batched && report.Rows%BatchSize == 0 {
+			if err := tx.Commit().Error; err != nil {
+				return partial(), err
+			}
+			report.Applied = true
+			cache.InvalidateAll()
+
+			if tx = database.DB.WithContext(ctx).Begin(); tx.Error != nil {
+				return partial(), tx.Error
+			}
+		}
+	}
+
+	if opts.DryRun || (opts.Mode == ModeAtomic && report.Failed > 0) {
+		tx.Rollback()
+		return report, nil
+	}
+	// The audit event goes in the last batch, once the totals are known
+	if err := audit.Record(tx, opts.Actor, audit.Event{
+		Action: audit.ActionCatalogImported,
+		Details: map[string]interface{}{
+			"format":  opts.Format,
+			"mode":    opts.Mode,
+			"rows":    report.Rows,
+			"created": report.Created,
+			"updated": report.Updated,
+			"failed":  report.Failed,
+		},
+	}); err != nil {
+		tx.Rollback()
+		return partial(), err
+	}
+	if err := tx.Commit().Error; err != nil {
+		return partial(), err
+	}
+	report.Applied = true
+	cache.InvalidateAll()
+	return report, nil
+}
+
+// validate applies the same limits as the item API
+func (r Record) validate() []string {
+	var problems []string
+	if r.Name == "" {
+		problems = append(problems, "name: required")
+	} else if len(r.Name) > 255 {
+		problems = append(problems, "name: at most 255 characters")
+	}
+	if len(r.SKU) > 64 {
+		problems = append(problems, "sku: at most 64 characters")
+	}
+	if len(r.Description) > 1000 {
+		problems = append(problems, "description: at most 1000 characters")
+	}
+	if r.Price < 0 {
+		problems = append(problems, "price: must not be negative")
+	}
+	if len(r.Category) > 100 {
+		problems = append(problems, "category: at most 100 characters")
+	}
+	if r.ImageURL != "" {
+		u, err := url.ParseRequestURI(r.ImageURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			problems = append(problems, "image_url: must be an http(s) URL")
+		} else if len(r.ImageURL) > 500 {
+			problems = append(problems, "image_url: at most 500 characters")
+		}
+	}
+	return problems
+}
+
+// upsert writes one record, and its audit event, inside a savepoint so a failure
+// only undoes this row. Price changes and restocks publish events for the
+// alerts, as item edits do. It returns whether the item is new.
+func upsert(tx *gorm.DB, record Record, actor audit.Actor) (bool, error) {
+	var item models.Item
+	var err error
+	switch {
+	case record.SKU != "":
+		err = tx.Unscoped().Where("sku = ?", record.SKU).First(&item).Error
+	case record.ID != 0:
+		err = tx.First(&item, record.ID).Error
+	default:
+		err = gorm.ErrRecordNotFound
+	}
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return false, err
+	}
+	exists := err == nil
+
+	if err := tx.SavePoint("catalog_row").Error; err != nil {
+		return false, err
+	}
+	defer tx.Exec("RELEASE SAVEPOINT catalog_row")
+	rollback := func(err error) (bool, error) {
+		tx.RollbackTo("catalog_row")
+		if strings.Contains(strings.ToLower(err.Error()), "unique") {
+			return false, errors.New("conflicts with an existing item")
+		}
+		return false, err
+	}
+
+	if !exists {
+		item = models.Item{IsActive: true}
+		if record.SKU == "" {
+			item.ID = record.ID
+		}
+		record.apply(&item)
+		item.Price = record.Price
+		active := item.IsActive
+		if err := tx.Create(&item).Error; err != nil {
+			return rollback(err)
+		}
+		if err := pricing.SetRegular(tx, &item, item.Price, item.CreatedAt); err != nil {
+			return rollback(err)
+		}
+		// Create applies the column default, turning a false IsActive into true
+		if !active {
+			if err := tx.Model(&item).Update("is_active", false).Error; err != nil {
+				return rollback(err)
+			}
+		}
+		event := audit.Event{Action: audit.ActionItemCreated, TargetType: audit.TargetItem, TargetID: item.ID, After: item.ToResponse()}
+		if err := audit.Record(tx, actor, event); err != nil {
+			return rollback(err)
+		}
+		return true, nil
+	}
+
+	before, wasDeleted := item.ToResponse(), item.DeletedAt.Valid
+	wasActive := item.IsActive && !wasDeleted
+	record.apply(&item)
+	item.DeletedAt = gorm.DeletedAt{}
+	// Rows that match the item leave it, and its version, untouched
+	if changes, _ := audit.Diff(before, item.ToResponse()); len(changes) == 0 && record.Price == item.Price && !wasDeleted {
+		return false, nil
+	}
+	if err := models.ClaimVersion(tx.Unscoped(), &item, item.Version); err != nil {
+		return rollback(err)
+	}
+	if err := tx.Unscoped().Omit(models.ItemRatingColumns...).Save(&item).Error; err != nil {
+		return rollback(err)
+	}
+	if err := pricing.SetRegular(tx, &item, record.Price, time.Now()); err != nil {
+		return rollback(err)
+	}
+	event := audit.Event{Action: audit.ActionItemUpdated, TargetType: audit.TargetItem, TargetID: item.ID, Before: before, After: item.ToResponse()}
+	if wasDeleted {
+		event.Details = map[string]interface{}{"restored": true}
+	}
+	if err := audit.Record(tx, actor, event); err != nil {
+		return rollback(err)
+	}
+	if item.IsActive && !wasActive {
+		if err := events.Publish(tx, events.ItemRestocked{ItemID: item.ID, Price: item.Price}); err != nil {
+			return rollback(err)
+		}
+	}
+	return false, nil
+}
+
+// apply copies the record onto item; an omitted is_active leaves it
+// unchanged. The price is left to pricing.SetRegular, which records it in
+// the item's price history and publishes the change.
+func (r Record) apply(item *models.Item) {
+	if r.SKU != "" {
+		item.SetSKU(r.SKU)
+	}
+	item.Name = r.Name
+	item.Description = r.Description
+	item.ImageURL = r.ImageURL
+	item.Category = r.Category
+	if r.IsActive != nil {
+		item.IsActive = *r.IsActive
+	}
+}
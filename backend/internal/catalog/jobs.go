@@ -0,0 +1,165 @@
+package catalog
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log"
+	"os"
+	"sync/atomic"
+	"time"
+
+	"shopease/internal/audit"
+	"shopease/internal/jobs"
+	"shopease/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// ImportJob is the type of the background job that imports an uploaded file
+const ImportJob = "catalog.import"
+
+// progressInterval is how often a running import reports its progress
+const progressInterval = time.Second
+
+// importPayload is what an import job needs to run on a worker
+type importPayload struct {
+	Path   string      `json:"path"`
+	Size   int64       `json:"size"`
+	Format Format      `json:"format"`
+	Mode   Mode        `json:"mode"`
+	DryRun bool        `json:"dry_run"`
+	Actor  audit.Actor `json:"actor"`
+}
+
+// ImportProgress is what an import job reports as its result: how far it has
+// got while running, and its report once finished
+type ImportProgress struct {
+	Rows     int     `json:"rows"`     // Rows processed so far
+	Progress float64 `json:"progress"` // Fraction of the file read, 0 to 1
+	Report   *Report `json:"report,omitempty"`
+}
+
+// ImportStatus is a point-in-time view of a background import
+type ImportStatus struct {
+	ID     uint            `json:"id"`
+	State  models.JobState `json:"state"`
+	Format Format          `json:"format"`
+	Mode   Mode            `json:"mode"`
+	DryRun bool            `json:"dry_run"`
+	ImportProgress
+	Error      string     `json:"error,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+}
+
+func init() {
+	jobs.Register(ImportJob, runImport)
+}
+
+// EnqueueImport queues the file at path to be imported by the job workers,
+// which delete it when done. Imports get a single attempt: a failed one is
+// reported rather than retried, since the file is the likely cause.
+func EnqueueImport(tx *gorm.DB, path string, opts Options) (*models.Job, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	mode := opts.Mode
+	if mode == "" {
+		mode = ModeAtomic
+	}
+	return jobs.Enqueue(tx, ImportJob, importPayload{
+		Path:   path,
+		Size:   info.Size(),
+		Format: opts.Format,
+		Mode:   mode,
+		DryRun: opts.DryRun,
+		Actor:  opts.Actor,
+	}, jobs.Options{MaxAttempts: 1})
+}
+
+// ImportStatusOf describes an import job, as loaded by jobs.Get
+func ImportStatusOf(job *models.Job) (ImportStatus, error) {
+	var payload importPayload
+	if err := json.Unmarshal(job.Payload, &payload); err != nil {
+		return ImportStatus{}, err
+	}
+
+	status := ImportStatus{
+		ID:         job.ID,
+		State:      job.State,
+		Format:     payload.Format,
+		Mode:       payload.Mode,
+		DryRun:     payload.DryRun,
+		Error:      job.LastError,
+		CreatedAt:  job.CreatedAt,
+		FinishedAt: job.FinishedAt,
+	}
+	if len(job.Result) > 0 {
+		if err := json.Unmarshal(job.Result, &status.ImportProgress); err != nil {
+			return ImportStatus{}, err
+		}
+	}
+	return status, nil
+}
+
+// runImport imports a queued file, reporting progress as it goes
+func runImport(ctx context.Context, payload importPayload) error {
+	defer os.Remove(payload.Path)
+
+	f, err := os.Open(payload.Path)
+	if err != nil {
+		return jobs.Permanent(err)
+	}
+	defer f.Close()
+
+	var read atomic.Int64
+	progress := func(rows int) ImportProgress {
+		p := ImportProgress{Rows: rows}
+		if payload.Size > 0 {
+			p.Progress = float64(read.Load()) / float64(payload.Size)
+		}
+		return p
+	}
+
+	var reported time.Time
+	report, err := Import(ctx, &countingReader{r: f, n: &read}, Options{
+		Format: payload.Format,
+		Mode:   payload.Mode,
+		DryRun: payload.DryRun,
+		Actor:  payload.Actor,
+		Progress: func(rows int) {
+			if time.Since(reported) >= progressInterval {
+				reported = time.Now()
+				_ = jobs.SetResult(ctx, progress(rows))
+			}
+		},
+	})
+
+	final := ImportProgress{Progress: 1, Report: report}
+	if err != nil {
+		final = progress(0)
+		final.Report = report
+	}
+	if report != nil {
+		final.Rows = report.Rows
+	}
+	if err := jobs.SetResult(ctx, final); err != nil {
+		log.Printf("Error recording catalogue import report: %v", err)
+	}
+	return jobs.Permanent(err)
+}
+
+// countingReader counts the bytes read through it
+type countingReader struct {
+	r io.Reader
+	n *atomic.Int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n.Add(int64(n))
+	return n, err
+}
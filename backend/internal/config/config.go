@@ -1,57 +1,205 @@
 package config
 
 import (
+	"flag"
 	"log"
-	"os"
-	"strconv"
+	"strings"
 
 	"github.com/joho/godotenv"
 )
 
-// Config holds all configuration variables
+// Config holds all configuration variables.
+//
+// Each field is set, in increasing order of precedence, from Defaults, the
+// optional config file, the environment variable named by its env tag and
+// the matching command-line flag. See Load.
 type Config struct {
-	Port           string
-	GinMode        string
-	DBPath         string
-	JWTSecret      string
-	JWTExpiryHours int
-	AllowedOrigins string
+	Port           string `env:"PORT"`
+	GinMode        string `env:"GIN_MODE"`
+	DBPath         string `env:"DB_PATH"`
+	SeedProfile    string `env:"SEED_PROFILE"` // Applied to an empty database on start: test, dev, demo or none
+	JWTSecret      string `env:"JWT_SECRET" secret:"true"`
+	JWTExpiryHours int    `env:"JWT_EXPIRY_HOURS"`
+	AllowedOrigins string `env:"ALLOWED_ORIGINS"`
+
+	// HTTP server timeouts, in seconds
+	HTTPReadTimeout       int `env:"HTTP_READ_TIMEOUT"` // Reading the whole request, including the body
+	HTTPReadHeaderTimeout int `env:"HTTP_READ_HEADER_TIMEOUT"`
+	HTTPWriteTimeout      int `env:"HTTP_WRITE_TIMEOUT"` // Writing the response, measured from the end of the request headers
+	HTTPIdleTimeout       int `env:"HTTP_IDLE_TIMEOUT"`  // Keep-alive connections waiting for the next request
+	ShutdownTimeout       int `env:"SHUTDOWN_TIMEOUT"`   // How long to wait for in-flight requests to finish on shutdown
+
+	// Logging
+	LogLevel           string `env:"LOG_LEVEL"`     // debug, info, warn or error; debug also logs every SQL query
+	LogFormat          string `env:"LOG_FORMAT"`    // json or text
+	SlowQueryThreshold int    `env:"SLOW_QUERY_MS"` // Milliseconds above which queries are logged as warnings
+
+	// Require a verified email address before checkout
+	RequireVerifiedEmail bool `env:"REQUIRE_VERIFIED_EMAIL"`
+
+	// Require staff and admin accounts to enrol in two-factor authentication
+	RequireStaff2FA bool   `env:"REQUIRE_STAFF_2FA"`
+	TOTPIssuer      string `env:"TOTP_ISSUER"` // Shown as the account label in authenticator apps
+
+	// OpenTelemetry tracing
+	TracingExporter    string  `env:"TRACING_EXPORTER"`     // none, otlp, stdout or file
+	TracingFile        string  `env:"TRACING_FILE"`         // Output path for the file exporter
+	TracingSampleRatio float64 `env:"TRACING_SAMPLE_RATIO"` // Fraction of new traces to record
+
+	// Health checks
+	HealthMinFreeMB int `env:"HEALTH_MIN_FREE_MB"` // Readiness fails when the database volume has less free space than this
+
+	// Prometheus metrics
+	MetricsAddr  string `env:"METRICS_ADDR"`                // Serve /metrics on this separate address instead of the API port, e.g. ":9090"
+	MetricsToken string `env:"METRICS_TOKEN" secret:"true"` // Bearer token required to scrape /metrics, if set
+
+	// Request rate limiting
+	RateLimitEnabled bool   `env:"RATE_LIMIT_ENABLED"`
+	APIKeys          string `env:"API_KEYS" secret:"true"` // Comma-separated X-API-Key values limited per key; other requests are limited per IP
+
+	// Comma-separated IPs or CIDRs of reverse proxies whose X-Forwarded-For
+	// and X-Real-IP headers are believed; empty trusts none, so the client IP
+	// is always the connecting address
+	TrustedProxies string `env:"TRUSTED_PROXIES"`
+
+	// Login brute-force protection
+	LoginBackoffThreshold int `env:"LOGIN_BACKOFF_THRESHOLD"` // Consecutive failures before back-off delays start
+	LoginMaxFailures      int `env:"LOGIN_MAX_FAILURES"`      // Consecutive failures before the username is locked out
+	LoginLockoutMinutes   int `env:"LOGIN_LOCKOUT_MINUTES"`
+	LoginIPMaxFailures    int `env:"LOGIN_IP_MAX_FAILURES"` // Failures from one IP within the lockout window before it is throttled
+
+	// Response caching for the public catalogue; a TTL of zero disables it
+	CatalogueCacheTTL  int `env:"CATALOGUE_CACHE_TTL"`  // Seconds, also sent as Cache-Control max-age
+	CatalogueCacheSize int `env:"CATALOGUE_CACHE_SIZE"` // Most responses held; the least recently used are evicted
+
+	// Most of one item a cart line may hold; zero means no limit
+	CartMaxQuantity int `env:"CART_MAX_QUANTITY"`
+
+	// Account deletion: deleted accounts are anonymised at once and purged after this many days
+	AccountPurgeDays int `env:"ACCOUNT_PURGE_DAYS"`
+
+	// Background jobs: number of workers running queued jobs
+	JobWorkers int `env:"JOB_WORKERS"`
+
+	// Outbound email
+	MailDriver   string `env:"MAIL_DRIVER"` // smtp, file or log
+	MailFrom     string `env:"MAIL_FROM"`
+	MailDir      string `env:"MAIL_DIR"` // Output directory for the file driver
+	SMTPHost     string `env:"SMTP_HOST"`
+	SMTPPort     string `env:"SMTP_PORT"`
+	SMTPUsername string `env:"SMTP_USERNAME"`
+	SMTPPassword string `env:"SMTP_PASSWORD" secret:"true"`
 }
 
 // AppConfig is the global configuration instance
 var AppConfig *Config
 
-// LoadConfig loads configuration from environment variables
-func LoadConfig() {
+// Defaults returns the configuration used when nothing else is set
+func Defaults() *Config {
+	return &Config{
+		Port:           "8080",
+		GinMode:        "debug",
+		DBPath:         "./shopease.db",
+		SeedProfile:    "test",
+		JWTSecret:      DefaultJWTSecret,
+		JWTExpiryHours: 24,
+		AllowedOrigins: "http://localhost:5173",
+
+		HTTPReadTimeout:       15,
+		HTTPReadHeaderTimeout: 5,
+		HTTPWriteTimeout:      30,
+		HTTPIdleTimeout:       60,
+		ShutdownTimeout:       30,
+
+		LogLevel:           "info",
+		LogFormat:          "json",
+		SlowQueryThreshold: 200,
+
+		RequireVerifiedEmail: false,
+
+		RequireStaff2FA: false,
+		TOTPIssuer:      "ShopEase",
+
+		TracingExporter:    "none",
+		TracingFile:        "./traces.jsonl",
+		TracingSampleRatio: 1.0,
+
+		HealthMinFreeMB: 100,
+
+		MetricsAddr:  "",
+		MetricsToken: "",
+
+		RateLimitEnabled: true,
+		APIKeys:          "",
+
+		TrustedProxies: "",
+
+		LoginBackoffThreshold: 3,
+		LoginMaxFailures:      10,
+		LoginLockoutMinutes:   15,
+		LoginIPMaxFailures:    50,
+
+		CatalogueCacheTTL:  60,
+		CatalogueCacheSize: 1000,
+
+		CartMaxQuantity: 99,
+
+		AccountPurgeDays: 30,
+
+		JobWorkers: 4,
+
+		MailDriver:   "log",
+		MailFrom:     "ShopEase <no-reply@shopease.local>",
+		MailDir:      "./mail",
+		SMTPHost:     "",
+		SMTPPort:     "587",
+		SMTPUsername: "",
+		SMTPPassword: "",
+	}
+}
+
+// APIKeyList returns the configured API keys
+func (c *Config) APIKeyList() []string {
+	return splitList(c.APIKeys)
+}
+
+// TrustedProxyList returns the configured trusted proxies, or nil for none
+func (c *Config) TrustedProxyList() []string {
+	return splitList(c.TrustedProxies)
+}
+
+// splitList splits a comma-separated setting, dropping blank entries
+func splitList(value string) []string {
+	var list []string
+	for _, entry := range strings.Split(value, ",") {
+		if entry = strings.TrimSpace(entry); entry != "" {
+			list = append(list, entry)
+		}
+	}
+	return list
+}
+
+// LoadConfig loads the configuration from the config file, environment
+// (including a .env file) and command-line flags, and validates it
+func LoadConfig(fs *flag.FlagSet, args []string) error {
 	// Load .env file
-	err := godotenv.Load()
-	if err != nil {
+	if err := godotenv.Load(); err != nil {
 		log.Println("Warning: .env file not found, using environment variables")
 	}
 
-	expiryHours, err := strconv.Atoi(getEnv("JWT_EXPIRY_HOURS", "24"))
+	cfg, err := Load(fs, args)
 	if err != nil {
-		expiryHours = 24
+		return err
 	}
-
-	AppConfig = &Config{
-		Port:           getEnv("PORT", "8080"),
-		GinMode:        getEnv("GIN_MODE", "debug"),
-		DBPath:         getEnv("DB_PATH", "./shopease.db"),
-		JWTSecret:      getEnv("JWT_SECRET", "default-secret-key"),
-		JWTExpiryHours: expiryHours,
-		AllowedOrigins: getEnv("ALLOWED_ORIGINS", "http://localhost:5173"),
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+	for _, warning := range cfg.Warnings() {
+		log.Printf("Warning: %s", warning)
 	}
 
+	AppConfig = cfg
 	log.Printf("Configuration loaded successfully")
 	log.Printf("Server will run on port: %s", AppConfig.Port)
-}
-
-// getEnv gets an environment variable with a default value
-func getEnv(key, defaultValue string) string {
-	value := os.Getenv(key)
-	if value == "" {
-		return defaultValue
-	}
-	return value
+	return nil
 }
@@ -0,0 +1,245 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// redacted replaces secret values in config dumps
+const redacted = "[REDACTED]"
+
+// field describes one configurable Config field
+type field struct {
+	index  int
+	env    string // Environment variable, e.g. SLOW_QUERY_MS
+	secret bool   // May be read from a file and is redacted in dumps
+}
+
+// key is the field's name in config files and dumps, e.g. slow_query_ms
+func (f field) key() string { return strings.ToLower(f.env) }
+
+// flag is the field's command-line flag, e.g. --slow-query-ms
+func (f field) flag() string { return strings.ReplaceAll(f.key(), "_", "-") }
+
+// fields lists every Config field that has an env tag, in declaration order
+func fields() []field {
+	t := reflect.TypeOf(Config{})
+	var result []field
+	for i := 0; i < t.NumField(); i++ {
+		tag := t.Field(i).Tag
+		if env := tag.Get("env"); env != "" {
+			result = append(result, field{index: i, env: env, secret: tag.Get("secret") == "true"})
+		}
+	}
+	return result
+}
+
+// Load builds a Config from, in increasing order of precedence: Defaults, the
+// YAML or TOML file named by --config or CONFIG_FILE, environment variables and
+// command-line flags. Every field can be set as an env var (JWT_EXPIRY_HOURS),
+// a file key (jwt_expiry_hours) or a flag (--jwt-expiry-hours). Secret fields
+// can instead be read from a file with the _FILE / _file / -file suffix, e.g.
+// JWT_SECRET_FILE=/run/secrets/jwt.
+//
+// The caller may register its own flags on fs before calling Load. Malformed
+// values are reported rather than silently replaced by defaults. Load does not
+// validate the result; see Validate.
+func Load(fs *flag.FlagSet, args []string) (*Config, error) {
+	cfg := Defaults()
+
+	configFile := fs.String("config", "", "path to a YAML or TOML config file (default $CONFIG_FILE)")
+	flags := map[string]string{}
+	for _, f := range fields() {
+		names := []string{f.flag()}
+		if f.secret {
+			names = append(names, f.flag()+"-file")
+		}
+		for _, name := range names {
+			name := name
+			fs.Func(name, "overrides "+strings.ToUpper(strings.ReplaceAll(name, "-", "_")), func(v string) error {
+				flags[name] = v
+				return nil
+			})
+		}
+	}
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+
+	var errs []error
+
+	path := *configFile
+	if path == "" {
+		path = os.Getenv("CONFIG_FILE")
+	}
+	if path != "" {
+		values, err := readConfigFile(path)
+		if err != nil {
+			return nil, err
+		}
+		errs = append(errs, cfg.apply(path, values, field.key, "_file")...)
+	}
+
+	env := map[string]string{}
+	for _, f := range fields() {
+		for _, name := range []string{f.env, f.env + "_FILE"} {
+			if v := os.Getenv(name); v != "" {
+				env[name] = v
+			}
+		}
+	}
+	errs = append(errs, cfg.apply("environment", env, func(f field) string { return f.env }, "_FILE")...)
+	errs = append(errs, cfg.apply("flags", flags, field.flag, "-file")...)
+
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// apply sets every field present in values, which are keyed by name(f).
+// Secret fields may instead be given as a path under name(f)+fileSuffix.
+// Keys that match no field are reported as errors.
+func (c *Config) apply(source string, values map[string]string, name func(field) string, fileSuffix string) []error {
+	var errs []error
+	known := map[string]bool{}
+	v := reflect.ValueOf(c).Elem()
+
+	for _, f := range fields() {
+		key := name(f)
+		known[key] = true
+		raw, ok := values[key]
+
+		if f.secret {
+			known[key+fileSuffix] = true
+			if path, fromFile := values[key+fileSuffix]; fromFile {
+				if ok {
+					errs = append(errs, fmt.Errorf("%s: %s and %s%s are both set", source, key, key, fileSuffix))
+					continue
+				}
+				secret, err := os.ReadFile(path)
+				if err != nil {
+					errs = append(errs, fmt.Errorf("%s: reading %s%s: %w", source, key, fileSuffix, err))
+					continue
+				}
+				raw, ok = strings.TrimSpace(string(secret)), true
+			}
+		}
+		if !ok {
+			continue
+		}
+
+		if err := setValue(v.Field(f.index), raw); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %s: %w", source, key, err))
+		}
+	}
+
+	var unknown []string
+	for key := range values {
+		if !known[key] {
+			unknown = append(unknown, key)
+		}
+	}
+	sort.Strings(unknown)
+	for _, key := range unknown {
+		errs = append(errs, fmt.Errorf("%s: unknown setting %q", source, key))
+	}
+	return errs
+}
+
+// setValue parses raw into a string, int, bool or float64 field
+func setValue(v reflect.Value, raw string) error {
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(raw)
+	case reflect.Int:
+		n, err := strconv.Atoi(raw)
+		if err != nil {
+			return fmt.Errorf("%q is not an integer", raw)
+		}
+		v.SetInt(int64(n))
+	case reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return fmt.Errorf("%q is not a boolean", raw)
+		}
+		v.SetBool(b)
+	case reflect.Float64:
+		n, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return fmt.Errorf("%q is not a number", raw)
+		}
+		v.SetFloat(n)
+	default:
+		return fmt.Errorf("unsupported field type %s", v.Kind())
+	}
+	return nil
+}
+
+// readConfigFile reads a flat YAML or TOML file, chosen by extension, into raw values
+func readConfigFile(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading config file: %w", err)
+	}
+
+	var doc map[string]interface{}
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &doc)
+	case ".toml":
+		err = toml.Unmarshal(data, &doc)
+	default:
+		return nil, fmt.Errorf("config file %s: unsupported format %q (want .yaml, .yml or .toml)", path, ext)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("parsing config file %s: %w", path, err)
+	}
+
+	values := make(map[string]string, len(doc))
+	for key, value := range doc {
+		switch value.(type) {
+		case map[string]interface{}, []interface{}:
+			return nil, fmt.Errorf("config file %s: %s must be a single value", path, key)
+		case nil:
+			values[key] = ""
+		default:
+			values[key] = fmt.Sprint(value)
+		}
+	}
+	return values, nil
+}
+
+// Redacted returns every setting keyed by its config file name, with secrets
+// masked, so the result can be shown to operators or saved as a config file
+func (c *Config) Redacted() map[string]interface{} {
+	v := reflect.ValueOf(c).Elem()
+	dump := make(map[string]interface{})
+	for _, f := range fields() {
+		value := v.Field(f.index).Interface()
+		if f.secret && value != "" {
+			value = redacted
+		}
+		dump[f.key()] = value
+	}
+	return dump
+}
+
+// Dump renders the redacted configuration as YAML
+func (c *Config) Dump() (string, error) {
+	out, err := yaml.Marshal(c.Redacted())
+	if err != nil {
+		return "", err
+	}
+	return string(out), nil
+}
@@ -0,0 +1,113 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// DefaultJWTSecret is the development signing key used when none is configured
+const DefaultJWTSecret = "default-secret-key"
+
+// MinJWTSecretLength is the shortest signing key accepted in release mode
+const MinJWTSecretLength = 32
+
+// IsRelease reports whether the server runs in Gin's release mode
+func (c *Config) IsRelease() bool {
+	return c.GinMode == "release"
+}
+
+// Validate checks every setting and returns all problems found at once.
+// Release mode additionally refuses insecure secrets and wildcard CORS.
+func (c *Config) Validate() error {
+	var errs []error
+	check := func(ok bool, format string, args ...interface{}) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+	oneOf := func(key, value string, allowed ...string) {
+		for _, a := range allowed {
+			if value == a {
+				return
+			}
+		}
+		errs = append(errs, fmt.Errorf("%s: %q must be one of %s", key, value, strings.Join(allowed, ", ")))
+	}
+
+	port, err := strconv.Atoi(c.Port)
+	check(err == nil && port > 0 && port < 65536, "port: %q is not a valid TCP port", c.Port)
+	oneOf("gin_mode", c.GinMode, "debug", "release", "test")
+	check(c.DBPath != "", "db_path: must be set")
+	oneOf("seed_profile", c.SeedProfile, "test", "dev", "demo", "none")
+	check(c.JWTSecret != "", "jwt_secret: must be set")
+	check(c.JWTExpiryHours > 0, "jwt_expiry_hours: must be positive")
+
+	for key, seconds := range map[string]int{
+		"http_read_timeout":        c.HTTPReadTimeout,
+		"http_read_header_timeout": c.HTTPReadHeaderTimeout,
+		"http_write_timeout":       c.HTTPWriteTimeout,
+		"http_idle_timeout":        c.HTTPIdleTimeout,
+		"shutdown_timeout":         c.ShutdownTimeout,
+	} {
+		check(seconds >= 0, "%s: must not be negative", key)
+	}
+
+	oneOf("log_level", strings.ToLower(c.LogLevel), "debug", "info", "warn", "error")
+	oneOf("log_format", c.LogFormat, "json", "text")
+	check(c.SlowQueryThreshold >= 0, "slow_query_ms: must not be negative")
+
+	oneOf("tracing_exporter", c.TracingExporter, "none", "otlp", "stdout", "file")
+	check(c.TracingSampleRatio >= 0 && c.TracingSampleRatio <= 1, "tracing_sample_ratio: must be between 0 and 1")
+	check(c.HealthMinFreeMB >= 0, "health_min_free_mb: must not be negative")
+
+	check(c.LoginBackoffThreshold >= 0, "login_backoff_threshold: must not be negative")
+	check(c.LoginMaxFailures >= 0, "login_max_failures: must not be negative")
+	check(c.LoginLockoutMinutes >= 0, "login_lockout_minutes: must not be negative")
+	check(c.LoginIPMaxFailures >= 0, "login_ip_max_failures: must not be negative")
+	check(c.CatalogueCacheTTL >= 0, "catalogue_cache_ttl: must not be negative")
+	check(c.CatalogueCacheSize > 0, "catalogue_cache_size: must be positive")
+	check(c.CartMaxQuantity >= 0, "cart_max_quantity: must not be negative")
+	check(c.AccountPurgeDays >= 0, "account_purge_days: must not be negative")
+	check(c.JobWorkers > 0, "job_workers: must be positive")
+
+	for _, proxy := range c.TrustedProxyList() {
+		_, _, cidrErr := net.ParseCIDR(proxy)
+		check(cidrErr == nil || net.ParseIP(proxy) != nil, "trusted_proxies: %q is not an IP address or CIDR range", proxy)
+	}
+
+	oneOf("mail_driver", c.MailDriver, "log", "file", "smtp")
+	check(c.MailDriver != "smtp" || c.SMTPHost != "", "smtp_host: required when mail_driver is smtp")
+
+	if c.IsRelease() {
+		check(c.JWTSecret != DefaultJWTSecret, "jwt_secret: the default secret is not allowed in release mode")
+		check(len(c.JWTSecret) >= MinJWTSecretLength, "jwt_secret: must be at least %d bytes in release mode", MinJWTSecretLength)
+		check(!c.wildcardOrigins(), "allowed_origins: wildcard CORS is not allowed in release mode")
+		check(c.SeedProfile != "dev" && c.SeedProfile != "demo", "seed_profile: %s creates accounts with published passwords; use test or none in release mode", c.SeedProfile)
+	}
+
+	return errors.Join(errs...)
+}
+
+// Warnings lists insecure settings that Validate tolerates outside release mode
+func (c *Config) Warnings() []string {
+	var warnings []string
+	if c.JWTSecret == DefaultJWTSecret {
+		warnings = append(warnings, "jwt_secret is the built-in default; set JWT_SECRET or JWT_SECRET_FILE")
+	}
+	if c.wildcardOrigins() {
+		warnings = append(warnings, "allowed_origins allows any origin")
+	}
+	return warnings
+}
+
+func (c *Config) wildcardOrigins() bool {
+	for _, origin := range strings.Split(c.AllowedOrigins, ",") {
+		if strings.TrimSpace(origin) == "*" {
+			return true
+		}
+	}
+	return false
+}
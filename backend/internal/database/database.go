@@ -1,243 +1,296 @@
 package database
 
 import (
+	"context"
+	"fmt"
 	"log"
+	"net/url"
+	"strings"
+	"time"
 
 	"shopease/internal/config"
+	"shopease/internal/logging"
+	"shopease/internal/metrics"
 	"shopease/internal/models"
+	"shopease/internal/tracing"
 
 	"github.com/glebarez/sqlite"
 	"gorm.io/gorm"
-	"gorm.io/gorm/logger"
 )
 
 // DB is the global database instance
 var DB *gorm.DB
 
+// SchemaVersion is the schema this build expects. Bump it when Migrate gains
+// a step so readiness checks can tell when a database hasn't been migrated.
+const SchemaVersion = 14
+
+// busyTimeout is how long a connection waits for another's write lock before
+// failing with "database is locked"
+const busyTimeout = 5 * time.Second
+
+// SchemaMigration records each schema version applied to the database
+type SchemaMigration struct {
+	Version   int `gorm:"primaryKey;autoIncrement:false"`
+	AppliedAt time.Time
+}
+
+// TableName specifies the table name for GORM
+func (SchemaMigration) TableName() string {
+	return "schema_migrations"
+}
+
 // Connect establishes a connection to the database
 func Connect() error {
 	var err error
 
-	// Configure GORM logger
+	// Route GORM logs through the application logger
+	slowThreshold := logging.DefaultSlowQueryThreshold
+	if config.AppConfig.SlowQueryThreshold > 0 {
+		slowThreshold = time.Duration(config.AppConfig.SlowQueryThreshold) * time.Millisecond
+	}
 	gormConfig := &gorm.Config{
-		Logger: logger.Default.LogMode(logger.Info),
+		Logger: logging.NewGormLogger(slowThreshold),
 	}
 
 	// Connect to SQLite database
-	DB, err = gorm.Open(sqlite.Open(config.AppConfig.DBPath), gormConfig)
+	DB, err = gorm.Open(sqlite.Open(dsn(config.AppConfig.DBPath)), gormConfig)
+	if err != nil {
+		return err
+	}
+
+	// Trace queries run with a request context
+	if err := DB.Use(tracing.GormPlugin{}); err != nil {
+		return err
+	}
+
+	sqlDB, err := DB.DB()
 	if err != nil {
 		return err
 	}
+	if isMemory(config.AppConfig.DBPath) {
+		// Every connection to :memory: opens its own empty database, so the
+		// pool must keep the one that was migrated and never open another
+		sqlDB.SetMaxOpenConns(1)
+		sqlDB.SetMaxIdleConns(1)
+		sqlDB.SetConnMaxLifetime(0)
+		sqlDB.SetConnMaxIdleTime(0)
+	}
+	if err := metrics.RegisterDB(sqlDB); err != nil {
+		log.Printf("Warning: failed to register database metrics: %v", err)
+	}
 
 	log.Printf("Database connected successfully: %s", config.AppConfig.DBPath)
 	return nil
 }
 
+// dsn adds the connection pragmas to a database path: a busy timeout, so
+// background workers and requests writing at once wait for each other, and
+// write-ahead logging for files, so reads don't block on a long write
+func dsn(path string) string {
+	params := url.Values{}
+	params.Add("_pragma", fmt.Sprintf("busy_timeout(%d)", busyTimeout.Milliseconds()))
+	if !isMemory(path) {
+		params.Add("_pragma", "journal_mode(WAL)")
+	}
+
+	separator := "?"
+	if strings.Contains(path, "?") {
+		separator = "&"
+	}
+	return path + separator + params.Encode()
+}
+
+// isMemory reports whether path names an in-memory database
+func isMemory(path string) bool {
+	return path == ":memory:" || strings.Contains(path, "mode=memory")
+}
+
 // Migrate runs database migrations
 func Migrate() error {
 	log.Println("Running database migrations...")
 
 	err := DB.AutoMigrate(
+		&SchemaMigration{},
 		&models.User{},
 		&models.Item{},
+		&models.ItemPrice{},
 		&models.Cart{},
 		&models.CartItem{},
 		&models.Order{},
 		&models.OrderItem{},
+		&models.Review{},
+		&models.ReviewVote{},
+		&models.Wishlist{},
+		&models.WishlistItem{},
+		&models.ItemAlert{},
+		&models.Notification{},
+		&models.UserToken{},
+		&models.RecoveryCode{},
+		&models.LoginAttempt{},
+		&models.AuditEvent{},
+		&models.Job{},
+		&models.OutboxEvent{},
 	)
 
 	if err != nil {
 		return err
 	}
 
+	if err := migrateFavorites(); err != nil {
+		return err
+	}
+
+	if err := uniqueDefaultWishlists(); err != nil {
+		return err
+	}
+
+	if err := protectAuditLog(); err != nil {
+		return err
+	}
+
+	if err := backfillItemPrices(); err != nil {
+		return err
+	}
+
+	if err := backfillCartPrices(); err != nil {
+		return err
+	}
+
+	applied := SchemaMigration{Version: SchemaVersion, AppliedAt: time.Now()}
+	if err := DB.Where("version = ?", SchemaVersion).FirstOrCreate(&applied).Error; err != nil {
+		return err
+	}
+
 	log.Println("Database migrations completed successfully")
 	return nil
 }
 
-// SeedItems seeds some initial items for testing
-func SeedItems() error {
-	var count int64
-	DB.Model(&models.Item{}).Count(&count)
+// CurrentSchemaVersion returns the newest schema version applied to the database
+func CurrentSchemaVersion(ctx context.Context) (int, error) {
+	var version int
+	err := DB.WithContext(ctx).Model(&SchemaMigration{}).Select("COALESCE(MAX(version), 0)").Scan(&version).Error
+	return version, err
+}
+
+// AppliedMigrations lists the schema versions applied to the database, oldest first
+func AppliedMigrations(ctx context.Context) ([]SchemaMigration, error) {
+	var applied []SchemaMigration
+	err := DB.WithContext(ctx).Order("version").Find(&applied).Error
+	return applied, err
+}
 
-	if count > 0 {
-		log.Println("Items already exist, skipping seed")
+// migrateFavorites moves rows from the legacy user_favorites join table
+// into each user's default wishlist and drops the old table
+func migrateFavorites() error {
+	if !DB.Migrator().HasTable("user_favorites") {
 		return nil
 	}
 
-	log.Println("Seeding initial items...")
-
-	items := []models.Item{
-		{
-			Name:        "Wireless Bluetooth Headphones",
-			Description: "Premium noise-cancelling headphones with 30hr battery life",
-			Price:       149.99,
-			ImageURL:    "https://images.unsplash.com/photo-1505740420928-5e560c06d30e?w=400",
-			Category:    "Electronics",
-			IsActive:    true,
-		},
-		{
-			Name:        "Smart Watch Pro",
-			Description: "Fitness tracker with heart rate monitor and GPS",
-			Price:       299.99,
-			ImageURL:    "https://images.unsplash.com/photo-1523275335684-37898b6baf30?w=400",
-			Category:    "Electronics",
-			IsActive:    true,
-		},
-		{
-			Name:        "Laptop Backpack",
-			Description: "Water-resistant backpack with USB charging port",
-			Price:       59.99,
-			ImageURL:    "https://images.unsplash.com/photo-1553062407-98eeb64c6a62?w=400",
-			Category:    "Accessories",
-			IsActive:    true,
-		},
-		{
-			Name:        "Mechanical Keyboard",
-			Description: "RGB gaming keyboard with Cherry MX switches",
-			Price:       129.99,
-			ImageURL:    "https://images.unsplash.com/photo-1511467687858-23d96c32e4ae?w=400",
-			Category:    "Electronics",
-			IsActive:    true,
-		},
-		{
-			Name:        "Wireless Mouse",
-			Description: "Ergonomic wireless mouse with precision tracking",
-			Price:       49.99,
-			ImageURL:    "https://images.unsplash.com/photo-1527864550417-7fd91fc51a46?w=400",
-			Category:    "Electronics",
-			IsActive:    true,
-		},
-		{
-			Name:        "USB-C Hub",
-			Description: "7-in-1 USB-C hub with HDMI and card reader",
-			Price:       39.99,
-			ImageURL:    "https://images.unsplash.com/photo-1625723044792-44de16ccb4e9?w=400",
-			Category:    "Accessories",
-			IsActive:    true,
-		},
-		{
-			Name:        "Portable Charger",
-			Description: "20000mAh power bank with fast charging",
-			Price:       34.99,
-			ImageURL:    "https://images.unsplash.com/photo-1609091839311-d5365f9ff1c5?w=400",
-			Category:    "Electronics",
-			IsActive:    true,
-		},
-		{
-			Name:        "Webcam HD Pro",
-			Description: "1080p webcam with built-in microphone",
-			Price:       79.99,
-			ImageURL:    "https://images.unsplash.com/photo-1587826080692-f439cd0b70da?w=400",
-			Category:    "Electronics",
-			IsActive:    true,
-		},
-		{
-			Name:        "Desk Lamp LED",
-			Description: "Adjustable LED desk lamp with touch control",
-			Price:       29.99,
-			ImageURL:    "https://images.unsplash.com/photo-1507473885765-e6ed057f782c?w=400",
-			Category:    "Home",
-			IsActive:    true,
-		},
-		{
-			Name:        "Coffee Mug Warmer",
-			Description: "Electric mug warmer with auto shut-off",
-			Price:       24.99,
-			ImageURL:    "https://images.unsplash.com/photo-1514228742587-6b1558fcca3d?w=400",
-			Category:    "Home",
-			IsActive:    true,
-		},
-		{
-			Name:        "Notebook Set",
-			Description: "Premium leather-bound notebook with pen",
-			Price:       19.99,
-			ImageURL:    "https://images.unsplash.com/photo-1531346878377-a5be20888e57?w=400",
-			Category:    "Office",
-			IsActive:    true,
-		},
-		{
-			Name:        "Phone Stand",
-			Description: "Adjustable aluminum phone and tablet stand",
-			Price:       15.99,
-			ImageURL:    "https://images.unsplash.com/photo-1586105251261-72a756497a11?w=400",
-			Category:    "Accessories",
-			IsActive:    true,
-		},
-		{
-			Name:        "Smart Thermostat",
-			Description: "Wi-Fi enabled smart thermostat for home automation",
-			Price:       199.99,
-			ImageURL:    "https://images.unsplash.com/photo-1563461661026-6b2c5c9930f7?w=400",
-			Category:    "Home",
-			IsActive:    true,
-		},
-		{
-			Name:        "Gaming Headset",
-			Description: "Surround sound gaming headset with microphone",
-			Price:       89.99,
-			ImageURL:    "https://images.unsplash.com/photo-1618366712010-f4ae9c647dcb?w=400",
-			Category:    "Electronics",
-			IsActive:    true,
-		},
-		{
-			Name:        "External SSD 1TB",
-			Description: "High-speed portable external solid state drive",
-			Price:       129.99,
-			ImageURL:    "https://images.unsplash.com/photo-1597872252721-24642f56f180?w=400",
-			Category:    "Electronics",
-			IsActive:    true,
-		},
-		{
-			Name:        "Bluetooth Speaker",
-			Description: "Portable waterproof bluetooth speaker",
-			Price:       79.99,
-			ImageURL:    "https://images.unsplash.com/photo-1608043152269-423dbba4e7e1?w=400",
-			Category:    "Electronics",
-			IsActive:    true,
-		},
-		{
-			Name:        "Monitor Stand",
-			Description: "Dual monitor mount with gas spring arms",
-			Price:       69.99,
-			ImageURL:    "https://images.unsplash.com/photo-1593640408182-31c70c8268f5?w=400",
-			Category:    "Office",
-			IsActive:    true,
-		},
-		{
-			Name:        "Wireless Charger",
-			Description: "Fast wireless charging pad for smartphones",
-			Price:       29.99,
-			ImageURL:    "https://images.unsplash.com/photo-1586953208448-b95a79798f07?w=400",
-			Category:    "Accessories",
-			IsActive:    true,
-		},
-		{
-			Name:        "Drone Camera",
-			Description: "4K camera drone with stabilization",
-			Price:       499.99,
-			ImageURL:    "https://images.unsplash.com/photo-1507582020474-9a35b7d450d7?w=400",
-			Category:    "Electronics",
-			IsActive:    true,
-		},
-		{
-			Name:        "VR Headset",
-			Description: "Virtual reality headset with controllers",
-			Price:       399.99,
-			ImageURL:    "https://images.unsplash.com/photo-1622979135225-d2ba269fb1bd?w=400",
-			Category:    "Electronics",
-			IsActive:    true,
-		},
-
-	}
-	
-	for _, item := range items {
-		if err := DB.Create(&item).Error; err != nil {
-			log.Printf("Error seeding item %s: %v", item.Name, err)
+	log.Println("Migrating legacy favorites to wishlists...")
+
+	var rows []struct {
+		UserID uint
+		ItemID uint
+	}
+	if err := DB.Table("user_favorites").Select("user_id, item_id").Scan(&rows).Error; err != nil {
+		return err
+	}
+
+	return DB.Transaction(func(tx *gorm.DB) error {
+		defaults := make(map[uint]uint)
+		positions := make(map[uint]int)
+
+		for _, row := range rows {
+			wishlistID, ok := defaults[row.UserID]
+			if !ok {
+				wishlist := models.Wishlist{UserID: row.UserID, Name: models.DefaultWishlistName, IsDefault: true}
+				if err := tx.Where("user_id = ? AND is_default = ?", row.UserID, true).FirstOrCreate(&wishlist).Error; err != nil {
+					return err
+				}
+				wishlistID = wishlist.ID
+				defaults[row.UserID] = wishlistID
+			}
+
+			wishlistItem := models.WishlistItem{WishlistID: wishlistID, ItemID: row.ItemID, Position: positions[wishlistID]}
+			if err := tx.Where("wishlist_id = ? AND item_id = ?", wishlistID, row.ItemID).FirstOrCreate(&wishlistItem).Error; err != nil {
+				return err
+			}
+			positions[wishlistID]++
 		}
+
+		return tx.Migrator().DropTable("user_favorites")
+	})
+}
+
+// uniqueDefaultWishlists keeps one default wishlist per user in the database
+// itself, so concurrent first requests can't each create one. Extra defaults
+// left by earlier races become ordinary lists first.
+func uniqueDefaultWishlists() error {
+	return DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec(`UPDATE wishlists SET is_default = false
+			WHERE is_default AND deleted_at IS NULL AND id > (
+				SELECT MIN(w.id) FROM wishlists w
+				WHERE w.user_id = wishlists.user_id AND w.is_default AND w.deleted_at IS NULL
+			)`).Error; err != nil {
+			return err
+		}
+		return tx.Exec(`CREATE UNIQUE INDEX IF NOT EXISTS idx_wishlists_user_default
+			ON wishlists (user_id) WHERE is_default AND deleted_at IS NULL`).Error
+	})
+}
+
+// protectAuditLog makes audit_events append-only in the database itself, so
+// raw SQL can't quietly rewrite history either. An event may be updated only
+// while its hash is unset, which audit.Record does once, straight after insert.
+func protectAuditLog() error {
+	return DB.Transaction(func(tx *gorm.DB) error {
+		for _, statement := range []string{
+			`CREATE TRIGGER IF NOT EXISTS audit_events_no_update BEFORE UPDATE ON audit_events
+			WHEN OLD.hash IS NOT NULL AND OLD.hash <> ''
+			BEGIN SELECT RAISE(ABORT, 'audit events are append-only'); END`,
+			`CREATE TRIGGER IF NOT EXISTS audit_events_no_delete BEFORE DELETE ON audit_events
+			BEGIN SELECT RAISE(ABORT, 'audit events are append-only'); END`,
+		} {
+			if err := tx.Exec(statement).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// backfillItemPrices starts the price history of items created before it
+// existed with their current price, effective from when they were created
+func backfillItemPrices() error {
+	var items []models.Item
+	if err := DB.Unscoped().Select("id, price, created_at").
+		Where("id NOT IN (?)", DB.Model(&models.ItemPrice{}).Select("item_id")).
+		Find(&items).Error; err != nil {
+		return err
+	}
+	if len(items) == 0 {
+		return nil
 	}
 
-	log.Println("Items seeded successfully")
-	return nil
+	log.Printf("Starting price history for %d items...", len(items))
+	prices := make([]models.ItemPrice, len(items))
+	for i, item := range items {
+		prices[i] = models.ItemPrice{
+			ItemID:        item.ID,
+			Kind:          models.PriceKindRegular,
+			Price:         item.Price,
+			EffectiveFrom: item.CreatedAt.UTC(),
+		}
+	}
+	return DB.CreateInBatches(prices, 500).Error
+}
+
+// backfillCartPrices sets the remembered price of cart lines added before it
+// was tracked to the item's current price, so later changes are reported
+func backfillCartPrices() error {
+	return DB.Exec(`UPDATE cart_items SET price_at_add = (SELECT price FROM items WHERE items.id = cart_items.item_id)
+		WHERE price_at_add IS NULL`).Error
 }
 
 // Close closes the database connection
@@ -0,0 +1,258 @@
+package events
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"shopease/internal/database"
+	"shopease/internal/health"
+	"shopease/internal/metrics"
+	"shopease/internal/models"
+)
+
+const (
+	pollInterval    = time.Second
+	batchSize       = 100
+	deliveryTimeout = 30 * time.Second
+	baseBackoff     = time.Second
+	maxBackoff      = 5 * time.Minute
+
+	// MaxAttempts is how many deliveries of an event fail before it is dead,
+	// about an hour of retries with the back-off above
+	MaxAttempts = 20
+
+	// Dispatched events are kept this long for inspection, then deleted
+	retention      = 7 * 24 * time.Hour
+	pruneInterval  = time.Hour
+	maxErrorLength = 2000
+)
+
+var (
+	mu      sync.Mutex
+	stop    chan struct{}
+	stopped sync.WaitGroup
+
+	// wakeup lets Publish start a pass without waiting for the next poll
+	wakeup = make(chan struct{}, 1)
+
+	// Heartbeat is beaten after every pass so health checks can detect a stalled dispatcher
+	Heartbeat health.Heartbeat
+)
+
+// Start launches the dispatcher. There is one per process, which is what
+// keeps each aggregate's events in order; its first pass delivers anything
+// left pending when the server last stopped.
+func Start() {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if stop != nil {
+		return
+	}
+	stop = make(chan struct{})
+
+	stopped.Add(1)
+	go run(stop)
+
+	log.Println("Event dispatcher started")
+}
+
+// Stop waits for any pass in progress and stops the dispatcher. Pending
+// events stay in the outbox for the next start.
+func Stop() {
+	mu.Lock()
+	if stop == nil {
+		mu.Unlock()
+		return
+	}
+	close(stop)
+	stop = nil
+	mu.Unlock()
+
+	stopped.Wait()
+}
+
+// run dispatches pending events and prunes old ones until stop, after each
+// poll or wake-up
+func run(stop chan struct{}) {
+	defer stopped.Done()
+
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	var pruned time.Time
+	for {
+		if _, err := Dispatch(context.Background()); err != nil {
+			log.Printf("Error dispatching events: %v", err)
+		}
+		if time.Since(pruned) >= pruneInterval {
+			if err := prune(); err != nil {
+				log.Printf("Error pruning dispatched events: %v", err)
+			}
+			pruned = time.Now()
+		}
+		Heartbeat.Beat()
+
+		select {
+		case <-ticker.C:
+		case <-wakeup:
+		case <-stop:
+			return
+		}
+	}
+}
+
+// wake nudges the dispatcher to look for pending events
+func wake() {
+	select {
+	case wakeup <- struct{}{}:
+	default:
+	}
+}
+
+// aggregate identifies the stream an event is ordered within
+type aggregate struct {
+	kind string
+	id   uint
+}
+
+// Dispatch delivers pending events in ID order and returns how many were
+// delivered. Once an aggregate's event fails or is waiting to be retried,
+// its later events are held back until it has been delivered or is dead.
+func Dispatch(ctx context.Context) (int, error) {
+	now := time.Now()
+	blocked := map[aggregate]bool{}
+	delivered := 0
+
+	var after uint
+	for {
+		var pending []models.OutboxEvent
+		if err := database.DB.WithContext(ctx).
+			Where("dispatched_at IS NULL AND dead_at IS NULL AND id > ?", after).
+			Order("id").Limit(batchSize).
+			Find(&pending).Error; err != nil {
+			return delivered, err
+		}
+
+		for i := range pending {
+			event := &pending[i]
+			after = event.ID
+
+			key := aggregate{event.AggregateType, event.AggregateID}
+			if blocked[key] {
+				continue
+			}
+			if event.NextAttemptAt != nil && event.NextAttemptAt.After(now) {
+				blocked[key] = true
+				continue
+			}
+
+			ok, err := dispatchOne(ctx, event)
+			if err != nil {
+				return delivered, err
+			}
+			if !ok {
+				blocked[key] = true
+				continue
+			}
+			delivered++
+		}
+
+		if len(pending) < batchSize {
+			return delivered, nil
+		}
+	}
+}
+
+// dispatchOne delivers event to its subscribers and records the outcome,
+// reporting whether it was delivered. Only failing to record is an error.
+func dispatchOne(ctx context.Context, event *models.OutboxEvent) (bool, error) {
+	err := deliver(ctx, event)
+	attempts := event.Attempts + 1
+	now := time.Now().UTC()
+
+	changes := map[string]interface{}{"attempts": attempts}
+	if err == nil {
+		changes["dispatched_at"] = &now
+		changes["last_error"] = ""
+		changes["next_attempt_at"] = nil
+		metrics.EventsDispatched.WithLabelValues(event.Type, "delivered").Inc()
+	} else {
+		message := err.Error()
+		if len(message) > maxErrorLength {
+			message = message[:maxErrorLength]
+		}
+		changes["last_error"] = message
+		if attempts >= MaxAttempts {
+			changes["dead_at"] = &now
+			changes["next_attempt_at"] = nil
+			metrics.EventsDispatched.WithLabelValues(event.Type, "dead").Inc()
+			log.Printf("Error delivering %s event %d, giving up after %d attempts: %v", event.Type, event.ID, attempts, err)
+		} else {
+			backoff := Backoff(attempts)
+			changes["next_attempt_at"] = now.Add(backoff).UTC()
+			metrics.EventsDispatched.WithLabelValues(event.Type, "failed").Inc()
+			log.Printf("Error delivering %s event %d (attempt %d), retrying in %s: %v", event.Type, event.ID, attempts, backoff, err)
+		}
+	}
+
+	if err := database.DB.WithContext(ctx).Model(&models.OutboxEvent{}).Where("id = ?", event.ID).Updates(changes).Error; err != nil {
+		return false, fmt.Errorf("recording delivery of event %d: %w", event.ID, err)
+	}
+	return err == nil, nil
+}
+
+// deliver hands event to each subscriber in turn, stopping at the first
+// failure. A retry starts again from the first subscriber.
+func deliver(ctx context.Context, event *models.OutboxEvent) error {
+	env := Envelope{
+		ID:            event.ID,
+		Type:          event.Type,
+		AggregateType: event.AggregateType,
+		AggregateID:   event.AggregateID,
+		OccurredAt:    event.CreatedAt,
+	}
+	for _, s := range subscribersOf(event.Type) {
+		if err := call(ctx, s, env, event); err != nil {
+			return fmt.Errorf("%s: %w", s.name, err)
+		}
+	}
+	return nil
+}
+
+// call runs one subscriber with a timeout, turning panics into errors
+func call(ctx context.Context, s subscriber, env Envelope, event *models.OutboxEvent) (err error) {
+	ctx, cancel := context.WithTimeout(ctx, deliveryTimeout)
+	defer cancel()
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return s.deliver(ctx, env, event.Payload)
+}
+
+// Backoff is the wait before redelivering an event that has failed attempts times
+func Backoff(attempts int) time.Duration {
+	if attempts < 1 {
+		return 0
+	}
+	backoff := baseBackoff
+	for i := 1; i < attempts && backoff < maxBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > maxBackoff {
+		backoff = maxBackoff
+	}
+	return backoff
+}
+
+// prune deletes events dispatched longer ago than the retention period
+func prune() error {
+	return database.DB.
+		Where("dispatched_at IS NOT NULL AND dispatched_at < ?", time.Now().Add(-retention).UTC()).
+		Delete(&models.OutboxEvent{}).Error
+}
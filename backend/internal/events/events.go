@@ -0,0 +1,156 @@
+// Package events implements a transactional outbox for domain events. State
+// changes publish events with the transaction that makes them, so an event
+// exists exactly when its change committed; the dispatcher then delivers
+// each event at least once to every subscriber of its type, in order per
+// aggregate. Subscribers must therefore tolerate seeing an event twice.
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"shopease/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// Aggregates that events are ordered by
+const (
+	AggregateOrder = "order"
+	AggregateItem  = "item"
+	AggregateUser  = "user"
+)
+
+// Event is a domain event that can be published to the outbox
+type Event interface {
+	EventType() string
+	AggregateID() uint
+	AggregateType() string
+}
+
+// OrderPlaced is published when a customer checks out
+type OrderPlaced struct {
+	OrderID     uint    `json:"order_id"`
+	UserID      uint    `json:"user_id"`
+	TotalAmount float64 `json:"total_amount"`
+	ItemCount   int     `json:"item_count"`
+}
+
+func (OrderPlaced) EventType() string     { return "order.placed" }
+func (e OrderPlaced) AggregateID() uint   { return e.OrderID }
+func (OrderPlaced) AggregateType() string { return AggregateOrder }
+
+// OrderCancelled is published when an order moves to cancelled, by the customer or an operator
+type OrderCancelled struct {
+	OrderID        uint               `json:"order_id"`
+	UserID         uint               `json:"user_id"`
+	PreviousStatus models.OrderStatus `json:"previous_status"`
+}
+
+func (OrderCancelled) EventType() string     { return "order.cancelled" }
+func (e OrderCancelled) AggregateID() uint   { return e.OrderID }
+func (OrderCancelled) AggregateType() string { return AggregateOrder }
+
+// ItemPriceChanged is published when the price in effect for an item changes
+type ItemPriceChanged struct {
+	ItemID   uint    `json:"item_id"`
+	OldPrice float64 `json:"old_price"`
+	NewPrice float64 `json:"new_price"`
+}
+
+func (ItemPriceChanged) EventType() string     { return "item.price_changed" }
+func (e ItemPriceChanged) AggregateID() uint   { return e.ItemID }
+func (ItemPriceChanged) AggregateType() string { return AggregateItem }
+
+// ItemRestocked is published when an inactive or deleted item becomes available again
+type ItemRestocked struct {
+	ItemID uint    `json:"item_id"`
+	Price  float64 `json:"price"`
+}
+
+func (ItemRestocked) EventType() string     { return "item.restocked" }
+func (e ItemRestocked) AggregateID() uint   { return e.ItemID }
+func (ItemRestocked) AggregateType() string { return AggregateItem }
+
+// UserRegistered is published when a customer signs up
+type UserRegistered struct {
+	UserID uint `json:"user_id"`
+}
+
+func (UserRegistered) EventType() string     { return "user.registered" }
+func (e UserRegistered) AggregateID() uint   { return e.UserID }
+func (UserRegistered) AggregateType() string { return AggregateUser }
+
+// Publish writes event to the outbox using tx, so it is delivered only if
+// the transaction commits
+func Publish(tx *gorm.DB, event Event) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("encoding %s event: %w", event.EventType(), err)
+	}
+
+	if err := tx.Create(&models.OutboxEvent{
+		Type:          event.EventType(),
+		AggregateType: event.AggregateType(),
+		AggregateID:   event.AggregateID(),
+		Payload:       payload,
+	}).Error; err != nil {
+		return err
+	}
+
+	wake()
+	return nil
+}
+
+// Envelope describes a delivered event. ID is stable across redeliveries,
+// so subscribers can use it to ignore duplicates.
+type Envelope struct {
+	ID            uint
+	Type          string
+	AggregateType string
+	AggregateID   uint
+	OccurredAt    time.Time
+}
+
+// subscriber receives events of one type with their raw payload
+type subscriber struct {
+	name    string
+	deliver func(ctx context.Context, env Envelope, payload json.RawMessage) error
+}
+
+var (
+	subscribersMu sync.RWMutex
+	subscribers   = map[string][]subscriber{}
+)
+
+// Subscribe registers fn, under name for logs and errors, to receive every
+// event of type T. Call it at startup, before Start. Subscribers of a type
+// are called in the order they subscribed.
+func Subscribe[T Event](name string, fn func(ctx context.Context, env Envelope, event T) error) {
+	var zero T
+	kind := zero.EventType()
+
+	subscribersMu.Lock()
+	defer subscribersMu.Unlock()
+
+	subscribers[kind] = append(subscribers[kind], subscriber{
+		name: name,
+		deliver: func(ctx context.Context, env Envelope, payload json.RawMessage) error {
+			var event T
+			if err := json.Unmarshal(payload, &event); err != nil {
+				return fmt.Errorf("decoding %s event: %w", kind, err)
+			}
+			return fn(ctx, env, event)
+		},
+	})
+}
+
+// subscribersOf returns the subscribers to events of kind
+func subscribersOf(kind string) []subscriber {
+	subscribersMu.RLock()
+	defer subscribersMu.RUnlock()
+	return subscribers[kind]
+}
@@ -0,0 +1,255 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"shopease/internal/audit"
+	"shopease/internal/database"
+	"shopease/internal/middleware"
+	"shopease/internal/models"
+	"shopease/internal/notify"
+	"shopease/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// errInvalidUserToken is returned when a token is unknown, expired, used or for another purpose
+var errInvalidUserToken = errors.New("invalid or expired token")
+
+// ForgotPassword handles POST /users/password/forgot - Email a password reset code
+// @Summary Request password reset
+// @Description Email a single-use reset code to the account's address.
+// @Description Always succeeds so the endpoint can't be used to discover accounts.
+// @Tags users
+// @Accept json
+// @Produce json
+// @Param request body models.ForgotPasswordRequest true "Username or email"
+// @Success 200 {object} utils.Response
+// @Router /users/password/forgot [post]
+func (h *UserHandler) ForgotPassword(c *gin.Context) {
+	var req models.ForgotPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ValidationErrorResponse(c, "Invalid request data", err.Error())
+		return
+	}
+
+	query := database.DB.Where("email <> ''")
+	if req.Username != "" {
+		query = query.Where("username = ?", req.Username)
+	} else {
+		query = query.Where("email = ?", req.Email)
+	}
+
+	var user models.User
+	if err := query.First(&user).Error; err == nil {
+		err := database.DB.Transaction(func(tx *gorm.DB) error {
+			if err := notify.Send(tx, notify.Mail{Template: notify.TemplatePasswordReset, UserID: user.ID}); err != nil {
+				return err
+			}
+			return audit.Record(tx, auditActor(c), audit.Event{Action: audit.ActionPasswordResetRequested, TargetType: audit.TargetUser, TargetID: user.ID})
+		})
+		if err != nil {
+			utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to start password reset")
+			return
+		}
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "If the account exists, a reset code has been emailed", nil)
+}
+
+// ResetPassword handles POST /users/password/reset - Set a new password using a reset code
+// @Summary Reset password
+// @Description Set a new password with a reset code. Logs the user out of any active session.
+// @Tags users
+// @Accept json
+// @Produce json
+// @Param request body models.ResetPasswordRequest true "Reset code and new password"
+// @Success 200 {object} utils.Response
+// @Failure 400 {object} utils.Response
+// @Router /users/password/reset [post]
+func (h *UserHandler) ResetPassword(c *gin.Context) {
+	var req models.ResetPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ValidationErrorResponse(c, "Invalid request data", err.Error())
+		return
+	}
+
+	tx := database.DB.Begin()
+
+	userToken, err := consumeUserToken(tx, req.Token, models.UserTokenPasswordReset)
+	if err != nil {
+		tx.Rollback()
+		if errors.Is(err, errInvalidUserToken) {
+			utils.ErrorResponse(c, http.StatusBadRequest, "Invalid or expired reset code")
+			return
+		}
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to reset password")
+		return
+	}
+
+	var user models.User
+	if err := tx.First(&user, userToken.UserID).Error; err != nil {
+		tx.Rollback()
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid or expired reset code")
+		return
+	}
+
+	if err := user.SetPassword(req.Password); err != nil {
+		tx.Rollback()
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to reset password")
+		return
+	}
+
+	// Revoke the active session so a stolen token stops working
+	user.ClearToken()
+	if err := tx.Model(&user).Select("password", "token").Updates(&user).Error; err != nil {
+		tx.Rollback()
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to reset password")
+		return
+	}
+
+	actor := auditActor(c)
+	actor.UserID, actor.Username = &user.ID, user.Username
+	if err := audit.Record(tx, actor, audit.Event{Action: audit.ActionPasswordReset, TargetType: audit.TargetUser, TargetID: user.ID}); err != nil {
+		tx.Rollback()
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to reset password")
+		return
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to reset password")
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Password has been reset. Please login again.", nil)
+}
+
+// SendEmailVerification handles POST /users/email/verification - Email a verification code
+// @Summary Send email verification
+// @Description Email a new verification code to the current user's address
+// @Tags users
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {object} utils.Response
+// @Failure 400 {object} utils.Response
+// @Router /users/email/verification [post]
+func (h *UserHandler) SendEmailVerification(c *gin.Context) {
+	user, exists := middleware.GetUserFromContext(c)
+	if !exists {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "User not found")
+		return
+	}
+
+	if user.Email == "" {
+		utils.ErrorResponse(c, http.StatusBadRequest, "No email address on this account")
+		return
+	}
+
+	if user.IsEmailVerified() {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Email address is already verified")
+		return
+	}
+
+	if err := notify.Send(database.DB, notify.Mail{Template: notify.TemplateVerifyEmail, UserID: user.ID}); err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to send verification email")
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Verification email sent", nil)
+}
+
+// VerifyEmail handles POST /users/email/verify - Confirm an email address
+// @Summary Verify email
+// @Description Confirm the account's email address using the emailed code
+// @Tags users
+// @Accept json
+// @Produce json
+// @Param request body models.VerifyEmailRequest true "Verification code"
+// @Success 200 {object} utils.Response
+// @Failure 400 {object} utils.Response
+// @Router /users/email/verify [post]
+func (h *UserHandler) VerifyEmail(c *gin.Context) {
+	var req models.VerifyEmailRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ValidationErrorResponse(c, "Invalid request data", err.Error())
+		return
+	}
+
+	tx := database.DB.Begin()
+
+	userToken, err := consumeUserToken(tx, req.Token, models.UserTokenEmailVerification)
+	if err != nil {
+		tx.Rollback()
+		if errors.Is(err, errInvalidUserToken) {
+			utils.ErrorResponse(c, http.StatusBadRequest, "Invalid or expired verification code")
+			return
+		}
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to verify email")
+		return
+	}
+
+	var user models.User
+	if err := tx.First(&user, userToken.UserID).Error; err != nil {
+		tx.Rollback()
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid or expired verification code")
+		return
+	}
+
+	now := time.Now()
+	user.EmailVerifiedAt = &now
+	if err := tx.Model(&user).Update("email_verified_at", now).Error; err != nil {
+		tx.Rollback()
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to verify email")
+		return
+	}
+
+	actor := auditActor(c)
+	actor.UserID, actor.Username = &user.ID, user.Username
+	if err := audit.Record(tx, actor, audit.Event{
+		Action:     audit.ActionEmailVerified,
+		TargetType: audit.TargetUser,
+		TargetID:   user.ID,
+	}); err != nil {
+		tx.Rollback()
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to verify email")
+		return
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to verify email")
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Email address verified", user.ToResponse())
+}
+
+// consumeUserToken redeems a token exactly once. Concurrent redemptions of the same token
+// race on the used_at update, and only one of them wins.
+func consumeUserToken(tx *gorm.DB, token string, purpose models.UserTokenPurpose) (*models.UserToken, error) {
+	var userToken models.UserToken
+	if err := tx.Where("token_hash = ? AND purpose = ?", utils.HashToken(token), purpose).First(&userToken).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errInvalidUserToken
+		}
+		return nil, err
+	}
+
+	if !userToken.IsUsable() {
+		return nil, errInvalidUserToken
+	}
+
+	result := tx.Model(&models.UserToken{}).
+		Where("id = ? AND used_at IS NULL", userToken.ID).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, errInvalidUserToken
+	}
+
+	return &userToken, nil
+}
@@ -0,0 +1,30 @@
+package handlers
+
+import (
+	"net/http"
+
+	"shopease/internal/config"
+	"shopease/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AdminHandler handles operational endpoints for administrators
+type AdminHandler struct{}
+
+// NewAdminHandler creates a new AdminHandler
+func NewAdminHandler() *AdminHandler {
+	return &AdminHandler{}
+}
+
+// GetConfig handles GET /admin/config - Effective configuration (admin)
+// @Summary Get configuration
+// @Description Get the effective server configuration, keyed by config file name, with secrets redacted
+// @Tags admin
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {object} utils.Response
+// @Router /admin/config [get]
+func (h *AdminHandler) GetConfig(c *gin.Context) {
+	utils.SuccessResponse(c, http.StatusOK, "Configuration retrieved", config.AppConfig.Redacted())
+}
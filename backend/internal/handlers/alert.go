@@ -0,0 +1,168 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"shopease/internal/database"
+	"shopease/internal/middleware"
+	"shopease/internal/models"
+	"shopease/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AlertHandler handles item alert subscriptions
+type AlertHandler struct{}
+
+// NewAlertHandler creates a new AlertHandler
+func NewAlertHandler() *AlertHandler {
+	return &AlertHandler{}
+}
+
+// CreateAlert handles POST /items/:id/alerts - Watch an item for price drops or restocks
+// @Summary Subscribe to item alerts
+// @Description Get notified when an item's price drops (optionally below a target) or it comes back in stock.
+// @Description Subscribing again with the same type replaces the target price.
+// @Tags alerts
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "Item ID"
+// @Param alert body models.ItemAlertRequest true "Alert data"
+// @Success 201 {object} utils.Response
+// @Failure 400 {object} utils.Response
+// @Failure 404 {object} utils.Response
+// @Router /items/{id}/alerts [post]
+func (h *AlertHandler) CreateAlert(c *gin.Context) {
+	userID, exists := middleware.GetUserIDFromContext(c)
+	if !exists {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	itemID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid item ID")
+		return
+	}
+
+	var req models.ItemAlertRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ValidationErrorResponse(c, "Invalid request data", err.Error())
+		return
+	}
+
+	if req.Type == models.AlertTypeBackInStock && req.TargetPrice != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Target price only applies to price drop alerts")
+		return
+	}
+
+	var item models.Item
+	if err := database.DB.First(&item, itemID).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusNotFound, "Item not found")
+		return
+	}
+
+	var alert models.ItemAlert
+	result := database.DB.Where("user_id = ? AND item_id = ? AND type = ?", userID, item.ID, req.Type).First(&alert)
+
+	if result.Error == nil {
+		// Existing subscription, update target and start notifying afresh
+		if err := database.DB.Model(&alert).Updates(map[string]interface{}{
+			"target_price":        req.TargetPrice,
+			"last_notified_price": nil,
+		}).Error; err != nil {
+			utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to update alert")
+			return
+		}
+		alert.TargetPrice = req.TargetPrice
+		alert.LastNotifiedPrice = nil
+	} else {
+		alert = models.ItemAlert{
+			UserID:      userID,
+			ItemID:      item.ID,
+			Type:        req.Type,
+			TargetPrice: req.TargetPrice,
+		}
+		if err := database.DB.Create(&alert).Error; err != nil {
+			utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to create alert")
+			return
+		}
+	}
+
+	alert.Item = &item
+	utils.SuccessResponse(c, http.StatusCreated, "Alert saved", alert.ToResponse())
+}
+
+// ListAlerts handles GET /alerts - List the user's item alerts
+// @Summary List my alerts
+// @Description Get the authenticated user's item alert subscriptions
+// @Tags alerts
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {object} utils.Response
+// @Router /alerts [get]
+func (h *AlertHandler) ListAlerts(c *gin.Context) {
+	userID, exists := middleware.GetUserIDFromContext(c)
+	if !exists {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	var alerts []models.ItemAlert
+	if err := database.DB.Preload("Item").Where("user_id = ?", userID).Order("created_at DESC").Find(&alerts).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to fetch alerts")
+		return
+	}
+
+	responses := make([]models.ItemAlertResponse, len(alerts))
+	for i, alert := range alerts {
+		responses[i] = alert.ToResponse()
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Alerts retrieved successfully", responses)
+}
+
+// DeleteAlert handles DELETE /alerts/:id - Unsubscribe from an item alert
+// @Summary Delete alert
+// @Description Stop watching an item
+// @Tags alerts
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Alert ID"
+// @Success 200 {object} utils.Response
+// @Failure 404 {object} utils.Response
+// @Router /alerts/{id} [delete]
+func (h *AlertHandler) DeleteAlert(c *gin.Context) {
+	userID, exists := middleware.GetUserIDFromContext(c)
+	if !exists {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	alertID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid alert ID")
+		return
+	}
+
+	var alert models.ItemAlert
+	if err := database.DB.First(&alert, alertID).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusNotFound, "Alert not found")
+		return
+	}
+
+	if alert.UserID != userID {
+		utils.ErrorResponse(c, http.StatusForbidden, "Not authorized to delete this alert")
+		return
+	}
+
+	// Hard delete so the user can subscribe again later
+	if err := database.DB.Unscoped().Delete(&alert).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to delete alert")
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Alert deleted", nil)
+}
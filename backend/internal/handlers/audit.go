@@ -0,0 +1,134 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"shopease/internal/audit"
+	"shopease/internal/database"
+	"shopease/internal/middleware"
+	"shopease/internal/models"
+	"shopease/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ListAuditEvents handles GET /admin/audit - Search the audit log (admin)
+// @Summary List audit events
+// @Description Get audit events, newest first. An action ending in "." matches every action with that prefix, e.g. "auth.".
+// @Tags admin
+// @Security BearerAuth
+// @Produce json
+// @Param actor_id query int false "Filter by acting user ID"
+// @Param actor query string false "Filter by acting username"
+// @Param action query string false "Filter by action, or action prefix ending in '.'"
+// @Param target_type query string false "Filter by target type (user, item, order, review, job)"
+// @Param target_id query int false "Filter by target ID"
+// @Param request_id query string false "Filter by request ID"
+// @Param since query string false "Only events at or after this RFC 3339 time"
+// @Param until query string false "Only events before this RFC 3339 time"
+// @Param page query int false "Page number" default(1)
+// @Param page_size query int false "Page size" default(20)
+// @Success 200 {object} utils.PaginatedResponse
+// @Failure 400 {object} utils.Response
+// @Router /admin/audit [get]
+func (h *AdminHandler) ListAuditEvents(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	offset := (page - 1) * pageSize
+
+	query := database.DB.Model(&models.AuditEvent{})
+	for param, column := range map[string]string{"actor_id": "actor_id", "target_id": "target_id"} {
+		if value := c.Query(param); value != "" {
+			id, err := strconv.ParseUint(value, 10, 32)
+			if err != nil {
+				utils.ErrorResponse(c, http.StatusBadRequest, "Invalid "+param)
+				return
+			}
+			query = query.Where(column+" = ?", id)
+		}
+	}
+	for param, column := range map[string]string{"actor": "actor_name", "target_type": "target_type", "request_id": "request_id"} {
+		if value := c.Query(param); value != "" {
+			query = query.Where(column+" = ?", value)
+		}
+	}
+	if action := c.Query("action"); strings.HasSuffix(action, ".") {
+		query = query.Where("action LIKE ?", action+"%")
+	} else if action != "" {
+		query = query.Where("action = ?", action)
+	}
+	for param, condition := range map[string]string{"since": "created_at >= ?", "until": "created_at < ?"} {
+		if value := c.Query(param); value != "" {
+			at, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				utils.ErrorResponse(c, http.StatusBadRequest, "Invalid "+param+"; use an RFC 3339 time such as 2024-01-02T15:04:05Z")
+				return
+			}
+			query = query.Where(condition, at.UTC())
+		}
+	}
+
+	var totalCount int64
+	query.Count(&totalCount)
+
+	var events []models.AuditEvent
+	if err := query.Order("id DESC").Offset(offset).Limit(pageSize).Find(&events).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to fetch audit events")
+		return
+	}
+
+	utils.PaginatedSuccessResponse(c, events, page, pageSize, totalCount)
+}
+
+// VerifyAuditLog handles GET /admin/audit/verify - Check the audit log's hash chain (admin)
+// @Summary Verify audit log
+// @Description Recompute the hash chain and report the first event that was modified, removed or reordered
+// @Tags admin
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {object} utils.Response
+// @Failure 409 {object} utils.Response
+// @Router /admin/audit/verify [get]
+func (h *AdminHandler) VerifyAuditLog(c *gin.Context) {
+	result, err := audit.Verify(database.DB.WithContext(c.Request.Context()))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to verify audit log")
+		return
+	}
+
+	if !result.Valid {
+		c.JSON(http.StatusConflict, utils.Response{
+			Success: false,
+			Error:   "Audit log has been tampered with",
+			Data:    result,
+		})
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Audit log verified", result)
+}
+
+// auditActor identifies the authenticated user, if any, and the request for the audit log
+func auditActor(c *gin.Context) audit.Actor {
+	actor := audit.Actor{
+		RequestID: middleware.GetRequestID(c),
+		IP:        c.ClientIP(),
+	}
+	if user, ok := middleware.GetUserFromContext(c); ok {
+		id := user.ID
+		actor.UserID = &id
+		actor.Username = user.Username
+	}
+	return actor
+}
@@ -1,15 +1,20 @@
 package handlers
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
 	"strconv"
 
+	"shopease/internal/config"
 	"shopease/internal/database"
+	"shopease/internal/metrics"
 	"shopease/internal/middleware"
 	"shopease/internal/models"
 	"shopease/internal/utils"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
 // CartHandler handles cart-related requests
@@ -33,6 +38,8 @@ func NewCartHandler() *CartHandler {
 // @Failure 401 {object} utils.Response
 // @Router /carts [post]
 func (h *CartHandler) AddToCart(c *gin.Context) {
+	db := database.DB.WithContext(c.Request.Context())
+
 	userID, exists := middleware.GetUserIDFromContext(c)
 	if !exists {
 		utils.ErrorResponse(c, http.StatusUnauthorized, "User not authenticated")
@@ -52,7 +59,7 @@ func (h *CartHandler) AddToCart(c *gin.Context) {
 
 	// Verify item exists
 	var item models.Item
-	if err := database.DB.First(&item, req.ItemID).Error; err != nil {
+	if err := db.First(&item, req.ItemID).Error; err != nil {
 		utils.ErrorResponse(c, http.StatusNotFound, "Item not found")
 		return
 	}
@@ -63,54 +70,110 @@ func (h *CartHandler) AddToCart(c *gin.Context) {
 	}
 
 	// Get or create cart for user (single cart per user)
-	var cart models.Cart
-	result := database.DB.Where("user_id = ?", userID).First(&cart)
+	cart, err := getOrCreateCart(db, userID)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to create cart")
+		return
+	}
 
-	if result.Error != nil {
-		// Create new cart for user
-		cart = models.Cart{UserID: userID}
-		if err := database.DB.Create(&cart).Error; err != nil {
-			utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to create cart")
+	if err := addCartItem(db, cart.ID, &item, req.Quantity); err != nil {
+		if errors.Is(err, errCartQuantityLimit) {
+			utils.ErrorResponse(c, http.StatusBadRequest, quantityLimitMessage())
 			return
 		}
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to add item to cart")
+		return
 	}
+	metrics.CartAdds.Inc()
 
-	// Check if item already in cart
-	var cartItem models.CartItem
-	result = database.DB.Where("cart_id = ? AND item_id = ?", cart.ID, req.ItemID).First(&cartItem)
+	// Reload cart with items
+	if err := db.Preload("CartItems.Item").First(cart, cart.ID).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to reload cart")
+		return
+	}
+
+	setVersionETag(c, cart.Version)
+	utils.SuccessResponse(c, http.StatusOK, "Item added to cart", cartResponse(cart))
+}
+
+// getOrCreateCart returns the user's cart, creating it on first use
+func getOrCreateCart(db *gorm.DB, userID uint) (*models.Cart, error) {
+	var cart models.Cart
+	if err := db.Where("user_id = ?", userID).First(&cart).Error; err == nil {
+		return &cart, nil
+	}
+
+	cart = models.Cart{UserID: userID}
+	if err := db.Create(&cart).Error; err != nil {
+		return nil, err
+	}
+	return &cart, nil
+}
+
+// errCartQuantityLimit is returned when a cart line would exceed CartMaxQuantity
+var errCartQuantityLimit = errors.New("cart line quantity limit exceeded")
+
+// quantityLimitMessage explains errCartQuantityLimit to the customer
+func quantityLimitMessage() string {
+	return fmt.Sprintf("You can buy at most %d of an item", config.AppConfig.CartMaxQuantity)
+}
 
-	if result.Error == nil {
+// exceedsQuantityLimit reports whether quantity is more than a cart line may hold
+func exceedsQuantityLimit(quantity int) bool {
+	return config.AppConfig.CartMaxQuantity > 0 && quantity > config.AppConfig.CartMaxQuantity
+}
+
+// addCartItem adds quantity of an item to a cart, merging with an existing
+// line for the same item, and moves the cart on to its next version. The line
+// remembers the item's current price, which the customer has just seen.
+func addCartItem(db *gorm.DB, cartID uint, item *models.Item, quantity int) error {
+	price := item.Price
+
+	var cartItem models.CartItem
+	if err := db.Where("cart_id = ? AND item_id = ?", cartID, item.ID).First(&cartItem).Error; err == nil {
 		// Item exists, update quantity
-		cartItem.Quantity += req.Quantity
-		if err := database.DB.Save(&cartItem).Error; err != nil {
-			utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to update cart item")
-			return
-		}
-	} else {
-		// Add new item to cart
-		cartItem = models.CartItem{
-			CartID:   cart.ID,
-			ItemID:   req.ItemID,
-			Quantity: req.Quantity,
+		if exceedsQuantityLimit(cartItem.Quantity + quantity) {
+			return errCartQuantityLimit
 		}
-		if err := database.DB.Create(&cartItem).Error; err != nil {
-			utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to add item to cart")
-			return
+		cartItem.Quantity += quantity
+		cartItem.PriceAtAdd = &price
+		if err := db.Save(&cartItem).Error; err != nil {
+			return err
 		}
+		return touchCart(db, cartID)
 	}
 
-	// Reload cart with items
-	if err := database.DB.Preload("CartItems.Item").First(&cart, cart.ID).Error; err != nil {
-		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to reload cart")
-		return
+	if exceedsQuantityLimit(quantity) {
+		return errCartQuantityLimit
+	}
+
+	// Add new item to cart
+	cartItem = models.CartItem{
+		CartID:     cartID,
+		ItemID:     item.ID,
+		Quantity:   quantity,
+		PriceAtAdd: &price,
 	}
+	if err := db.Create(&cartItem).Error; err != nil {
+		return err
+	}
+	return touchCart(db, cartID)
+}
 
-	utils.SuccessResponse(c, http.StatusOK, "Item added to cart", cart.ToResponse())
+// touchCart moves a cart on to its next version after a change that doesn't
+// depend on which version the client last saw
+func touchCart(db *gorm.DB, cartID uint) error {
+	return db.Model(&models.Cart{}).Where("id = ?", cartID).UpdateColumn("version", models.NextVersion).Error
+}
+
+// cartResponse reviews a cart, with its items preloaded, against current prices and availability
+func cartResponse(cart *models.Cart) models.CartResponse {
+	return cart.ToResponse(config.AppConfig.CartMaxQuantity)
 }
 
 // GetMyCart handles GET /carts/my - Get current user's cart
 // @Summary Get my cart
-// @Description Get the authenticated user's cart
+// @Description Get the authenticated user's cart. The ETag header carries the cart's version, for If-Match on later changes.
 // @Tags carts
 // @Security BearerAuth
 // @Produce json
@@ -119,6 +182,8 @@ func (h *CartHandler) AddToCart(c *gin.Context) {
 // @Failure 404 {object} utils.Response
 // @Router /carts/my [get]
 func (h *CartHandler) GetMyCart(c *gin.Context) {
+	db := database.DB.WithContext(c.Request.Context())
+
 	userID, exists := middleware.GetUserIDFromContext(c)
 	if !exists {
 		utils.ErrorResponse(c, http.StatusUnauthorized, "User not authenticated")
@@ -126,19 +191,21 @@ func (h *CartHandler) GetMyCart(c *gin.Context) {
 	}
 
 	var cart models.Cart
-	if err := database.DB.Preload("CartItems.Item").Where("user_id = ?", userID).First(&cart).Error; err != nil {
+	if err := db.Preload("CartItems.Item").Where("user_id = ?", userID).First(&cart).Error; err != nil {
 		// Return empty cart response
 		emptyCart := models.CartResponse{
 			UserID:    userID,
 			Items:     []models.CartItemResponse{},
 			Total:     0,
 			ItemCount: 0,
+			Warnings:  []models.CartWarning{},
 		}
 		utils.SuccessResponse(c, http.StatusOK, "Cart is empty", emptyCart)
 		return
 	}
 
-	utils.SuccessResponse(c, http.StatusOK, "Cart retrieved successfully", cart.ToResponse())
+	setVersionETag(c, cart.Version)
+	utils.SuccessResponse(c, http.StatusOK, "Cart retrieved successfully", cartResponse(&cart))
 }
 
 // ListCarts handles GET /carts - List all carts (admin)
@@ -150,16 +217,18 @@ func (h *CartHandler) GetMyCart(c *gin.Context) {
 // @Success 200 {object} utils.Response
 // @Router /carts [get]
 func (h *CartHandler) ListCarts(c *gin.Context) {
+	db := database.DB.WithContext(c.Request.Context())
+
 	var carts []models.Cart
 
-	if err := database.DB.Preload("CartItems.Item").Preload("User").Find(&carts).Error; err != nil {
+	if err := db.Preload("CartItems.Item").Preload("User").Find(&carts).Error; err != nil {
 		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to fetch carts")
 		return
 	}
 
 	responses := make([]models.CartResponse, len(carts))
-	for i, cart := range carts {
-		responses[i] = cart.ToResponse()
+	for i := range carts {
+		responses[i] = cartResponse(&carts[i])
 	}
 
 	utils.SuccessResponse(c, http.StatusOK, "Carts retrieved successfully", responses)
@@ -167,18 +236,22 @@ func (h *CartHandler) ListCarts(c *gin.Context) {
 
 // UpdateCartItem handles PUT /carts/items/:id - Update cart item quantity
 // @Summary Update cart item
-// @Description Update the quantity of an item in the cart
+// @Description Update the quantity of an item in the cart. Send the cart's ETag in If-Match to fail with 412 if the cart changed elsewhere.
 // @Tags carts
 // @Security BearerAuth
 // @Accept json
 // @Produce json
 // @Param id path int true "Cart Item ID"
+// @Param If-Match header string false "ETag of the cart version being edited"
 // @Param quantity body object{quantity int} true "New quantity"
 // @Success 200 {object} utils.Response
 // @Failure 400 {object} utils.Response
 // @Failure 404 {object} utils.Response
+// @Failure 412 {object} utils.Response
 // @Router /carts/items/{id} [put]
 func (h *CartHandler) UpdateCartItem(c *gin.Context) {
+	db := database.DB.WithContext(c.Request.Context())
+
 	userID, exists := middleware.GetUserIDFromContext(c)
 	if !exists {
 		utils.ErrorResponse(c, http.StatusUnauthorized, "User not authenticated")
@@ -202,7 +275,7 @@ func (h *CartHandler) UpdateCartItem(c *gin.Context) {
 
 	// Find cart item and verify ownership
 	var cartItem models.CartItem
-	if err := database.DB.Preload("Cart").First(&cartItem, cartItemID).Error; err != nil {
+	if err := db.Preload("Cart").First(&cartItem, cartItemID).Error; err != nil {
 		utils.ErrorResponse(c, http.StatusNotFound, "Cart item not found")
 		return
 	}
@@ -212,43 +285,70 @@ func (h *CartHandler) UpdateCartItem(c *gin.Context) {
 		return
 	}
 
+	if !checkIfMatch(c, cartItem.Cart.Version, "Cart") {
+		return
+	}
+
+	if exceedsQuantityLimit(req.Quantity) {
+		utils.ErrorResponse(c, http.StatusBadRequest, quantityLimitMessage())
+		return
+	}
+
 	if req.Quantity == 0 {
 		// Remove item from cart
-		if err := database.DB.Delete(&cartItem).Error; err != nil {
+		if err := deleteCartItem(db, &cartItem); err != nil {
+			if respondVersionConflict(c, err, "Cart") {
+				return
+			}
 			utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to remove item")
 			return
 		}
+		setVersionETag(c, cartItem.Cart.Version)
 		utils.SuccessResponse(c, http.StatusOK, "Item removed from cart", nil)
 		return
 	}
 
 	cartItem.Quantity = req.Quantity
-	if err := database.DB.Save(&cartItem).Error; err != nil {
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := models.ClaimVersion(tx, cartItem.Cart, cartItem.Cart.Version); err != nil {
+			return err
+		}
+		return tx.Omit("Cart").Save(&cartItem).Error
+	})
+	if err != nil {
+		if respondVersionConflict(c, err, "Cart") {
+			return
+		}
 		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to update cart item")
 		return
 	}
 
 	// Reload cart
 	var cart models.Cart
-	if err := database.DB.Preload("CartItems.Item").First(&cart, cartItem.CartID).Error; err != nil {
+	if err := db.Preload("CartItems.Item").First(&cart, cartItem.CartID).Error; err != nil {
 		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to reload cart")
 		return
 	}
 
-	utils.SuccessResponse(c, http.StatusOK, "Cart item updated", cart.ToResponse())
+	setVersionETag(c, cart.Version)
+	utils.SuccessResponse(c, http.StatusOK, "Cart item updated", cartResponse(&cart))
 }
 
 // RemoveFromCart handles DELETE /carts/items/:id - Remove item from cart
 // @Summary Remove item from cart
-// @Description Remove an item from the user's cart
+// @Description Remove an item from the user's cart, optionally only if If-Match names the cart's current version
 // @Tags carts
 // @Security BearerAuth
 // @Produce json
 // @Param id path int true "Cart Item ID"
+// @Param If-Match header string false "ETag of the cart version being edited"
 // @Success 200 {object} utils.Response
 // @Failure 404 {object} utils.Response
+// @Failure 412 {object} utils.Response
 // @Router /carts/items/{id} [delete]
 func (h *CartHandler) RemoveFromCart(c *gin.Context) {
+	db := database.DB.WithContext(c.Request.Context())
+
 	userID, exists := middleware.GetUserIDFromContext(c)
 	if !exists {
 		utils.ErrorResponse(c, http.StatusUnauthorized, "User not authenticated")
@@ -263,7 +363,7 @@ func (h *CartHandler) RemoveFromCart(c *gin.Context) {
 
 	// Find cart item and verify ownership
 	var cartItem models.CartItem
-	if err := database.DB.Preload("Cart").First(&cartItem, cartItemID).Error; err != nil {
+	if err := db.Preload("Cart").First(&cartItem, cartItemID).Error; err != nil {
 		utils.ErrorResponse(c, http.StatusNotFound, "Cart item not found")
 		return
 	}
@@ -273,23 +373,45 @@ func (h *CartHandler) RemoveFromCart(c *gin.Context) {
 		return
 	}
 
-	if err := database.DB.Delete(&cartItem).Error; err != nil {
+	if !checkIfMatch(c, cartItem.Cart.Version, "Cart") {
+		return
+	}
+
+	if err := deleteCartItem(db, &cartItem); err != nil {
+		if respondVersionConflict(c, err, "Cart") {
+			return
+		}
 		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to remove item")
 		return
 	}
 
+	setVersionETag(c, cartItem.Cart.Version)
 	utils.SuccessResponse(c, http.StatusOK, "Item removed from cart", nil)
 }
 
+// deleteCartItem removes a line, with its Cart preloaded, from the version of the cart it was read with
+func deleteCartItem(db *gorm.DB, cartItem *models.CartItem) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := models.ClaimVersion(tx, cartItem.Cart, cartItem.Cart.Version); err != nil {
+			return err
+		}
+		return tx.Delete(cartItem).Error
+	})
+}
+
 // ClearCart handles DELETE /carts/my - Clear the user's cart
 // @Summary Clear cart
-// @Description Remove all items from the user's cart
+// @Description Remove all items from the user's cart, optionally only if If-Match names its current version
 // @Tags carts
 // @Security BearerAuth
 // @Produce json
+// @Param If-Match header string false "ETag of the cart version being cleared"
 // @Success 200 {object} utils.Response
+// @Failure 412 {object} utils.Response
 // @Router /carts/my [delete]
 func (h *CartHandler) ClearCart(c *gin.Context) {
+	db := database.DB.WithContext(c.Request.Context())
+
 	userID, exists := middleware.GetUserIDFromContext(c)
 	if !exists {
 		utils.ErrorResponse(c, http.StatusUnauthorized, "User not authenticated")
@@ -297,16 +419,30 @@ func (h *CartHandler) ClearCart(c *gin.Context) {
 	}
 
 	var cart models.Cart
-	if err := database.DB.Where("user_id = ?", userID).First(&cart).Error; err != nil {
+	if err := db.Where("user_id = ?", userID).First(&cart).Error; err != nil {
 		utils.SuccessResponse(c, http.StatusOK, "Cart was already empty", nil)
 		return
 	}
 
+	if !checkIfMatch(c, cart.Version, "Cart") {
+		return
+	}
+
 	// Delete all cart items
-	if err := database.DB.Where("cart_id = ?", cart.ID).Delete(&models.CartItem{}).Error; err != nil {
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := models.ClaimVersion(tx, &cart, cart.Version); err != nil {
+			return err
+		}
+		return tx.Where("cart_id = ?", cart.ID).Delete(&models.CartItem{}).Error
+	})
+	if err != nil {
+		if respondVersionConflict(c, err, "Cart") {
+			return
+		}
 		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to clear cart")
 		return
 	}
 
+	setVersionETag(c, cart.Version)
 	utils.SuccessResponse(c, http.StatusOK, "Cart cleared successfully", nil)
 }
@@ -0,0 +1,229 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"shopease/internal/catalog"
+	"shopease/internal/database"
+	"shopease/internal/jobs"
+	"shopease/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// MaxImportBytes caps the size of an uploaded catalogue file
+	MaxImportBytes = 50 << 20
+	// SyncImportLimit is the largest upload imported within the request unless async is requested
+	SyncImportLimit = 1 << 20
+)
+
+// ImportItems handles POST /admin/items/import - Bulk create or update items (admin)
+// @Summary Import items
+// @Description Upsert items from CSV or NDJSON, matching by SKU then ID. Send the file as the request body or as multipart field "file".
+// @Description Large files (or async=true) are imported in the background; poll the returned job.
+// @Tags admin
+// @Security BearerAuth
+// @Accept text/csv,application/x-ndjson,multipart/form-data
+// @Produce json
+// @Param format query string false "csv or ndjson; defaults from the file name or Content-Type"
+// @Param mode query string false "all_or_nothing (default) or best_effort"
+// @Param dry_run query bool false "Validate without saving"
+// @Param async query bool false "Run as a background job (default: only for files over 1 MB)"
+// @Success 200 {object} utils.Response
+// @Success 202 {object} utils.Response
+// @Failure 400 {object} utils.Response
+// @Failure 422 {object} utils.Response
+// @Router /admin/items/import [post]
+func (h *AdminHandler) ImportItems(c *gin.Context) {
+	body, name, contentType, err := importSource(c)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	defer body.Close()
+
+	formatName := c.Query("format")
+	if formatName == "" {
+		switch ext := filepath.Ext(name); ext {
+		case ".csv", ".ndjson", ".jsonl":
+			formatName = ext[1:]
+		default:
+			formatName = contentType
+		}
+	}
+	format, err := catalog.ParseFormat(formatName)
+	if err != nil || format == catalog.FormatJSON {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Unsupported format; use format=csv or format=ndjson")
+		return
+	}
+
+	opts := catalog.Options{
+		Format: format,
+		Mode:   catalog.Mode(c.DefaultQuery("mode", string(catalog.ModeAtomic))),
+		DryRun: c.Query("dry_run") == "true",
+		Actor:  auditActor(c),
+	}
+	if opts.Mode != catalog.ModeAtomic && opts.Mode != catalog.ModeBestEffort {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid mode; use all_or_nothing or best_effort")
+		return
+	}
+
+	// Spool the upload to disk so its size is known and a background job can outlive the request
+	file, err := os.CreateTemp("", "shopease-import-*")
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to store upload")
+		return
+	}
+	size, err := io.Copy(file, body)
+	file.Close()
+	if err != nil {
+		os.Remove(file.Name())
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			utils.ErrorResponse(c, http.StatusRequestEntityTooLarge, fmt.Sprintf("Import files are limited to %d MB", MaxImportBytes>>20))
+			return
+		}
+		utils.ErrorResponse(c, http.StatusBadRequest, "Failed to read upload")
+		return
+	}
+
+	async, _ := strconv.ParseBool(c.DefaultQuery("async", strconv.FormatBool(size > SyncImportLimit)))
+	if async {
+		job, err := catalog.EnqueueImport(database.DB, file.Name(), opts)
+		if err != nil {
+			os.Remove(file.Name())
+			utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to start import")
+			return
+		}
+		status, err := catalog.ImportStatusOf(job)
+		if err != nil {
+			utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to start import")
+			return
+		}
+		c.Header("Location", fmt.Sprintf("/api/v1/admin/items/import/%d", job.ID))
+		utils.SuccessResponse(c, http.StatusAccepted, "Import started", status)
+		return
+	}
+
+	defer os.Remove(file.Name())
+	f, err := os.Open(file.Name())
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to read upload")
+		return
+	}
+	defer f.Close()
+
+	report, err := catalog.Import(c.Request.Context(), f, opts)
+	if err != nil {
+		log.Printf("Catalogue import failed: %v", err)
+		if report != nil {
+			// Earlier batches of a best-effort import were committed
+			utils.ErrorDataResponse(c, http.StatusBadRequest, "Import failed: "+err.Error(), report)
+			return
+		}
+		utils.ErrorResponse(c, http.StatusBadRequest, "Import failed: "+err.Error())
+		return
+	}
+
+	if !report.Applied && !report.DryRun {
+		c.JSON(http.StatusUnprocessableEntity, utils.Response{
+			Success: false,
+			Error:   fmt.Sprintf("Import rejected: %d of %d row(s) are invalid", report.Failed, report.Rows),
+			Data:    report,
+		})
+		return
+	}
+
+	message := "Import completed"
+	if report.DryRun {
+		message = "Dry run completed; nothing was saved"
+	}
+	utils.SuccessResponse(c, http.StatusOK, message, report)
+}
+
+// GetImportJob handles GET /admin/items/import/:id - Poll a background import (admin)
+// @Summary Get import job
+// @Description Get the progress of a background import, and its report once finished
+// @Tags admin
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Job ID"
+// @Success 200 {object} utils.Response
+// @Failure 404 {object} utils.Response
+// @Router /admin/items/import/{id} [get]
+func (h *AdminHandler) GetImportJob(c *gin.Context) {
+	jobID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusNotFound, "Import job not found")
+		return
+	}
+
+	job, err := jobs.Get(database.DB, uint(jobID))
+	if err != nil || job.Type != catalog.ImportJob {
+		utils.ErrorResponse(c, http.StatusNotFound, "Import job not found")
+		return
+	}
+
+	status, err := catalog.ImportStatusOf(job)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to read import job")
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Import job retrieved", status)
+}
+
+// ExportItems handles GET /admin/items/export - Stream the catalogue (admin)
+// @Summary Export items
+// @Description Stream every item, including inactive ones, as CSV or NDJSON
+// @Tags admin
+// @Security BearerAuth
+// @Produce text/csv,application/x-ndjson
+// @Param format query string false "csv (default) or ndjson"
+// @Success 200 {file} file
+// @Failure 400 {object} utils.Response
+// @Router /admin/items/export [get]
+func (h *AdminHandler) ExportItems(c *gin.Context) {
+	format, err := catalog.ParseFormat(c.DefaultQuery("format", "csv"))
+	if err != nil || format == catalog.FormatJSON {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Unsupported format; use format=csv or format=ndjson")
+		return
+	}
+
+	c.Header("Content-Type", format.ContentType())
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="items.%s"`, format))
+	c.Status(http.StatusOK)
+
+	// Headers are already sent, so a failure part-way can only be logged
+	if _, err := catalog.Export(c.Request.Context(), c.Writer, format); err != nil {
+		log.Printf("Catalogue export failed: %v", err)
+	}
+}
+
+// importSource returns the uploaded file from multipart field "file", or else
+// the raw request body, along with its file name and content type
+func importSource(c *gin.Context) (io.ReadCloser, string, string, error) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, MaxImportBytes)
+
+	if c.ContentType() != "multipart/form-data" {
+		return c.Request.Body, "", c.ContentType(), nil
+	}
+
+	header, err := c.FormFile("file")
+	if err != nil {
+		return nil, "", "", errors.New("multipart upload must include a \"file\" field")
+	}
+	file, err := header.Open()
+	if err != nil {
+		return nil, "", "", err
+	}
+	return file, header.Filename, header.Header.Get("Content-Type"), nil
+}
@@ -0,0 +1,51 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"shopease/internal/middleware"
+	"shopease/internal/models"
+	"shopease/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// versionETag is the entity tag of a versioned resource
+func versionETag(version uint) string {
+	return fmt.Sprintf(`"v%d"`, version)
+}
+
+// setVersionETag sends the resource's version as its ETag, for clients to
+// echo in If-Match or If-None-Match
+func setVersionETag(c *gin.Context, version uint) {
+	c.Header("ETag", versionETag(version))
+}
+
+// checkIfMatch checks the request's If-Match header, if it has one, against
+// the resource's current version. It responds 412 and returns false when the
+// client edited an older version.
+func checkIfMatch(c *gin.Context, version uint, resource string) bool {
+	header := c.GetHeader("If-Match")
+	if header == "" || middleware.ETagMatches(header, versionETag(version), true) {
+		return true
+	}
+	setVersionETag(c, version)
+	utils.ErrorResponse(c, http.StatusPreconditionFailed, resource+" has been modified; reload it and try again")
+	return false
+}
+
+// respondVersionConflict handles a models.ErrVersionConflict from a write
+// that lost a race after passing checkIfMatch, and reports whether err was one
+func respondVersionConflict(c *gin.Context, err error, resource string) bool {
+	if !errors.Is(err, models.ErrVersionConflict) {
+		return false
+	}
+	if c.GetHeader("If-Match") != "" {
+		utils.ErrorResponse(c, http.StatusPreconditionFailed, resource+" has been modified; reload it and try again")
+	} else {
+		utils.ErrorResponse(c, http.StatusConflict, resource+" was modified at the same time; please try again")
+	}
+	return true
+}
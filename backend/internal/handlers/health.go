@@ -0,0 +1,73 @@
+package handlers
+
+import (
+	"net/http"
+
+	"shopease/internal/health"
+
+	"github.com/gin-gonic/gin"
+)
+
+// HealthHandler handles liveness and readiness probes
+type HealthHandler struct{}
+
+// NewHealthHandler creates a new HealthHandler
+func NewHealthHandler() *HealthHandler {
+	return &HealthHandler{}
+}
+
+// Livez handles GET /livez - Liveness probe
+// @Summary Liveness probe
+// @Description Reports that the process is up and serving HTTP. Does not check dependencies.
+// @Tags health
+// @Produce json
+// @Success 200 {object} map[string]string
+// @Router /livez [get]
+func (h *HealthHandler) Livez(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"status": health.StatusOK})
+}
+
+// Readyz handles GET /readyz - Readiness probe
+// @Summary Readiness probe
+// @Description Runs every registered dependency check. Fails while the server is shutting down.
+// @Tags health
+// @Produce json
+// @Success 200 {object} health.Report
+// @Failure 503 {object} health.Report
+// @Router /readyz [get]
+func (h *HealthHandler) Readyz(c *gin.Context) {
+	report := health.Run(c.Request.Context())
+
+	status := http.StatusOK
+	if !report.Healthy() {
+		status = http.StatusServiceUnavailable
+	}
+	c.JSON(status, report)
+}
+
+// Health handles GET /health - Legacy health check, backed by the readiness checks
+// @Summary Health check
+// @Description Summarised readiness for older monitors
+// @Tags health
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+// @Failure 503 {object} map[string]interface{}
+// @Router /health [get]
+func (h *HealthHandler) Health(c *gin.Context) {
+	report := health.Run(c.Request.Context())
+
+	if !report.Healthy() {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"status":  "unhealthy",
+			"message": "ShopEase API is not ready",
+			"checks":  report.Checks,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "healthy",
+		"message": "ShopEase API is running",
+		"checks":  report.Checks,
+	})
+}
@@ -3,12 +3,18 @@ package handlers
 import (
 	"net/http"
 	"strconv"
+	"time"
 
+	"shopease/internal/audit"
+	"shopease/internal/cache"
 	"shopease/internal/database"
+	"shopease/internal/events"
 	"shopease/internal/models"
+	"shopease/internal/pricing"
 	"shopease/internal/utils"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
 // ItemHandler handles item-related requests
@@ -45,11 +51,32 @@ func (h *ItemHandler) CreateItem(c *gin.Context) {
 		Category:    req.Category,
 		IsActive:    true,
 	}
+	item.SetSKU(req.SKU)
 
-	if err := database.DB.Create(&item).Error; err != nil {
+	if item.SKU != nil && skuTaken(*item.SKU, 0) {
+		utils.ErrorResponse(c, http.StatusConflict, "SKU already exists")
+		return
+	}
+
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&item).Error; err != nil {
+			return err
+		}
+		if err := pricing.SetRegular(tx, &item, item.Price, item.CreatedAt); err != nil {
+			return err
+		}
+		return audit.Record(tx, auditActor(c), audit.Event{
+			Action:     audit.ActionItemCreated,
+			TargetType: audit.TargetItem,
+			TargetID:   item.ID,
+			After:      item.ToResponse(),
+		})
+	})
+	if err != nil {
 		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to create item")
 		return
 	}
+	cache.InvalidateItem(item.ID)
 
 	utils.SuccessResponse(c, http.StatusCreated, "Item created successfully", item.ToResponse())
 }
@@ -65,17 +92,9 @@ func (h *ItemHandler) CreateItem(c *gin.Context) {
 // @Success 200 {object} utils.PaginatedResponse
 // @Router /items [get]
 func (h *ItemHandler) ListItems(c *gin.Context) {
-	// Parse pagination parameters
-	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
-	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
-	category := c.Query("category")
-
-	if page < 1 {
-		page = 1
-	}
-	if pageSize < 1 || pageSize > 100 {
-		pageSize = 20
-	}
+	// Parse pagination parameters, as the catalogue cache keys them
+	params := models.ParseItemListQuery(c.Request.URL.Query())
+	page, pageSize, category := params.Page, params.PageSize, params.Category
 
 	offset := (page - 1) * pageSize
 
@@ -108,11 +127,13 @@ func (h *ItemHandler) ListItems(c *gin.Context) {
 
 // GetItem handles GET /items/:id - Get a single item
 // @Summary Get item by ID
-// @Description Get detailed information about a specific item
+// @Description Get detailed information about a specific item. The ETag header carries the item's version; send it in If-None-Match to get 304 if the item is unchanged.
 // @Tags items
 // @Produce json
 // @Param id path int true "Item ID"
+// @Param If-None-Match header string false "ETag from an earlier response"
 // @Success 200 {object} utils.Response
+// @Success 304 "Not modified"
 // @Failure 404 {object} utils.Response
 // @Router /items/{id} [get]
 func (h *ItemHandler) GetItem(c *gin.Context) {
@@ -128,20 +149,23 @@ func (h *ItemHandler) GetItem(c *gin.Context) {
 		return
 	}
 
+	setVersionETag(c, item.Version)
 	utils.SuccessResponse(c, http.StatusOK, "Item retrieved successfully", item.ToResponse())
 }
 
 // UpdateItem handles PUT /items/:id - Update an item
 // @Summary Update item
-// @Description Update an existing item
+// @Description Update an existing item. Send the item's ETag in If-Match to fail with 412 rather than overwrite someone else's changes.
 // @Tags items
 // @Accept json
 // @Produce json
 // @Param id path int true "Item ID"
+// @Param If-Match header string false "ETag of the version being edited"
 // @Param item body models.ItemUpdateRequest true "Item data"
 // @Success 200 {object} utils.Response
 // @Failure 400 {object} utils.Response
 // @Failure 404 {object} utils.Response
+// @Failure 412 {object} utils.Response
 // @Router /items/{id} [put]
 func (h *ItemHandler) UpdateItem(c *gin.Context) {
 	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
@@ -156,22 +180,33 @@ func (h *ItemHandler) UpdateItem(c *gin.Context) {
 		return
 	}
 
+	if !checkIfMatch(c, item.Version, "Item") {
+		return
+	}
+
 	var req models.ItemUpdateRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		utils.ValidationErrorResponse(c, "Invalid request data", err.Error())
 		return
 	}
 
+	before := item.ToResponse()
+	wasActive := item.IsActive
+
 	// Update fields if provided
+	if req.SKU != nil {
+		item.SetSKU(*req.SKU)
+		if item.SKU != nil && skuTaken(*item.SKU, item.ID) {
+			utils.ErrorResponse(c, http.StatusConflict, "SKU already exists")
+			return
+		}
+	}
 	if req.Name != nil {
 		item.Name = *req.Name
 	}
 	if req.Description != nil {
 		item.Description = *req.Description
 	}
-	if req.Price != nil {
-		item.Price = *req.Price
-	}
 	if req.ImageURL != nil {
 		item.ImageURL = *req.ImageURL
 	}
@@ -182,22 +217,55 @@ func (h *ItemHandler) UpdateItem(c *gin.Context) {
 		item.IsActive = *req.IsActive
 	}
 
-	if err := database.DB.Save(&item).Error; err != nil {
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := models.ClaimVersion(tx, &item, item.Version); err != nil {
+			return err
+		}
+		if err := tx.Omit(models.ItemRatingColumns...).Save(&item).Error; err != nil {
+			return err
+		}
+		// A new price becomes the regular price from now on, recorded in the price history
+		if req.Price != nil {
+			if err := pricing.SetRegular(tx, &item, *req.Price, time.Now()); err != nil {
+				return err
+			}
+		}
+		if item.IsActive && !wasActive {
+			if err := events.Publish(tx, events.ItemRestocked{ItemID: item.ID, Price: item.Price}); err != nil {
+				return err
+			}
+		}
+		return audit.Record(tx, auditActor(c), audit.Event{
+			Action:     audit.ActionItemUpdated,
+			TargetType: audit.TargetItem,
+			TargetID:   item.ID,
+			Before:     before,
+			After:      item.ToResponse(),
+		})
+	})
+	if err != nil {
+		if respondVersionConflict(c, err, "Item") {
+			return
+		}
 		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to update item")
 		return
 	}
+	cache.InvalidateItem(item.ID)
 
+	setVersionETag(c, item.Version)
 	utils.SuccessResponse(c, http.StatusOK, "Item updated successfully", item.ToResponse())
 }
 
 // DeleteItem handles DELETE /items/:id - Delete an item
 // @Summary Delete item
-// @Description Soft delete an item
+// @Description Soft delete an item, optionally only if If-Match names its current version
 // @Tags items
 // @Produce json
 // @Param id path int true "Item ID"
+// @Param If-Match header string false "ETag of the version being deleted"
 // @Success 200 {object} utils.Response
 // @Failure 404 {object} utils.Response
+// @Failure 412 {object} utils.Response
 // @Router /items/{id} [delete]
 func (h *ItemHandler) DeleteItem(c *gin.Context) {
 	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
@@ -212,10 +280,32 @@ func (h *ItemHandler) DeleteItem(c *gin.Context) {
 		return
 	}
 
-	if err := database.DB.Delete(&item).Error; err != nil {
+	if !checkIfMatch(c, item.Version, "Item") {
+		return
+	}
+
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := models.ClaimVersion(tx, &item, item.Version); err != nil {
+			return err
+		}
+		if err := tx.Delete(&item).Error; err != nil {
+			return err
+		}
+		return audit.Record(tx, auditActor(c), audit.Event{
+			Action:     audit.ActionItemDeleted,
+			TargetType: audit.TargetItem,
+			TargetID:   item.ID,
+			Before:     item.ToResponse(),
+		})
+	})
+	if err != nil {
+		if respondVersionConflict(c, err, "Item") {
+			return
+		}
 		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to delete item")
 		return
 	}
+	cache.InvalidateItem(item.ID)
 
 	utils.SuccessResponse(c, http.StatusOK, "Item deleted successfully", nil)
 }
@@ -240,3 +330,10 @@ func (h *ItemHandler) GetCategories(c *gin.Context) {
 
 	utils.SuccessResponse(c, http.StatusOK, "Categories retrieved successfully", categories)
 }
+
+// skuTaken reports whether another item than exceptID already uses sku
+func skuTaken(sku string, exceptID uint) bool {
+	var count int64
+	database.DB.Model(&models.Item{}).Where("sku = ? AND id <> ?", sku, exceptID).Count(&count)
+	return count > 0
+}
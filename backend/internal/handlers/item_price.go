@@ -0,0 +1,212 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"shopease/internal/audit"
+	"shopease/internal/cache"
+	"shopease/internal/database"
+	"shopease/internal/middleware"
+	"shopease/internal/models"
+	"shopease/internal/pricing"
+	"shopease/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// GetPriceHistory handles GET /items/:id/price-history - Get an item's past, current and scheduled prices
+// @Summary Get item price history
+// @Description List an item's price entries, newest first. Scheduled prices are only shown to staff.
+// @Tags items
+// @Produce json
+// @Param id path int true "Item ID"
+// @Success 200 {object} utils.Response
+// @Failure 404 {object} utils.Response
+// @Router /items/{id}/price-history [get]
+func (h *ItemHandler) GetPriceHistory(c *gin.Context) {
+	item, ok := findItem(c)
+	if !ok {
+		return
+	}
+
+	prices, err := pricing.History(database.DB, item.ID)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to fetch price history")
+		return
+	}
+
+	user, _ := middleware.GetUserFromContext(c)
+	showScheduled := user != nil && user.IsStaff()
+
+	now := time.Now()
+	history := make([]models.ItemPriceResponse, 0, len(prices))
+	for i := len(prices) - 1; i >= 0; i-- {
+		entry := prices[i].ToResponse(now)
+		if entry.Status == "scheduled" && !showScheduled {
+			continue
+		}
+		history = append(history, entry)
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Price history retrieved successfully", history)
+}
+
+// SchedulePrice handles POST /items/:id/prices - Set or schedule a price (staff)
+// @Summary Schedule item price
+// @Description Add a regular price or a sale price, starting now or at effective_from. A sale ends at effective_until and shows compare_at_price (by default the regular price) alongside the sale price.
+// @Tags items
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "Item ID"
+// @Param price body models.ItemPriceRequest true "Price entry"
+// @Success 201 {object} utils.Response
+// @Failure 400 {object} utils.Response
+// @Failure 404 {object} utils.Response
+// @Failure 409 {object} utils.Response
+// @Router /items/{id}/prices [post]
+func (h *ItemHandler) SchedulePrice(c *gin.Context) {
+	item, ok := findItem(c)
+	if !ok {
+		return
+	}
+
+	var req models.ItemPriceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ValidationErrorResponse(c, "Invalid request data", err.Error())
+		return
+	}
+
+	before := item.ToResponse()
+	var entry *models.ItemPrice
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		if entry, err = pricing.Schedule(tx, item, req, time.Now()); err != nil {
+			return err
+		}
+		return audit.Record(tx, auditActor(c), audit.Event{
+			Action:     audit.ActionPriceScheduled,
+			TargetType: audit.TargetItem,
+			TargetID:   item.ID,
+			Before:     before,
+			After:      item.ToResponse(),
+			Details:    priceDetails(entry),
+		})
+	})
+	if err != nil {
+		respondPricingError(c, err, "Failed to schedule price")
+		return
+	}
+
+	invalidatePriceChange(before, item)
+
+	utils.SuccessResponse(c, http.StatusCreated, "Price scheduled successfully", entry.ToResponse(time.Now()))
+}
+
+// CancelPrice handles DELETE /items/:id/prices/:priceId - Cancel a scheduled price (staff)
+// @Summary Cancel scheduled price
+// @Description Remove a price entry that has not taken effect yet
+// @Tags items
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Item ID"
+// @Param priceId path int true "Price entry ID"
+// @Success 200 {object} utils.Response
+// @Failure 404 {object} utils.Response
+// @Failure 409 {object} utils.Response
+// @Router /items/{id}/prices/{priceId} [delete]
+func (h *ItemHandler) CancelPrice(c *gin.Context) {
+	item, ok := findItem(c)
+	if !ok {
+		return
+	}
+
+	priceID, err := strconv.ParseUint(c.Param("priceId"), 10, 32)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid price ID")
+		return
+	}
+
+	before := item.ToResponse()
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		entry, err := pricing.Cancel(tx, item, uint(priceID), time.Now())
+		if err != nil {
+			return err
+		}
+		return audit.Record(tx, auditActor(c), audit.Event{
+			Action:     audit.ActionPriceCancelled,
+			TargetType: audit.TargetItem,
+			TargetID:   item.ID,
+			Details:    priceDetails(entry),
+		})
+	})
+	if err != nil {
+		respondPricingError(c, err, "Failed to cancel price")
+		return
+	}
+
+	invalidatePriceChange(before, item)
+
+	utils.SuccessResponse(c, http.StatusOK, "Scheduled price cancelled", nil)
+}
+
+// findItem loads the item named by the :id param. It writes the error
+// response itself and returns false on failure.
+func findItem(c *gin.Context) (*models.Item, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid item ID")
+		return nil, false
+	}
+
+	var item models.Item
+	if err := database.DB.First(&item, id).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusNotFound, "Item not found")
+		return nil, false
+	}
+	return &item, true
+}
+
+// priceDetails describes a price entry for the audit log
+func priceDetails(entry *models.ItemPrice) map[string]interface{} {
+	details := map[string]interface{}{
+		"price_id":       entry.ID,
+		"kind":           entry.Kind,
+		"price":          entry.Price,
+		"effective_from": entry.EffectiveFrom.Format(time.RFC3339),
+	}
+	if entry.CompareAtPrice != nil {
+		details["compare_at_price"] = *entry.CompareAtPrice
+	}
+	if entry.EffectiveUntil != nil {
+		details["effective_until"] = entry.EffectiveUntil.Format(time.RFC3339)
+	}
+	return details
+}
+
+// invalidatePriceChange drops cached catalogue responses if a schedule change
+// moved the price straight away. Alerts hear of it from the ItemPriceChanged event.
+func invalidatePriceChange(before models.ItemResponse, item *models.Item) {
+	if item.Version != before.Version {
+		cache.InvalidateItem(item.ID)
+	}
+}
+
+// respondPricingError maps pricing errors to responses
+func respondPricingError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		utils.ErrorResponse(c, http.StatusNotFound, "Price not found")
+	case errors.Is(err, pricing.ErrStartTaken), errors.Is(err, pricing.ErrAlreadyStarted):
+		utils.ErrorResponse(c, http.StatusConflict, err.Error())
+	case errors.Is(err, pricing.ErrUnknownKind), errors.Is(err, pricing.ErrStartsInPast), errors.Is(err, pricing.ErrEmptyRange),
+		errors.Is(err, pricing.ErrSaleOnly), errors.Is(err, pricing.ErrCompareAtTooLow):
+		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+	default:
+		utils.ErrorResponse(c, http.StatusInternalServerError, message)
+	}
+}
@@ -0,0 +1,169 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"shopease/internal/audit"
+	"shopease/internal/database"
+	"shopease/internal/jobs"
+	"shopease/internal/models"
+	"shopease/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// ListJobs handles GET /admin/jobs - List background jobs (admin)
+// @Summary List jobs
+// @Description Get background jobs, newest first
+// @Tags admin
+// @Security BearerAuth
+// @Produce json
+// @Param state query string false "Filter by state (queued, running, succeeded, dead, cancelled)"
+// @Param type query string false "Filter by job type"
+// @Param page query int false "Page number" default(1)
+// @Param page_size query int false "Page size" default(20)
+// @Success 200 {object} utils.PaginatedResponse
+// @Failure 400 {object} utils.Response
+// @Router /admin/jobs [get]
+func (h *AdminHandler) ListJobs(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	offset := (page - 1) * pageSize
+
+	query := database.DB.Model(&models.Job{})
+	if state := models.JobState(c.Query("state")); state != "" {
+		if !state.IsValid() {
+			utils.ErrorResponse(c, http.StatusBadRequest, "Invalid state; use queued, running, succeeded, dead or cancelled")
+			return
+		}
+		query = query.Where("state = ?", state)
+	}
+	if kind := c.Query("type"); kind != "" {
+		query = query.Where("type = ?", kind)
+	}
+
+	var totalCount int64
+	query.Count(&totalCount)
+
+	var list []models.Job
+	if err := query.Order("id DESC").Offset(offset).Limit(pageSize).Find(&list).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to fetch jobs")
+		return
+	}
+
+	utils.PaginatedSuccessResponse(c, list, page, pageSize, totalCount)
+}
+
+// GetJob handles GET /admin/jobs/:id - Get a background job (admin)
+// @Summary Get job
+// @Description Get a background job with its attempts, last error and result. Payloads are not returned.
+// @Tags admin
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Job ID"
+// @Success 200 {object} utils.Response
+// @Failure 404 {object} utils.Response
+// @Router /admin/jobs/{id} [get]
+func (h *AdminHandler) GetJob(c *gin.Context) {
+	jobID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid job ID")
+		return
+	}
+
+	job, err := jobs.Get(database.DB, uint(jobID))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusNotFound, "Job not found")
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Job retrieved", job)
+}
+
+// RetryJob handles POST /admin/jobs/:id/retry - Run a job again (admin)
+// @Summary Retry job
+// @Description Queue a dead, cancelled or waiting job to run straight away with a fresh set of attempts
+// @Tags admin
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Job ID"
+// @Success 200 {object} utils.Response
+// @Failure 404 {object} utils.Response
+// @Failure 409 {object} utils.Response
+// @Router /admin/jobs/{id}/retry [post]
+func (h *AdminHandler) RetryJob(c *gin.Context) {
+	h.changeJob(c, jobs.Retry, audit.ActionJobRetried, "Job queued for retry")
+}
+
+// CancelJob handles POST /admin/jobs/:id/cancel - Cancel a queued job (admin)
+// @Summary Cancel job
+// @Description Stop a queued or scheduled job from running. Running jobs can't be cancelled.
+// @Tags admin
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Job ID"
+// @Success 200 {object} utils.Response
+// @Failure 404 {object} utils.Response
+// @Failure 409 {object} utils.Response
+// @Router /admin/jobs/{id}/cancel [post]
+func (h *AdminHandler) CancelJob(c *gin.Context) {
+	h.changeJob(c, jobs.Cancel, audit.ActionJobCancelled, "Job cancelled")
+}
+
+// changeJob applies a retry or cancel to the job in the path and audits it
+func (h *AdminHandler) changeJob(c *gin.Context, change func(*gorm.DB, uint) (*models.Job, error), action, message string) {
+	jobID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid job ID")
+		return
+	}
+
+	var job *models.Job
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		before, err := currentJobState(tx, uint(jobID))
+		if err != nil {
+			return err
+		}
+		if job, err = change(tx, uint(jobID)); err != nil {
+			return err
+		}
+		return audit.Record(tx, auditActor(c), audit.Event{
+			Action:     action,
+			TargetType: audit.TargetJob,
+			TargetID:   job.ID,
+			Before:     map[string]interface{}{"state": before},
+			After:      map[string]interface{}{"state": job.State},
+			Details:    map[string]interface{}{"type": job.Type},
+		})
+	})
+
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		utils.ErrorResponse(c, http.StatusNotFound, "Job not found")
+	case errors.Is(err, jobs.ErrNotRetryable), errors.Is(err, jobs.ErrNotCancellable):
+		utils.ErrorDataResponse(c, http.StatusConflict, err.Error(), job)
+	case err != nil:
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to update job")
+	default:
+		utils.SuccessResponse(c, http.StatusOK, message, job)
+	}
+}
+
+func currentJobState(tx *gorm.DB, id uint) (models.JobState, error) {
+	var job models.Job
+	if err := tx.Select("state").First(&job, id).Error; err != nil {
+		return "", err
+	}
+	return job.State, nil
+}
@@ -0,0 +1,138 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"shopease/internal/database"
+	"shopease/internal/middleware"
+	"shopease/internal/models"
+	"shopease/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// NotificationHandler handles in-app notification requests
+type NotificationHandler struct{}
+
+// NewNotificationHandler creates a new NotificationHandler
+func NewNotificationHandler() *NotificationHandler {
+	return &NotificationHandler{}
+}
+
+// ListNotifications handles GET /notifications - List the user's notifications
+// @Summary List my notifications
+// @Description Get the authenticated user's in-app notifications, newest first
+// @Tags notifications
+// @Security BearerAuth
+// @Produce json
+// @Param unread query bool false "Only unread notifications"
+// @Param page query int false "Page number" default(1)
+// @Param page_size query int false "Page size" default(20)
+// @Success 200 {object} utils.PaginatedResponse
+// @Router /notifications [get]
+func (h *NotificationHandler) ListNotifications(c *gin.Context) {
+	userID, exists := middleware.GetUserIDFromContext(c)
+	if !exists {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	offset := (page - 1) * pageSize
+
+	query := database.DB.Model(&models.Notification{}).Where("user_id = ?", userID)
+	if c.Query("unread") == "true" {
+		query = query.Where("read_at IS NULL")
+	}
+
+	var totalCount int64
+	query.Count(&totalCount)
+
+	var notifications []models.Notification
+	if err := query.Order("created_at DESC").Offset(offset).Limit(pageSize).Find(&notifications).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to fetch notifications")
+		return
+	}
+
+	utils.PaginatedSuccessResponse(c, notifications, page, pageSize, totalCount)
+}
+
+// MarkNotificationRead handles POST /notifications/:id/read - Mark a notification as read
+// @Summary Mark notification read
+// @Description Mark one of the user's notifications as read
+// @Tags notifications
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Notification ID"
+// @Success 200 {object} utils.Response
+// @Failure 404 {object} utils.Response
+// @Router /notifications/{id}/read [post]
+func (h *NotificationHandler) MarkNotificationRead(c *gin.Context) {
+	userID, exists := middleware.GetUserIDFromContext(c)
+	if !exists {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	notificationID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid notification ID")
+		return
+	}
+
+	var notification models.Notification
+	if err := database.DB.Where("user_id = ?", userID).First(&notification, notificationID).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusNotFound, "Notification not found")
+		return
+	}
+
+	if !notification.IsRead() {
+		now := time.Now()
+		notification.ReadAt = &now
+		if err := database.DB.Model(&notification).Update("read_at", now).Error; err != nil {
+			utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to update notification")
+			return
+		}
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Notification marked as read", notification)
+}
+
+// MarkAllNotificationsRead handles POST /notifications/read - Mark every notification as read
+// @Summary Mark all notifications read
+// @Description Mark all of the user's unread notifications as read
+// @Tags notifications
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {object} utils.Response
+// @Router /notifications/read [post]
+func (h *NotificationHandler) MarkAllNotificationsRead(c *gin.Context) {
+	userID, exists := middleware.GetUserIDFromContext(c)
+	if !exists {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	result := database.DB.Model(&models.Notification{}).
+		Where("user_id = ? AND read_at IS NULL", userID).
+		Update("read_at", time.Now())
+	if result.Error != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to update notifications")
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Notifications marked as read", gin.H{
+		"updated": result.RowsAffected,
+	})
+}
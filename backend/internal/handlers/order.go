@@ -4,12 +4,20 @@ import (
 	"net/http"
 	"strconv"
 
+	"shopease/internal/audit"
+	"shopease/internal/config"
 	"shopease/internal/database"
+	"shopease/internal/events"
+	"shopease/internal/metrics"
 	"shopease/internal/middleware"
 	"shopease/internal/models"
+	"shopease/internal/notify"
+	"shopease/internal/tracing"
 	"shopease/internal/utils"
 
 	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/attribute"
+	"gorm.io/gorm"
 )
 
 // OrderHandler handles order-related requests
@@ -22,7 +30,7 @@ func NewOrderHandler() *OrderHandler {
 
 // CreateOrder handles POST /orders - Create order from cart
 // @Summary Create order
-// @Description Convert cart to order and clear the cart
+// @Description Convert cart to order and clear the cart. If the cart has changed since items were added (prices, availability or quantity limits), it is rejected with 409 and the reviewed cart until cart_version confirms that review.
 // @Tags orders
 // @Security BearerAuth
 // @Accept json
@@ -31,8 +39,11 @@ func NewOrderHandler() *OrderHandler {
 // @Success 201 {object} utils.Response
 // @Failure 400 {object} utils.Response
 // @Failure 401 {object} utils.Response
+// @Failure 409 {object} utils.Response
 // @Router /orders [post]
 func (h *OrderHandler) CreateOrder(c *gin.Context) {
+	db := database.DB.WithContext(c.Request.Context())
+
 	userID, exists := middleware.GetUserIDFromContext(c)
 	if !exists {
 		utils.ErrorResponse(c, http.StatusUnauthorized, "User not authenticated")
@@ -45,9 +56,16 @@ func (h *OrderHandler) CreateOrder(c *gin.Context) {
 		return
 	}
 
+	if config.AppConfig.RequireVerifiedEmail {
+		if user, ok := middleware.GetUserFromContext(c); !ok || !user.IsEmailVerified() {
+			utils.ErrorResponse(c, http.StatusForbidden, "Please verify your email address before checking out")
+			return
+		}
+	}
+
 	// Find and validate cart
 	var cart models.Cart
-	if err := database.DB.Preload("CartItems.Item").First(&cart, req.CartID).Error; err != nil {
+	if err := db.Preload("CartItems.Item").First(&cart, req.CartID).Error; err != nil {
 		utils.ErrorResponse(c, http.StatusNotFound, "Cart not found")
 		return
 	}
@@ -64,26 +82,37 @@ func (h *OrderHandler) CreateOrder(c *gin.Context) {
 		return
 	}
 
-	// Calculate total and create order items
+	// Make sure the customer has seen any changes since they filled the cart
+	review := cart.Review(config.AppConfig.CartMaxQuantity)
+	if req.CartVersion != review.Version && (req.CartVersion != "" || len(review.Warnings) > 0) {
+		utils.ErrorDataResponse(c, http.StatusConflict, "Your cart has changed; please review it and confirm its new version", cartResponse(&cart))
+		return
+	}
+
+	// Calculate total and create order items, leaving out unavailable items
 	var totalAmount float64
-	orderItems := make([]models.OrderItem, len(cart.CartItems))
+	orderItems := make([]models.OrderItem, 0, len(review.Lines))
 
-	for i, cartItem := range cart.CartItems {
-		if cartItem.Item == nil {
-			utils.ErrorResponse(c, http.StatusBadRequest, "Invalid item in cart")
-			return
+	for _, line := range review.Lines {
+		if !line.Available {
+			continue
 		}
 
-		subtotal := float64(cartItem.Quantity) * cartItem.Item.Price
+		subtotal := line.Subtotal()
 		totalAmount += subtotal
 
-		orderItems[i] = models.OrderItem{
-			ItemID:    cartItem.ItemID,
-			ItemName:  cartItem.Item.Name,
-			ItemPrice: cartItem.Item.Price,
-			Quantity:  cartItem.Quantity,
+		orderItems = append(orderItems, models.OrderItem{
+			ItemID:    line.CartItem.ItemID,
+			ItemName:  line.CartItem.Item.Name,
+			ItemPrice: line.CartItem.Item.Price,
+			Quantity:  line.Quantity,
 			Subtotal:  subtotal,
-		}
+		})
+	}
+
+	if len(orderItems) == 0 {
+		utils.ErrorResponse(c, http.StatusBadRequest, "None of the items in your cart are available")
+		return
 	}
 
 	// Create order
@@ -95,14 +124,41 @@ func (h *OrderHandler) CreateOrder(c *gin.Context) {
 		OrderItems:  orderItems,
 	}
 
-	// Use transaction for data integrity
-	tx := database.DB.Begin()
+	tracing.SetAttributes(c.Request.Context(), attribute.Int("cart.id", int(cart.ID)))
+
+	// Use transaction for data integrity, in its own span so its cost is visible in traces
+	txCtx, txSpan := tracing.Tracer().Start(c.Request.Context(), "checkout transaction")
+	defer txSpan.End()
+	tx := db.WithContext(txCtx).Begin()
+
+	// Claim the cart version read above, so a concurrent checkout or cart edit
+	// can't be ordered twice or lost
+	if err := models.ClaimVersion(tx, &cart, cart.Version); err != nil {
+		tx.Rollback()
+		if respondVersionConflict(c, err, "Cart") {
+			return
+		}
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to create order")
+		return
+	}
 
 	if err := tx.Create(&order).Error; err != nil {
 		tx.Rollback()
 		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to create order")
 		return
 	}
+	tracing.SetAttributes(c.Request.Context(), attribute.Int("order.id", int(order.ID)))
+
+	if err := events.Publish(tx, events.OrderPlaced{
+		OrderID:     order.ID,
+		UserID:      order.UserID,
+		TotalAmount: order.TotalAmount,
+		ItemCount:   len(order.OrderItems),
+	}); err != nil {
+		tx.Rollback()
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to create order")
+		return
+	}
 
 	// Clear cart items
 	if err := tx.Where("cart_id = ?", cart.ID).Delete(&models.CartItem{}).Error; err != nil {
@@ -111,10 +167,15 @@ func (h *OrderHandler) CreateOrder(c *gin.Context) {
 		return
 	}
 
-	tx.Commit()
+	if err := tx.Commit().Error; err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to create order")
+		return
+	}
+	txSpan.End()
+	metrics.OrdersPlaced.Inc()
 
 	// Reload order with items
-	if err := database.DB.Preload("OrderItems").First(&order, order.ID).Error; err != nil {
+	if err := db.Preload("OrderItems").Preload("User").First(&order, order.ID).Error; err != nil {
 		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to reload order")
 		return
 	}
@@ -131,6 +192,8 @@ func (h *OrderHandler) CreateOrder(c *gin.Context) {
 // @Success 200 {object} utils.Response
 // @Router /orders/my [get]
 func (h *OrderHandler) GetMyOrders(c *gin.Context) {
+	db := database.DB.WithContext(c.Request.Context())
+
 	userID, exists := middleware.GetUserIDFromContext(c)
 	if !exists {
 		utils.ErrorResponse(c, http.StatusUnauthorized, "User not authenticated")
@@ -138,7 +201,7 @@ func (h *OrderHandler) GetMyOrders(c *gin.Context) {
 	}
 
 	var orders []models.Order
-	if err := database.DB.Preload("OrderItems").
+	if err := db.Preload("OrderItems").
 		Where("user_id = ?", userID).
 		Order("created_at DESC").
 		Find(&orders).Error; err != nil {
@@ -165,6 +228,8 @@ func (h *OrderHandler) GetMyOrders(c *gin.Context) {
 // @Success 200 {object} utils.PaginatedResponse
 // @Router /orders [get]
 func (h *OrderHandler) ListOrders(c *gin.Context) {
+	db := database.DB.WithContext(c.Request.Context())
+
 	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
 	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
 
@@ -178,10 +243,10 @@ func (h *OrderHandler) ListOrders(c *gin.Context) {
 	offset := (page - 1) * pageSize
 
 	var totalCount int64
-	database.DB.Model(&models.Order{}).Count(&totalCount)
+	db.Model(&models.Order{}).Count(&totalCount)
 
 	var orders []models.Order
-	if err := database.DB.Preload("OrderItems").Preload("User").
+	if err := db.Preload("OrderItems").Preload("User").
 		Order("created_at DESC").
 		Offset(offset).
 		Limit(pageSize).
@@ -209,6 +274,8 @@ func (h *OrderHandler) ListOrders(c *gin.Context) {
 // @Failure 404 {object} utils.Response
 // @Router /orders/{id} [get]
 func (h *OrderHandler) GetOrder(c *gin.Context) {
+	db := database.DB.WithContext(c.Request.Context())
+
 	userID, exists := middleware.GetUserIDFromContext(c)
 	if !exists {
 		utils.ErrorResponse(c, http.StatusUnauthorized, "User not authenticated")
@@ -220,9 +287,10 @@ func (h *OrderHandler) GetOrder(c *gin.Context) {
 		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid order ID")
 		return
 	}
+	tracing.SetAttributes(c.Request.Context(), attribute.Int("order.id", int(orderID)))
 
 	var order models.Order
-	if err := database.DB.Preload("OrderItems").First(&order, orderID).Error; err != nil {
+	if err := db.Preload("OrderItems").First(&order, orderID).Error; err != nil {
 		utils.ErrorResponse(c, http.StatusNotFound, "Order not found")
 		return
 	}
@@ -233,28 +301,35 @@ func (h *OrderHandler) GetOrder(c *gin.Context) {
 		return
 	}
 
+	setVersionETag(c, order.Version)
 	utils.SuccessResponse(c, http.StatusOK, "Order retrieved successfully", order.ToResponse())
 }
 
 // UpdateOrderStatus handles PATCH /orders/:id/status - Update order status
 // @Summary Update order status
-// @Description Update the status of an order (admin only)
+// @Description Update the status of an order (staff only). Send the order's ETag in If-Match to fail with 412 if it changed since it was read.
 // @Tags orders
 // @Security BearerAuth
 // @Accept json
 // @Produce json
 // @Param id path int true "Order ID"
+// @Param If-Match header string false "ETag of the order version being edited"
 // @Param status body object{status string} true "New status"
 // @Success 200 {object} utils.Response
 // @Failure 400 {object} utils.Response
+// @Failure 403 {object} utils.Response
 // @Failure 404 {object} utils.Response
+// @Failure 412 {object} utils.Response
 // @Router /orders/{id}/status [patch]
 func (h *OrderHandler) UpdateOrderStatus(c *gin.Context) {
+	db := database.DB.WithContext(c.Request.Context())
+
 	orderID, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
 		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid order ID")
 		return
 	}
+	tracing.SetAttributes(c.Request.Context(), attribute.Int("order.id", int(orderID)))
 
 	var req struct {
 		Status string `json:"status" binding:"required"`
@@ -266,53 +341,85 @@ func (h *OrderHandler) UpdateOrderStatus(c *gin.Context) {
 	}
 
 	// Validate status
-	validStatuses := map[string]models.OrderStatus{
-		"pending":   models.OrderStatusPending,
-		"confirmed": models.OrderStatusConfirmed,
-		"shipped":   models.OrderStatusShipped,
-		"delivered": models.OrderStatusDelivered,
-		"cancelled": models.OrderStatusCancelled,
-	}
-
-	newStatus, ok := validStatuses[req.Status]
+	newStatus, ok := models.ParseOrderStatus(req.Status)
 	if !ok {
 		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid order status")
 		return
 	}
 
 	var order models.Order
-	if err := database.DB.First(&order, orderID).Error; err != nil {
+	if err := db.First(&order, orderID).Error; err != nil {
 		utils.ErrorResponse(c, http.StatusNotFound, "Order not found")
 		return
 	}
 
+	if !checkIfMatch(c, order.Version, "Order") {
+		return
+	}
+
+	previousStatus := order.Status
 	order.Status = newStatus
-	if err := database.DB.Save(&order).Error; err != nil {
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := models.ClaimVersion(tx, &order, order.Version); err != nil {
+			return err
+		}
+		if err := tx.Save(&order).Error; err != nil {
+			return err
+		}
+		if previousStatus != newStatus {
+			if newStatus == models.OrderStatusCancelled {
+				if err := events.Publish(tx, events.OrderCancelled{OrderID: order.ID, UserID: order.UserID, PreviousStatus: previousStatus}); err != nil {
+					return err
+				}
+			}
+			if newStatus == models.OrderStatusShipped {
+				if err := notify.Send(tx, notify.Mail{Template: notify.TemplateOrderShipped, UserID: order.UserID, OrderID: order.ID}); err != nil {
+					return err
+				}
+			}
+		}
+		return audit.Record(tx, auditActor(c), audit.Event{
+			Action:     audit.ActionOrderStatusChanged,
+			TargetType: audit.TargetOrder,
+			TargetID:   order.ID,
+			Before:     map[string]interface{}{"status": previousStatus},
+			After:      map[string]interface{}{"status": newStatus},
+		})
+	})
+	if err != nil {
+		if respondVersionConflict(c, err, "Order") {
+			return
+		}
 		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to update order status")
 		return
 	}
 
 	// Reload with items
-	if err := database.DB.Preload("OrderItems").First(&order, order.ID).Error; err != nil {
+	if err := db.Preload("OrderItems").Preload("User").First(&order, order.ID).Error; err != nil {
 		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to reload order")
 		return
 	}
 
+	setVersionETag(c, order.Version)
 	utils.SuccessResponse(c, http.StatusOK, "Order status updated", order.ToResponse())
 }
 
 // CancelOrder handles POST /orders/:id/cancel - Cancel an order
 // @Summary Cancel order
-// @Description Cancel a pending order
+// @Description Cancel a pending order, optionally only if If-Match names its current version
 // @Tags orders
 // @Security BearerAuth
 // @Produce json
 // @Param id path int true "Order ID"
+// @Param If-Match header string false "ETag of the order version being cancelled"
 // @Success 200 {object} utils.Response
 // @Failure 400 {object} utils.Response
 // @Failure 404 {object} utils.Response
+// @Failure 412 {object} utils.Response
 // @Router /orders/{id}/cancel [post]
 func (h *OrderHandler) CancelOrder(c *gin.Context) {
+	db := database.DB.WithContext(c.Request.Context())
+
 	userID, exists := middleware.GetUserIDFromContext(c)
 	if !exists {
 		utils.ErrorResponse(c, http.StatusUnauthorized, "User not authenticated")
@@ -324,9 +431,10 @@ func (h *OrderHandler) CancelOrder(c *gin.Context) {
 		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid order ID")
 		return
 	}
+	tracing.SetAttributes(c.Request.Context(), attribute.Int("order.id", int(orderID)))
 
 	var order models.Order
-	if err := database.DB.First(&order, orderID).Error; err != nil {
+	if err := db.Preload("OrderItems").Preload("User").First(&order, orderID).Error; err != nil {
 		utils.ErrorResponse(c, http.StatusNotFound, "Order not found")
 		return
 	}
@@ -343,11 +451,29 @@ func (h *OrderHandler) CancelOrder(c *gin.Context) {
 		return
 	}
 
+	if !checkIfMatch(c, order.Version, "Order") {
+		return
+	}
+
+	previousStatus := order.Status
 	order.Status = models.OrderStatusCancelled
-	if err := database.DB.Save(&order).Error; err != nil {
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := models.ClaimVersion(tx, &order, order.Version); err != nil {
+			return err
+		}
+		if err := tx.Model(&models.Order{}).Where("id = ?", order.ID).Update("status", order.Status).Error; err != nil {
+			return err
+		}
+		return events.Publish(tx, events.OrderCancelled{OrderID: order.ID, UserID: order.UserID, PreviousStatus: previousStatus})
+	})
+	if err != nil {
+		if respondVersionConflict(c, err, "Order") {
+			return
+		}
 		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to cancel order")
 		return
 	}
 
+	setVersionETag(c, order.Version)
 	utils.SuccessResponse(c, http.StatusOK, "Order cancelled successfully", order.ToListResponse())
 }
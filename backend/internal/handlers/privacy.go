@@ -0,0 +1,128 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"shopease/internal/audit"
+	"shopease/internal/config"
+	"shopease/internal/database"
+	"shopease/internal/middleware"
+	"shopease/internal/models"
+	"shopease/internal/privacy"
+	"shopease/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// ExportMyData handles GET /users/me/export - Download everything stored about the current user
+// @Summary Export my data
+// @Description Download the profile, cart, orders, wishlists, reviews, alerts, notifications and login history as one JSON document or a ZIP of JSON files
+// @Tags users
+// @Security BearerAuth
+// @Produce json,application/zip
+// @Param format query string false "json (default) or zip"
+// @Success 200 {file} file
+// @Failure 400 {object} utils.Response
+// @Failure 401 {object} utils.Response
+// @Router /users/me/export [get]
+func (h *UserHandler) ExportMyData(c *gin.Context) {
+	user, exists := middleware.GetUserFromContext(c)
+	if !exists {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "User not found")
+		return
+	}
+
+	format := c.DefaultQuery("format", "json")
+	if format != "json" && format != "zip" {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Unsupported format; use format=json or format=zip")
+		return
+	}
+
+	bundle, err := privacy.Export(c.Request.Context(), user)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to export data")
+		return
+	}
+
+	if err := audit.Record(database.DB, auditActor(c), audit.Event{
+		Action:     audit.ActionDataExported,
+		TargetType: audit.TargetUser,
+		TargetID:   user.ID,
+		Details:    map[string]interface{}{"format": format},
+	}); err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to export data")
+		return
+	}
+
+	filename := fmt.Sprintf("shopease-export-%s.%s", bundle.ExportedAt.Format("20060102"), format)
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+	if format == "json" {
+		c.JSON(http.StatusOK, bundle)
+		return
+	}
+
+	c.Header("Content-Type", "application/zip")
+	c.Status(http.StatusOK)
+	// Headers are already sent, so a failure part-way can only be logged
+	if err := bundle.WriteZip(c.Writer); err != nil {
+		log.Printf("Data export for user %d failed: %v", user.ID, err)
+	}
+}
+
+// DeleteMyAccount handles DELETE /users/me - Delete the current user's account
+// @Summary Delete my account
+// @Description Anonymise the account straight away and purge it after the configured grace period. Orders are kept, without personal data, for accounting.
+// @Tags users
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param request body models.AccountDeleteRequest true "Current password, and a two-factor code if enabled"
+// @Success 200 {object} utils.Response
+// @Failure 400 {object} utils.Response
+// @Failure 401 {object} utils.Response
+// @Router /users/me [delete]
+func (h *UserHandler) DeleteMyAccount(c *gin.Context) {
+	user, exists := middleware.GetUserFromContext(c)
+	if !exists {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "User not found")
+		return
+	}
+
+	var req models.AccountDeleteRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ValidationErrorResponse(c, "Invalid request data", err.Error())
+		return
+	}
+
+	if !user.CheckPassword(req.Password) {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid password")
+		return
+	}
+
+	purgeAt := time.Now().AddDate(0, 0, config.AppConfig.AccountPurgeDays)
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if user.HasTwoFactor() {
+			if err := verifySecondFactor(tx, user, req.Code, req.Code); err != nil {
+				return err
+			}
+		}
+		return privacy.Anonymise(tx, user, auditActor(c), purgeAt)
+	})
+	if errors.Is(err, errInvalidSecondFactor) {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid two-factor code")
+		return
+	}
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to delete account")
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Account deleted", gin.H{
+		"purge_at": purgeAt.UTC(),
+	})
+}
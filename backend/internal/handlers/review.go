@@ -0,0 +1,376 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"shopease/internal/audit"
+	"shopease/internal/cache"
+	"shopease/internal/database"
+	"shopease/internal/middleware"
+	"shopease/internal/models"
+	"shopease/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// ReviewHandler handles review-related requests
+type ReviewHandler struct{}
+
+// NewReviewHandler creates a new ReviewHandler
+func NewReviewHandler() *ReviewHandler {
+	return &ReviewHandler{}
+}
+
+// CreateReview handles POST /items/:id/reviews - Review a purchased item
+// @Summary Review an item
+// @Description Rate an item. Only users with a delivered order containing the item may review it, once.
+// @Tags reviews
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "Item ID"
+// @Param review body models.ReviewCreateRequest true "Review data"
+// @Success 201 {object} utils.Response
+// @Failure 400 {object} utils.Response
+// @Failure 403 {object} utils.Response
+// @Failure 409 {object} utils.Response
+// @Router /items/{id}/reviews [post]
+func (h *ReviewHandler) CreateReview(c *gin.Context) {
+	user, exists := middleware.GetUserFromContext(c)
+	if !exists {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	itemID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid item ID")
+		return
+	}
+
+	var req models.ReviewCreateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ValidationErrorResponse(c, "Invalid request data", err.Error())
+		return
+	}
+
+	var item models.Item
+	if err := database.DB.First(&item, itemID).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusNotFound, "Item not found")
+		return
+	}
+
+	// Only verified buyers may review
+	var purchases int64
+	database.DB.Model(&models.OrderItem{}).
+		Joins("JOIN orders ON orders.id = order_items.order_id AND orders.deleted_at IS NULL").
+		Where("orders.user_id = ? AND orders.status = ? AND order_items.item_id = ?", user.ID, models.OrderStatusDelivered, item.ID).
+		Count(&purchases)
+
+	if purchases == 0 {
+		utils.ErrorResponse(c, http.StatusForbidden, "Only customers who received this item can review it")
+		return
+	}
+
+	// One review per user per item
+	var existing int64
+	database.DB.Unscoped().Model(&models.Review{}).Where("item_id = ? AND user_id = ?", item.ID, user.ID).Count(&existing)
+	if existing > 0 {
+		utils.ErrorResponse(c, http.StatusConflict, "You have already reviewed this item")
+		return
+	}
+
+	review := models.Review{
+		ItemID: item.ID,
+		UserID: user.ID,
+		Rating: req.Rating,
+		Title:  req.Title,
+		Body:   req.Body,
+		Status: models.ReviewStatusPublished,
+	}
+
+	tx := database.DB.Begin()
+
+	if err := tx.Create(&review).Error; err != nil {
+		tx.Rollback()
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to create review")
+		return
+	}
+
+	if err := models.ApplyRatingDelta(tx, review.ItemID, review.Rating, 1); err != nil {
+		tx.Rollback()
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to update item rating")
+		return
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to create review")
+		return
+	}
+	cache.InvalidateItem(review.ItemID) // Rating aggregates changed
+
+	review.User = user
+	utils.SuccessResponse(c, http.StatusCreated, "Review submitted successfully", review.ToResponse())
+}
+
+// ListItemReviews handles GET /items/:id/reviews - List published reviews for an item
+// @Summary List item reviews
+// @Description Get published reviews for an item, most helpful or most recent first
+// @Tags reviews
+// @Produce json
+// @Param id path int true "Item ID"
+// @Param page query int false "Page number" default(1)
+// @Param page_size query int false "Page size" default(20)
+// @Param sort query string false "Sort order (recent, helpful)" default(recent)
+// @Success 200 {object} utils.PaginatedResponse
+// @Router /items/{id}/reviews [get]
+func (h *ReviewHandler) ListItemReviews(c *gin.Context) {
+	itemID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid item ID")
+		return
+	}
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	offset := (page - 1) * pageSize
+
+	order := "created_at DESC"
+	if c.Query("sort") == "helpful" {
+		order = "helpful_count DESC, created_at DESC"
+	}
+
+	query := database.DB.Model(&models.Review{}).
+		Where("item_id = ? AND status = ?", itemID, models.ReviewStatusPublished)
+
+	var totalCount int64
+	query.Count(&totalCount)
+
+	var reviews []models.Review
+	if err := query.Preload("User").Order(order).Offset(offset).Limit(pageSize).Find(&reviews).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to fetch reviews")
+		return
+	}
+
+	responses := make([]models.ReviewResponse, len(reviews))
+	for i, review := range reviews {
+		responses[i] = review.ToResponse()
+	}
+
+	utils.PaginatedSuccessResponse(c, responses, page, pageSize, totalCount)
+}
+
+// MarkHelpful handles POST /reviews/:id/helpful - Vote a review as helpful
+// @Summary Mark review as helpful
+// @Description Record a helpful vote for a review (one vote per user)
+// @Tags reviews
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Review ID"
+// @Success 200 {object} utils.Response
+// @Failure 404 {object} utils.Response
+// @Failure 409 {object} utils.Response
+// @Router /reviews/{id}/helpful [post]
+func (h *ReviewHandler) MarkHelpful(c *gin.Context) {
+	userID, exists := middleware.GetUserIDFromContext(c)
+	if !exists {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	reviewID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid review ID")
+		return
+	}
+
+	var review models.Review
+	if err := database.DB.Where("status = ?", models.ReviewStatusPublished).First(&review, reviewID).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusNotFound, "Review not found")
+		return
+	}
+
+	if review.UserID == userID {
+		utils.ErrorResponse(c, http.StatusBadRequest, "You cannot vote on your own review")
+		return
+	}
+
+	var votes int64
+	database.DB.Model(&models.ReviewVote{}).Where("review_id = ? AND user_id = ?", review.ID, userID).Count(&votes)
+	if votes > 0 {
+		utils.ErrorResponse(c, http.StatusConflict, "You have already voted on this review")
+		return
+	}
+
+	tx := database.DB.Begin()
+
+	if err := tx.Create(&models.ReviewVote{ReviewID: review.ID, UserID: userID}).Error; err != nil {
+		tx.Rollback()
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to record vote")
+		return
+	}
+
+	if err := tx.Model(&review).UpdateColumn("helpful_count", gorm.Expr("helpful_count + 1")).Error; err != nil {
+		tx.Rollback()
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to record vote")
+		return
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to record vote")
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Vote recorded", gin.H{
+		"helpful_count": review.HelpfulCount + 1,
+	})
+}
+
+// ListReviews handles GET /reviews - List reviews for moderation (staff)
+// @Summary List reviews for moderation
+// @Description Get reviews across all items, optionally filtered by status
+// @Tags reviews
+// @Security BearerAuth
+// @Produce json
+// @Param status query string false "Filter by status"
+// @Param page query int false "Page number" default(1)
+// @Param page_size query int false "Page size" default(20)
+// @Success 200 {object} utils.PaginatedResponse
+// @Router /reviews [get]
+func (h *ReviewHandler) ListReviews(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+	status := c.Query("status")
+
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	offset := (page - 1) * pageSize
+
+	query := database.DB.Model(&models.Review{})
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	var totalCount int64
+	query.Count(&totalCount)
+
+	var reviews []models.Review
+	if err := query.Preload("User").Order("created_at DESC").Offset(offset).Limit(pageSize).Find(&reviews).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to fetch reviews")
+		return
+	}
+
+	responses := make([]models.ReviewResponse, len(reviews))
+	for i, review := range reviews {
+		responses[i] = review.ToResponse()
+	}
+
+	utils.PaginatedSuccessResponse(c, responses, page, pageSize, totalCount)
+}
+
+// ModerateReview handles PATCH /reviews/:id/status - Change a review's moderation status (staff)
+// @Summary Moderate review
+// @Description Publish, hold or reject a review. Item rating aggregates are adjusted accordingly.
+// @Tags reviews
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "Review ID"
+// @Param status body models.ReviewModerateRequest true "New status"
+// @Success 200 {object} utils.Response
+// @Failure 400 {object} utils.Response
+// @Failure 404 {object} utils.Response
+// @Failure 409 {object} utils.Response
+// @Router /reviews/{id}/status [patch]
+func (h *ReviewHandler) ModerateReview(c *gin.Context) {
+	reviewID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid review ID")
+		return
+	}
+
+	var req models.ReviewModerateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ValidationErrorResponse(c, "Invalid request data", err.Error())
+		return
+	}
+
+	var review models.Review
+	if err := database.DB.Preload("User").First(&review, reviewID).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusNotFound, "Review not found")
+		return
+	}
+
+	wasPublished := review.IsPublished()
+	previousStatus := review.Status
+	review.Status = req.Status
+
+	tx := database.DB.Begin()
+
+	// Only move the review from the status read above, so two moderators
+	// racing cannot both apply a rating delta for the same transition
+	result := tx.Model(&models.Review{}).Where("id = ? AND status = ?", review.ID, previousStatus).Update("status", review.Status)
+	if result.Error != nil {
+		tx.Rollback()
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to update review")
+		return
+	}
+	if result.RowsAffected != 1 {
+		tx.Rollback()
+		utils.ErrorResponse(c, http.StatusConflict, "Review was moderated by someone else, please reload and try again")
+		return
+	}
+
+	// Keep item aggregates in step with the set of published reviews
+	delta := 0
+	if wasPublished && !review.IsPublished() {
+		delta = -1
+	} else if !wasPublished && review.IsPublished() {
+		delta = 1
+	}
+
+	if delta != 0 {
+		if err := models.ApplyRatingDelta(tx, review.ItemID, review.Rating, delta); err != nil {
+			tx.Rollback()
+			utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to update item rating")
+			return
+		}
+	}
+
+	if err := audit.Record(tx, auditActor(c), audit.Event{
+		Action:     audit.ActionReviewModerated,
+		TargetType: audit.TargetReview,
+		TargetID:   review.ID,
+		Before:     map[string]interface{}{"status": previousStatus},
+		After:      map[string]interface{}{"status": review.Status},
+	}); err != nil {
+		tx.Rollback()
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to update review")
+		return
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to update review")
+		return
+	}
+	if delta != 0 {
+		cache.InvalidateItem(review.ItemID)
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Review status updated", review.ToResponse())
+}
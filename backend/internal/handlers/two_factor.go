@@ -0,0 +1,413 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"shopease/internal/audit"
+	"shopease/internal/config"
+	"shopease/internal/database"
+	"shopease/internal/middleware"
+	"shopease/internal/models"
+	"shopease/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// errInvalidSecondFactor is returned when a TOTP or recovery code is wrong, used or replayed
+var errInvalidSecondFactor = errors.New("invalid two-factor code")
+
+// SetupTwoFactor handles POST /users/2fa/setup - Start TOTP enrolment
+// @Summary Start two-factor setup
+// @Description Generate a new TOTP secret. 2FA is not active until confirmed with a code.
+// @Tags users
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {object} models.TwoFactorSetupResponse
+// @Failure 409 {object} utils.Response
+// @Router /users/2fa/setup [post]
+func (h *UserHandler) SetupTwoFactor(c *gin.Context) {
+	user, exists := middleware.GetUserFromContext(c)
+	if !exists {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "User not found")
+		return
+	}
+
+	if user.HasTwoFactor() {
+		utils.ErrorResponse(c, http.StatusConflict, "Two-factor authentication is already enabled")
+		return
+	}
+
+	secret, err := utils.GenerateTOTPSecret()
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to generate secret")
+		return
+	}
+
+	if err := database.DB.Model(user).Update("totp_secret", secret).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to start two-factor setup")
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Scan the code with your authenticator app, then confirm", models.TwoFactorSetupResponse{
+		Secret:     secret,
+		OTPAuthURI: utils.TOTPURI(config.AppConfig.TOTPIssuer, user.Username, secret),
+	})
+}
+
+// ConfirmTwoFactor handles POST /users/2fa/confirm - Finish TOTP enrolment
+// @Summary Confirm two-factor setup
+// @Description Enable 2FA with a code from the authenticator app. Returns one-time recovery codes.
+// @Tags users
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param request body models.TwoFactorCodeRequest true "Current TOTP code"
+// @Success 200 {object} models.RecoveryCodesResponse
+// @Failure 400 {object} utils.Response
+// @Router /users/2fa/confirm [post]
+func (h *UserHandler) ConfirmTwoFactor(c *gin.Context) {
+	user, exists := middleware.GetUserFromContext(c)
+	if !exists {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "User not found")
+		return
+	}
+
+	var req models.TwoFactorCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ValidationErrorResponse(c, "Invalid request data", err.Error())
+		return
+	}
+
+	if user.HasTwoFactor() {
+		utils.ErrorResponse(c, http.StatusConflict, "Two-factor authentication is already enabled")
+		return
+	}
+
+	if user.TOTPSecret == "" {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Two-factor setup has not been started")
+		return
+	}
+
+	step, ok := utils.ValidateTOTPCode(user.TOTPSecret, req.Code, time.Now())
+	if !ok {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid two-factor code")
+		return
+	}
+
+	tx := database.DB.Begin()
+
+	now := time.Now()
+	if err := tx.Model(user).Updates(map[string]interface{}{
+		"totp_enabled_at": now,
+		"totp_last_step":  step,
+	}).Error; err != nil {
+		tx.Rollback()
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to enable two-factor authentication")
+		return
+	}
+
+	codes, err := replaceRecoveryCodes(tx, user.ID)
+	if err != nil {
+		tx.Rollback()
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to generate recovery codes")
+		return
+	}
+
+	if err := audit.Record(tx, auditActor(c), audit.Event{Action: audit.ActionTwoFactorEnabled, TargetType: audit.TargetUser, TargetID: user.ID}); err != nil {
+		tx.Rollback()
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to enable two-factor authentication")
+		return
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to enable two-factor authentication")
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Two-factor authentication enabled. Store these recovery codes safely.", models.RecoveryCodesResponse{
+		RecoveryCodes: codes,
+	})
+}
+
+// DisableTwoFactor handles POST /users/2fa/disable - Turn off 2FA
+// @Summary Disable two-factor authentication
+// @Description Turn off 2FA. Requires the password and a current TOTP or recovery code.
+// @Tags users
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param request body models.TwoFactorDisableRequest true "Password and code"
+// @Success 200 {object} utils.Response
+// @Failure 400 {object} utils.Response
+// @Router /users/2fa/disable [post]
+func (h *UserHandler) DisableTwoFactor(c *gin.Context) {
+	user, exists := middleware.GetUserFromContext(c)
+	if !exists {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "User not found")
+		return
+	}
+
+	var req models.TwoFactorDisableRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ValidationErrorResponse(c, "Invalid request data", err.Error())
+		return
+	}
+
+	if !user.HasTwoFactor() {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Two-factor authentication is not enabled")
+		return
+	}
+
+	if !user.CheckPassword(req.Password) {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid password")
+		return
+	}
+
+	tx := database.DB.Begin()
+
+	if err := verifySecondFactor(tx, user, req.Code, req.Code); err != nil {
+		tx.Rollback()
+		if errors.Is(err, errInvalidSecondFactor) {
+			utils.ErrorResponse(c, http.StatusBadRequest, "Invalid two-factor code")
+			return
+		}
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to disable two-factor authentication")
+		return
+	}
+
+	if err := tx.Model(user).Updates(map[string]interface{}{
+		"totp_secret":     "",
+		"totp_enabled_at": nil,
+		"totp_last_step":  0,
+	}).Error; err != nil {
+		tx.Rollback()
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to disable two-factor authentication")
+		return
+	}
+
+	if err := tx.Where("user_id = ?", user.ID).Delete(&models.RecoveryCode{}).Error; err != nil {
+		tx.Rollback()
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to disable two-factor authentication")
+		return
+	}
+
+	if err := audit.Record(tx, auditActor(c), audit.Event{Action: audit.ActionTwoFactorDisabled, TargetType: audit.TargetUser, TargetID: user.ID}); err != nil {
+		tx.Rollback()
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to disable two-factor authentication")
+		return
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to disable two-factor authentication")
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Two-factor authentication disabled", nil)
+}
+
+// RegenerateRecoveryCodes handles POST /users/2fa/recovery-codes - Replace recovery codes
+// @Summary Regenerate recovery codes
+// @Description Invalidate all existing recovery codes and issue a new set
+// @Tags users
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param request body models.TwoFactorCodeRequest true "Current TOTP code"
+// @Success 200 {object} models.RecoveryCodesResponse
+// @Failure 400 {object} utils.Response
+// @Router /users/2fa/recovery-codes [post]
+func (h *UserHandler) RegenerateRecoveryCodes(c *gin.Context) {
+	user, exists := middleware.GetUserFromContext(c)
+	if !exists {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "User not found")
+		return
+	}
+
+	var req models.TwoFactorCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ValidationErrorResponse(c, "Invalid request data", err.Error())
+		return
+	}
+
+	if !user.HasTwoFactor() {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Two-factor authentication is not enabled")
+		return
+	}
+
+	tx := database.DB.Begin()
+
+	if err := verifySecondFactor(tx, user, req.Code, ""); err != nil {
+		tx.Rollback()
+		if errors.Is(err, errInvalidSecondFactor) {
+			utils.ErrorResponse(c, http.StatusBadRequest, "Invalid two-factor code")
+			return
+		}
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to generate recovery codes")
+		return
+	}
+
+	codes, err := replaceRecoveryCodes(tx, user.ID)
+	if err != nil {
+		tx.Rollback()
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to generate recovery codes")
+		return
+	}
+
+	if err := audit.Record(tx, auditActor(c), audit.Event{Action: audit.ActionRecoveryCodesRegenerated, TargetType: audit.TargetUser, TargetID: user.ID}); err != nil {
+		tx.Rollback()
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to generate recovery codes")
+		return
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to generate recovery codes")
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Recovery codes regenerated", models.RecoveryCodesResponse{
+		RecoveryCodes: codes,
+	})
+}
+
+// LoginTwoFactor handles POST /users/login/2fa - Second step of a two-factor login
+// @Summary Complete two-factor login
+// @Description Exchange a login challenge token and a TOTP or recovery code for a session token
+// @Tags users
+// @Accept json
+// @Produce json
+// @Param request body models.TwoFactorLoginRequest true "Challenge token and code"
+// @Success 200 {object} models.LoginResponse
+// @Failure 400 {object} utils.Response
+// @Failure 401 {object} utils.Response
+// @Failure 403 {object} utils.Response
+// @Failure 429 {object} utils.Response
+// @Router /users/login/2fa [post]
+func (h *UserHandler) LoginTwoFactor(c *gin.Context) {
+	var req models.TwoFactorLoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ValidationErrorResponse(c, "Invalid request data", err.Error())
+		return
+	}
+
+	claims, err := utils.ValidateChallengeToken(req.ChallengeToken)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "Invalid or expired challenge token")
+		return
+	}
+
+	var user models.User
+	if err := database.DB.First(&user, claims.UserID).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "Invalid or expired challenge token")
+		return
+	}
+
+	if !user.HasTwoFactor() {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Two-factor authentication is not enabled")
+		return
+	}
+
+	// Check if user is already logged in on another device (SINGLE-DEVICE ENFORCEMENT)
+	if user.HasActiveSession() {
+		utils.ErrorResponse(c, http.StatusForbidden, "User is already logged in on another device")
+		return
+	}
+
+	// Second-factor guesses count towards the same back-off and lockout as passwords
+	if block := checkLoginAllowed(user.Username, c.ClientIP(), time.Now()); block != nil {
+		respondLoginBlocked(c, block)
+		return
+	}
+
+	tx := database.DB.Begin()
+
+	if err := verifySecondFactor(tx, &user, req.Code, req.RecoveryCode); err != nil {
+		tx.Rollback()
+		if errors.Is(err, errInvalidSecondFactor) {
+			recordLoginAttempt(c, user.Username, &user.ID, models.LoginOutcomeFailure, models.LoginFailureInvalidTwoFactor)
+			utils.ErrorResponse(c, http.StatusBadRequest, "Invalid two-factor code")
+			return
+		}
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to verify two-factor code")
+		return
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to verify two-factor code")
+		return
+	}
+
+	completeLogin(c, &user)
+}
+
+// verifySecondFactor accepts either a TOTP code or an unused recovery code.
+// Accepted TOTP steps and recovery codes are burned so neither can be replayed.
+func verifySecondFactor(tx *gorm.DB, user *models.User, code, recoveryCode string) error {
+	if code != "" {
+		if step, ok := utils.ValidateTOTPCode(user.TOTPSecret, code, time.Now()); ok {
+			result := tx.Model(&models.User{}).
+				Where("id = ? AND totp_last_step < ?", user.ID, step).
+				Update("totp_last_step", step)
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected == 0 {
+				return errInvalidSecondFactor
+			}
+			user.TOTPLastStep = step
+			return nil
+		}
+	}
+
+	if recoveryCode != "" {
+		result := tx.Model(&models.RecoveryCode{}).
+			Where("user_id = ? AND code_hash = ? AND used_at IS NULL", user.ID, utils.HashToken(normalizeRecoveryCode(recoveryCode))).
+			Update("used_at", time.Now())
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 1 {
+			return nil
+		}
+	}
+
+	return errInvalidSecondFactor
+}
+
+// replaceRecoveryCodes deletes a user's recovery codes and issues a fresh set.
+// It returns the plaintext codes, which are never stored.
+func replaceRecoveryCodes(tx *gorm.DB, userID uint) ([]string, error) {
+	if err := tx.Where("user_id = ?", userID).Delete(&models.RecoveryCode{}).Error; err != nil {
+		return nil, err
+	}
+
+	codes := make([]string, models.RecoveryCodeCount)
+	records := make([]models.RecoveryCode, models.RecoveryCodeCount)
+
+	for i := range codes {
+		raw, err := utils.GenerateRandomToken(5)
+		if err != nil {
+			return nil, err
+		}
+		codes[i] = raw[:5] + "-" + raw[5:]
+		records[i] = models.RecoveryCode{
+			UserID:   userID,
+			CodeHash: utils.HashToken(raw),
+		}
+	}
+
+	if err := tx.Create(&records).Error; err != nil {
+		return nil, err
+	}
+
+	return codes, nil
+}
+
+// normalizeRecoveryCode strips formatting so codes can be typed with or without the dash
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(strings.TrimSpace(code))
+	return strings.ReplaceAll(code, "-", "")
+}
@@ -2,13 +2,18 @@ package handlers
 
 import (
 	"net/http"
+	"time"
 
+	"shopease/internal/audit"
 	"shopease/internal/database"
+	"shopease/internal/events"
 	"shopease/internal/middleware"
 	"shopease/internal/models"
+	"shopease/internal/notify"
 	"shopease/internal/utils"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
 // UserHandler handles user-related requests
@@ -49,9 +54,22 @@ func (h *UserHandler) CreateUser(c *gin.Context) {
 		Username: req.Username,
 		Password: req.Password, // Will be hashed by BeforeCreate hook
 		Email:    req.Email,
+		Role:     models.UserRoleCustomer,
 	}
 
-	if err := database.DB.Create(&user).Error; err != nil {
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&user).Error; err != nil {
+			return err
+		}
+		if err := events.Publish(tx, events.UserRegistered{UserID: user.ID}); err != nil {
+			return err
+		}
+		if user.Email == "" {
+			return nil
+		}
+		return notify.Send(tx, notify.Mail{Template: notify.TemplateVerifyEmail, UserID: user.ID})
+	})
+	if err != nil {
 		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to create user")
 		return
 	}
@@ -90,9 +108,10 @@ func (h *UserHandler) ListUsers(c *gin.Context) {
 // @Accept json
 // @Produce json
 // @Param credentials body models.UserLoginRequest true "Login credentials"
-// @Success 200 {object} models.LoginResponse
+// @Success 200 {object} models.LoginResponse "Session token, or models.TwoFactorChallengeResponse when 2FA is enabled"
 // @Failure 400 {object} utils.Response
 // @Failure 403 {object} utils.Response
+// @Failure 429 {object} utils.Response
 // @Router /users/login [post]
 func (h *UserHandler) Login(c *gin.Context) {
 	var req models.UserLoginRequest
@@ -102,9 +121,25 @@ func (h *UserHandler) Login(c *gin.Context) {
 		return
 	}
 
-	// Find user by username
+	// Brute-force protection: back-off, lockout and per-IP throttling
+	if block := checkLoginAllowed(req.Username, c.ClientIP(), time.Now()); block != nil {
+		respondLoginBlocked(c, block)
+		return
+	}
+
+	// Find user by username. Unknown users still pay for a bcrypt comparison
+	// so response timing doesn't reveal which usernames exist.
 	var user models.User
 	if err := database.DB.Where("username = ?", req.Username).First(&user).Error; err != nil {
+		models.CheckDummyPassword(req.Password)
+		recordLoginAttempt(c, req.Username, nil, models.LoginOutcomeFailure, models.LoginFailureInvalidCredentials)
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid username/password")
+		return
+	}
+
+	// Verify password
+	if !user.CheckPassword(req.Password) {
+		recordLoginAttempt(c, user.Username, &user.ID, models.LoginOutcomeFailure, models.LoginFailureInvalidCredentials)
 		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid username/password")
 		return
 	}
@@ -115,12 +150,27 @@ func (h *UserHandler) Login(c *gin.Context) {
 		return
 	}
 
-	// Verify password
-	if !user.CheckPassword(req.Password) {
-		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid username/password")
+	// Accounts with 2FA must complete a second step before a session is issued
+	if user.HasTwoFactor() {
+		challenge, err := utils.GenerateChallengeToken(user.ID, user.Username)
+		if err != nil {
+			utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to generate token")
+			return
+		}
+
+		utils.SuccessResponse(c, http.StatusOK, "Two-factor authentication required", models.TwoFactorChallengeResponse{
+			TwoFactorRequired: true,
+			ChallengeToken:    challenge,
+			ExpiresIn:         int(utils.TwoFactorChallengeTTL.Seconds()),
+		})
 		return
 	}
 
+	completeLogin(c, &user)
+}
+
+// completeLogin issues a session token for an authenticated user and writes the login response
+func completeLogin(c *gin.Context, user *models.User) {
 	// Generate JWT token
 	token, err := utils.GenerateToken(user.ID, user.Username)
 	if err != nil {
@@ -130,11 +180,13 @@ func (h *UserHandler) Login(c *gin.Context) {
 
 	// Store token in database (for single-device enforcement)
 	user.SetToken(token)
-	if err := database.DB.Save(&user).Error; err != nil {
+	if err := database.DB.Model(user).Update("token", token).Error; err != nil {
 		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to save session")
 		return
 	}
 
+	recordLoginAttempt(c, user.Username, &user.ID, models.LoginOutcomeSuccess, "")
+
 	// Return token
 	response := models.LoginResponse{
 		Token: token,
@@ -163,7 +215,13 @@ func (h *UserHandler) Logout(c *gin.Context) {
 
 	// Clear the token in the database
 	user.ClearToken()
-	if err := database.DB.Save(user).Error; err != nil {
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(user).Error; err != nil {
+			return err
+		}
+		return audit.Record(tx, auditActor(c), audit.Event{Action: audit.ActionLogout, TargetType: audit.TargetUser, TargetID: user.ID})
+	})
+	if err != nil {
 		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to logout")
 		return
 	}
@@ -189,7 +247,9 @@ func (h *UserHandler) GetCurrentUser(c *gin.Context) {
 
 	utils.SuccessResponse(c, http.StatusOK, "User retrieved successfully", user.ToResponse())
 }
+
 // ToggleFavorite handles POST /users/favorites - Add/Remove from favorites
+// Favorites are the user's default wishlist
 func (h *UserHandler) ToggleFavorite(c *gin.Context) {
 	user, exists := middleware.GetUserFromContext(c)
 	if !exists {
@@ -213,25 +273,38 @@ func (h *UserHandler) ToggleFavorite(c *gin.Context) {
 		return
 	}
 
+	wishlist, err := ensureDefaultWishlist(database.DB, user.ID)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to update favorites")
+		return
+	}
+
 	// Check if already in favorites
-	var count int64
-	database.DB.Table("user_favorites").Where("user_id = ? AND item_id = ?", user.ID, req.ItemID).Count(&count)
+	var wishlistItem models.WishlistItem
+	inFavorites := database.DB.Where("wishlist_id = ? AND item_id = ?", wishlist.ID, item.ID).First(&wishlistItem).Error == nil
 
 	action := "added"
-	if count > 0 {
+	tx := database.DB.Begin()
+	if inFavorites {
 		// Remove from favorites
-		if err := database.DB.Model(user).Association("Favorites").Delete(&item); err != nil {
+		if err := deleteWishlistItem(tx, &wishlistItem); err != nil {
+			tx.Rollback()
 			utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to remove from favorites")
 			return
 		}
 		action = "removed"
 	} else {
 		// Add to favorites
-		if err := database.DB.Model(user).Association("Favorites").Append(&item); err != nil {
+		if err := insertWishlistItem(tx, wishlist.ID, item.ID, "", nil); err != nil {
+			tx.Rollback()
 			utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to add to favorites")
 			return
 		}
 	}
+	if err := tx.Commit().Error; err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to update favorites")
+		return
+	}
 
 	utils.SuccessResponse(c, http.StatusOK, "Favorites updated", gin.H{
 		"action": action,
@@ -239,6 +312,7 @@ func (h *UserHandler) ToggleFavorite(c *gin.Context) {
 }
 
 // GetFavorites handles GET /users/favorites - Get user's favorite items
+// Returns the items on the user's default wishlist, in list order
 func (h *UserHandler) GetFavorites(c *gin.Context) {
 	user, exists := middleware.GetUserFromContext(c)
 	if !exists {
@@ -246,8 +320,18 @@ func (h *UserHandler) GetFavorites(c *gin.Context) {
 		return
 	}
 
+	wishlist, err := ensureDefaultWishlist(database.DB, user.ID)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to fetch favorites")
+		return
+	}
+
 	var favorites []models.Item
-	if err := database.DB.Model(user).Association("Favorites").Find(&favorites); err != nil {
+	if err := database.DB.
+		Joins("JOIN wishlist_items ON wishlist_items.item_id = items.id").
+		Where("wishlist_items.wishlist_id = ?", wishlist.ID).
+		Order("wishlist_items.position ASC").
+		Find(&favorites).Error; err != nil {
 		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to fetch favorites")
 		return
 	}
@@ -0,0 +1,812 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"shopease/internal/database"
+	"shopease/internal/middleware"
+	"shopease/internal/models"
+	"shopease/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// WishlistHandler handles wishlist-related requests
+type WishlistHandler struct{}
+
+// NewWishlistHandler creates a new WishlistHandler
+func NewWishlistHandler() *WishlistHandler {
+	return &WishlistHandler{}
+}
+
+// ListWishlists handles GET /wishlists - List the user's wishlists
+// @Summary List my wishlists
+// @Description Get all of the authenticated user's wishlists with their items
+// @Tags wishlists
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {object} utils.Response
+// @Router /wishlists [get]
+func (h *WishlistHandler) ListWishlists(c *gin.Context) {
+	userID, exists := middleware.GetUserIDFromContext(c)
+	if !exists {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	if _, err := ensureDefaultWishlist(database.DB, userID); err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to fetch wishlists")
+		return
+	}
+
+	var wishlists []models.Wishlist
+	if err := preloadWishlistItems(database.DB).
+		Where("user_id = ?", userID).
+		Order("is_default DESC, created_at ASC").
+		Find(&wishlists).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to fetch wishlists")
+		return
+	}
+
+	responses := make([]models.WishlistResponse, len(wishlists))
+	for i, wishlist := range wishlists {
+		responses[i] = wishlist.ToResponse()
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Wishlists retrieved successfully", responses)
+}
+
+// CreateWishlist handles POST /wishlists - Create a named wishlist
+// @Summary Create wishlist
+// @Description Create a new named wishlist
+// @Tags wishlists
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param wishlist body models.WishlistCreateRequest true "Wishlist data"
+// @Success 201 {object} utils.Response
+// @Failure 400 {object} utils.Response
+// @Router /wishlists [post]
+func (h *WishlistHandler) CreateWishlist(c *gin.Context) {
+	userID, exists := middleware.GetUserIDFromContext(c)
+	if !exists {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	var req models.WishlistCreateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ValidationErrorResponse(c, "Invalid request data", err.Error())
+		return
+	}
+
+	// The first list a user creates becomes their default
+	var count int64
+	database.DB.Model(&models.Wishlist{}).Where("user_id = ?", userID).Count(&count)
+
+	wishlist := models.Wishlist{
+		UserID:    userID,
+		Name:      req.Name,
+		IsDefault: count == 0,
+	}
+
+	result := database.DB.Clauses(clause.OnConflict{DoNothing: true}).Create(&wishlist)
+	if result.Error == nil && result.RowsAffected == 0 {
+		// A concurrent request created the default list first
+		wishlist.IsDefault = false
+		result = database.DB.Create(&wishlist)
+	}
+	if result.Error != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to create wishlist")
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusCreated, "Wishlist created successfully", wishlist.ToResponse())
+}
+
+// GetWishlist handles GET /wishlists/:id - Get a wishlist
+// @Summary Get wishlist
+// @Description Get one of the authenticated user's wishlists
+// @Tags wishlists
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Wishlist ID"
+// @Success 200 {object} utils.Response
+// @Failure 404 {object} utils.Response
+// @Router /wishlists/{id} [get]
+func (h *WishlistHandler) GetWishlist(c *gin.Context) {
+	wishlist, ok := findOwnWishlist(c)
+	if !ok {
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Wishlist retrieved successfully", wishlist.ToResponse())
+}
+
+// UpdateWishlist handles PATCH /wishlists/:id - Rename a wishlist or make it the default
+// @Summary Update wishlist
+// @Description Rename a wishlist or mark it as the default list
+// @Tags wishlists
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "Wishlist ID"
+// @Param wishlist body models.WishlistUpdateRequest true "Wishlist data"
+// @Success 200 {object} utils.Response
+// @Failure 400 {object} utils.Response
+// @Failure 404 {object} utils.Response
+// @Router /wishlists/{id} [patch]
+func (h *WishlistHandler) UpdateWishlist(c *gin.Context) {
+	wishlist, ok := findOwnWishlist(c)
+	if !ok {
+		return
+	}
+
+	var req models.WishlistUpdateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ValidationErrorResponse(c, "Invalid request data", err.Error())
+		return
+	}
+
+	if req.IsDefault != nil && !*req.IsDefault && wishlist.IsDefault {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Mark another wishlist as default instead")
+		return
+	}
+
+	tx := database.DB.Begin()
+
+	if req.Name != nil {
+		wishlist.Name = *req.Name
+	}
+
+	// Only one default list per user
+	if req.IsDefault != nil && *req.IsDefault && !wishlist.IsDefault {
+		if err := tx.Model(&models.Wishlist{}).
+			Where("user_id = ? AND is_default = ?", wishlist.UserID, true).
+			Update("is_default", false).Error; err != nil {
+			tx.Rollback()
+			utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to update wishlist")
+			return
+		}
+		wishlist.IsDefault = true
+	}
+
+	if err := tx.Model(&models.Wishlist{}).Where("id = ?", wishlist.ID).Updates(map[string]interface{}{
+		"name":       wishlist.Name,
+		"is_default": wishlist.IsDefault,
+	}).Error; err != nil {
+		tx.Rollback()
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to update wishlist")
+		return
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to update wishlist")
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Wishlist updated successfully", wishlist.ToResponse())
+}
+
+// DeleteWishlist handles DELETE /wishlists/:id - Delete a wishlist
+// @Summary Delete wishlist
+// @Description Delete a wishlist and its saved items. The default list cannot be deleted.
+// @Tags wishlists
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Wishlist ID"
+// @Success 200 {object} utils.Response
+// @Failure 400 {object} utils.Response
+// @Failure 404 {object} utils.Response
+// @Router /wishlists/{id} [delete]
+func (h *WishlistHandler) DeleteWishlist(c *gin.Context) {
+	wishlist, ok := findOwnWishlist(c)
+	if !ok {
+		return
+	}
+
+	if wishlist.IsDefault {
+		utils.ErrorResponse(c, http.StatusBadRequest, "The default wishlist cannot be deleted")
+		return
+	}
+
+	tx := database.DB.Begin()
+
+	if err := tx.Where("wishlist_id = ?", wishlist.ID).Delete(&models.WishlistItem{}).Error; err != nil {
+		tx.Rollback()
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to delete wishlist")
+		return
+	}
+
+	// Release the share token so it can never resolve again
+	if err := tx.Model(&models.Wishlist{}).Where("id = ?", wishlist.ID).Update("share_token", nil).Error; err != nil {
+		tx.Rollback()
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to delete wishlist")
+		return
+	}
+
+	if err := tx.Delete(&models.Wishlist{}, wishlist.ID).Error; err != nil {
+		tx.Rollback()
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to delete wishlist")
+		return
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to delete wishlist")
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Wishlist deleted successfully", nil)
+}
+
+// AddWishlistItem handles POST /wishlists/:id/items - Save an item to a wishlist
+// @Summary Add item to wishlist
+// @Description Save an item to a wishlist, optionally with a note and position
+// @Tags wishlists
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "Wishlist ID"
+// @Param item body models.WishlistItemRequest true "Item to save"
+// @Success 200 {object} utils.Response
+// @Failure 404 {object} utils.Response
+// @Failure 409 {object} utils.Response
+// @Router /wishlists/{id}/items [post]
+func (h *WishlistHandler) AddWishlistItem(c *gin.Context) {
+	wishlist, ok := findOwnWishlist(c)
+	if !ok {
+		return
+	}
+
+	var req models.WishlistItemRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ValidationErrorResponse(c, "Invalid request data", err.Error())
+		return
+	}
+
+	var item models.Item
+	if err := database.DB.First(&item, req.ItemID).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusNotFound, "Item not found")
+		return
+	}
+
+	if wishlistContains(wishlist, item.ID) {
+		utils.ErrorResponse(c, http.StatusConflict, "Item is already on this wishlist")
+		return
+	}
+
+	tx := database.DB.Begin()
+
+	if err := insertWishlistItem(tx, wishlist.ID, item.ID, req.Note, req.Position); err != nil {
+		tx.Rollback()
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to add item to wishlist")
+		return
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to add item to wishlist")
+		return
+	}
+
+	h.respondWithWishlist(c, wishlist.ID, "Item added to wishlist")
+}
+
+// UpdateWishlistItem handles PATCH /wishlists/:id/items/:itemId - Edit a saved item's note or position
+// @Summary Update wishlist item
+// @Description Change the note or position of a saved item
+// @Tags wishlists
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "Wishlist ID"
+// @Param itemId path int true "Item ID"
+// @Param item body models.WishlistItemUpdateRequest true "Changes"
+// @Success 200 {object} utils.Response
+// @Failure 404 {object} utils.Response
+// @Router /wishlists/{id}/items/{itemId} [patch]
+func (h *WishlistHandler) UpdateWishlistItem(c *gin.Context) {
+	wishlist, ok := findOwnWishlist(c)
+	if !ok {
+		return
+	}
+
+	wishlistItem, ok := findWishlistItem(c, wishlist)
+	if !ok {
+		return
+	}
+
+	var req models.WishlistItemUpdateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ValidationErrorResponse(c, "Invalid request data", err.Error())
+		return
+	}
+
+	tx := database.DB.Begin()
+
+	if req.Note != nil {
+		if err := tx.Model(&models.WishlistItem{}).Where("id = ?", wishlistItem.ID).Update("note", *req.Note).Error; err != nil {
+			tx.Rollback()
+			utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to update wishlist item")
+			return
+		}
+	}
+
+	if req.Position != nil {
+		if err := repositionWishlistItem(tx, wishlistItem, *req.Position, len(wishlist.Items)); err != nil {
+			tx.Rollback()
+			utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to update wishlist item")
+			return
+		}
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to update wishlist item")
+		return
+	}
+
+	h.respondWithWishlist(c, wishlist.ID, "Wishlist item updated")
+}
+
+// RemoveWishlistItem handles DELETE /wishlists/:id/items/:itemId - Remove an item from a wishlist
+// @Summary Remove item from wishlist
+// @Description Remove a saved item from a wishlist
+// @Tags wishlists
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Wishlist ID"
+// @Param itemId path int true "Item ID"
+// @Success 200 {object} utils.Response
+// @Failure 404 {object} utils.Response
+// @Router /wishlists/{id}/items/{itemId} [delete]
+func (h *WishlistHandler) RemoveWishlistItem(c *gin.Context) {
+	wishlist, ok := findOwnWishlist(c)
+	if !ok {
+		return
+	}
+
+	wishlistItem, ok := findWishlistItem(c, wishlist)
+	if !ok {
+		return
+	}
+
+	tx := database.DB.Begin()
+
+	if err := deleteWishlistItem(tx, wishlistItem); err != nil {
+		tx.Rollback()
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to remove item from wishlist")
+		return
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to remove item from wishlist")
+		return
+	}
+
+	h.respondWithWishlist(c, wishlist.ID, "Item removed from wishlist")
+}
+
+// MoveWishlistItem handles POST /wishlists/:id/items/:itemId/move - Move an item to another wishlist
+// @Summary Move wishlist item
+// @Description Move a saved item, with its note, to another of the user's wishlists
+// @Tags wishlists
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "Source wishlist ID"
+// @Param itemId path int true "Item ID"
+// @Param target body models.WishlistMoveRequest true "Target wishlist"
+// @Success 200 {object} utils.Response
+// @Failure 404 {object} utils.Response
+// @Failure 409 {object} utils.Response
+// @Router /wishlists/{id}/items/{itemId}/move [post]
+func (h *WishlistHandler) MoveWishlistItem(c *gin.Context) {
+	wishlist, ok := findOwnWishlist(c)
+	if !ok {
+		return
+	}
+
+	wishlistItem, ok := findWishlistItem(c, wishlist)
+	if !ok {
+		return
+	}
+
+	var req models.WishlistMoveRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ValidationErrorResponse(c, "Invalid request data", err.Error())
+		return
+	}
+
+	var target models.Wishlist
+	if err := preloadWishlistItems(database.DB).
+		Where("id = ? AND user_id = ?", req.TargetWishlistID, wishlist.UserID).
+		First(&target).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusNotFound, "Target wishlist not found")
+		return
+	}
+
+	if target.ID == wishlist.ID {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Item is already on this wishlist")
+		return
+	}
+
+	if wishlistContains(&target, wishlistItem.ItemID) {
+		utils.ErrorResponse(c, http.StatusConflict, "Item is already on the target wishlist")
+		return
+	}
+
+	tx := database.DB.Begin()
+
+	if err := deleteWishlistItem(tx, wishlistItem); err != nil {
+		tx.Rollback()
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to move item")
+		return
+	}
+
+	if err := insertWishlistItem(tx, target.ID, wishlistItem.ItemID, wishlistItem.Note, nil); err != nil {
+		tx.Rollback()
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to move item")
+		return
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to move item")
+		return
+	}
+
+	h.respondWithWishlist(c, target.ID, "Item moved")
+}
+
+// ShareWishlist handles POST /wishlists/:id/share - Create a public share link
+// @Summary Share wishlist
+// @Description Generate an unguessable token giving read-only public access to the wishlist.
+// @Description Calling it again rotates the token and invalidates the previous link.
+// @Tags wishlists
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Wishlist ID"
+// @Success 200 {object} utils.Response
+// @Failure 404 {object} utils.Response
+// @Router /wishlists/{id}/share [post]
+func (h *WishlistHandler) ShareWishlist(c *gin.Context) {
+	wishlist, ok := findOwnWishlist(c)
+	if !ok {
+		return
+	}
+
+	token, err := utils.GenerateRandomToken(24)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to generate share link")
+		return
+	}
+
+	if err := database.DB.Model(&models.Wishlist{}).Where("id = ?", wishlist.ID).Update("share_token", token).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to generate share link")
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Share link created", gin.H{
+		"share_token": token,
+		"share_path":  "/api/v1/wishlists/shared/" + token,
+	})
+}
+
+// UnshareWishlist handles DELETE /wishlists/:id/share - Revoke the public share link
+// @Summary Stop sharing wishlist
+// @Description Revoke the wishlist's public share link
+// @Tags wishlists
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Wishlist ID"
+// @Success 200 {object} utils.Response
+// @Failure 404 {object} utils.Response
+// @Router /wishlists/{id}/share [delete]
+func (h *WishlistHandler) UnshareWishlist(c *gin.Context) {
+	wishlist, ok := findOwnWishlist(c)
+	if !ok {
+		return
+	}
+
+	if err := database.DB.Model(&models.Wishlist{}).Where("id = ?", wishlist.ID).Update("share_token", nil).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to revoke share link")
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Share link revoked", nil)
+}
+
+// GetSharedWishlist handles GET /wishlists/shared/:token - View a shared wishlist
+// @Summary View shared wishlist
+// @Description Get a wishlist by its public share token (no authentication required)
+// @Tags wishlists
+// @Produce json
+// @Param token path string true "Share token"
+// @Success 200 {object} utils.Response
+// @Failure 404 {object} utils.Response
+// @Router /wishlists/shared/{token} [get]
+func (h *WishlistHandler) GetSharedWishlist(c *gin.Context) {
+	token := c.Param("token")
+	if token == "" {
+		utils.ErrorResponse(c, http.StatusNotFound, "Wishlist not found")
+		return
+	}
+
+	var wishlist models.Wishlist
+	if err := preloadWishlistItems(database.DB).Where("share_token = ?", token).First(&wishlist).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusNotFound, "Wishlist not found")
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Wishlist retrieved successfully", wishlist.ToResponse())
+}
+
+// MoveToCart handles POST /wishlists/:id/cart - Move every available item into the cart
+// @Summary Move wishlist to cart
+// @Description Add each active item on the wishlist to the cart (quantity 1) and remove it from the list.
+// @Description Unavailable items stay on the wishlist and are reported back.
+// @Tags wishlists
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Wishlist ID"
+// @Success 200 {object} utils.Response
+// @Failure 404 {object} utils.Response
+// @Router /wishlists/{id}/cart [post]
+func (h *WishlistHandler) MoveToCart(c *gin.Context) {
+	wishlist, ok := findOwnWishlist(c)
+	if !ok {
+		return
+	}
+
+	moved := []uint{}
+	unavailable := []uint{}
+
+	tx := database.DB.Begin()
+
+	cart, err := getOrCreateCart(tx, wishlist.UserID)
+	if err != nil {
+		tx.Rollback()
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to create cart")
+		return
+	}
+
+	for _, wishlistItem := range wishlist.Items {
+		if wishlistItem.Item == nil || !wishlistItem.Item.IsActive {
+			unavailable = append(unavailable, wishlistItem.ItemID)
+			continue
+		}
+
+		if err := addCartItem(tx, cart.ID, wishlistItem.Item, 1); err != nil {
+			tx.Rollback()
+			if errors.Is(err, errCartQuantityLimit) {
+				utils.ErrorResponse(c, http.StatusBadRequest, quantityLimitMessage())
+				return
+			}
+			utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to add item to cart")
+			return
+		}
+
+		moved = append(moved, wishlistItem.ItemID)
+	}
+
+	if len(moved) > 0 {
+		if err := tx.Where("wishlist_id = ? AND item_id IN ?", wishlist.ID, moved).Delete(&models.WishlistItem{}).Error; err != nil {
+			tx.Rollback()
+			utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to update wishlist")
+			return
+		}
+
+		if err := compactWishlistPositions(tx, wishlist.ID); err != nil {
+			tx.Rollback()
+			utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to update wishlist")
+			return
+		}
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to move wishlist to cart")
+		return
+	}
+
+	if err := database.DB.Preload("CartItems.Item").First(cart, cart.ID).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to reload cart")
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Wishlist moved to cart", gin.H{
+		"moved_item_ids":       moved,
+		"unavailable_item_ids": unavailable,
+		"cart":                 cartResponse(cart),
+	})
+}
+
+// respondWithWishlist reloads a wishlist and sends it as a success response
+func (h *WishlistHandler) respondWithWishlist(c *gin.Context, wishlistID uint, message string) {
+	var wishlist models.Wishlist
+	if err := preloadWishlistItems(database.DB).First(&wishlist, wishlistID).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to reload wishlist")
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, message, wishlist.ToResponse())
+}
+
+// preloadWishlistItems preloads wishlist items in display order along with their catalogue items
+func preloadWishlistItems(db *gorm.DB) *gorm.DB {
+	return db.Preload("Items", func(db *gorm.DB) *gorm.DB {
+		return db.Order("position ASC, id ASC")
+	}).Preload("Items.Item")
+}
+
+// findOwnWishlist loads the wishlist named by the :id param, checking it belongs to the current user.
+// It writes the error response itself and returns false on failure.
+func findOwnWishlist(c *gin.Context) (*models.Wishlist, bool) {
+	userID, exists := middleware.GetUserIDFromContext(c)
+	if !exists {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "User not authenticated")
+		return nil, false
+	}
+
+	wishlistID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid wishlist ID")
+		return nil, false
+	}
+
+	var wishlist models.Wishlist
+	if err := preloadWishlistItems(database.DB).First(&wishlist, wishlistID).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusNotFound, "Wishlist not found")
+		return nil, false
+	}
+
+	if wishlist.UserID != userID {
+		utils.ErrorResponse(c, http.StatusForbidden, "Not authorized to access this wishlist")
+		return nil, false
+	}
+
+	return &wishlist, true
+}
+
+// findWishlistItem finds the saved entry for the :itemId param on a loaded wishlist
+func findWishlistItem(c *gin.Context, wishlist *models.Wishlist) (*models.WishlistItem, bool) {
+	itemID, err := strconv.ParseUint(c.Param("itemId"), 10, 32)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid item ID")
+		return nil, false
+	}
+
+	for i := range wishlist.Items {
+		if wishlist.Items[i].ItemID == uint(itemID) {
+			return &wishlist.Items[i], true
+		}
+	}
+
+	utils.ErrorResponse(c, http.StatusNotFound, "Item is not on this wishlist")
+	return nil, false
+}
+
+// wishlistContains reports whether a loaded wishlist already holds an item
+func wishlistContains(wishlist *models.Wishlist, itemID uint) bool {
+	for _, wishlistItem := range wishlist.Items {
+		if wishlistItem.ItemID == itemID {
+			return true
+		}
+	}
+	return false
+}
+
+// ensureDefaultWishlist returns the user's default wishlist, creating it on first use.
+// Concurrent first requests race to create it; the database keeps one default per
+// user, and the losers read the winner's list.
+func ensureDefaultWishlist(db *gorm.DB, userID uint) (*models.Wishlist, error) {
+	var wishlist models.Wishlist
+	err := db.Where("user_id = ? AND is_default = ?", userID, true).First(&wishlist).Error
+	if err == nil {
+		return &wishlist, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	wishlist = models.Wishlist{
+		UserID:    userID,
+		Name:      models.DefaultWishlistName,
+		IsDefault: true,
+	}
+	result := db.Clauses(clause.OnConflict{DoNothing: true}).Create(&wishlist)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		wishlist = models.Wishlist{}
+		if err := db.Where("user_id = ? AND is_default = ?", userID, true).First(&wishlist).Error; err != nil {
+			return nil, err
+		}
+	}
+	return &wishlist, nil
+}
+
+// insertWishlistItem saves an item on a wishlist at position, or at the end if position is nil
+func insertWishlistItem(tx *gorm.DB, wishlistID, itemID uint, note string, position *int) error {
+	var count int64
+	if err := tx.Model(&models.WishlistItem{}).Where("wishlist_id = ?", wishlistID).Count(&count).Error; err != nil {
+		return err
+	}
+
+	at := int(count)
+	if position != nil && *position < at {
+		at = *position
+
+		// Make room for the new entry
+		if err := tx.Model(&models.WishlistItem{}).
+			Where("wishlist_id = ? AND position >= ?", wishlistID, at).
+			UpdateColumn("position", gorm.Expr("position + 1")).Error; err != nil {
+			return err
+		}
+	}
+
+	return tx.Create(&models.WishlistItem{
+		WishlistID: wishlistID,
+		ItemID:     itemID,
+		Position:   at,
+		Note:       note,
+	}).Error
+}
+
+// deleteWishlistItem removes an entry and closes the gap it leaves in the ordering
+func deleteWishlistItem(tx *gorm.DB, wishlistItem *models.WishlistItem) error {
+	if err := tx.Delete(&models.WishlistItem{}, wishlistItem.ID).Error; err != nil {
+		return err
+	}
+
+	return tx.Model(&models.WishlistItem{}).
+		Where("wishlist_id = ? AND position > ?", wishlistItem.WishlistID, wishlistItem.Position).
+		UpdateColumn("position", gorm.Expr("position - 1")).Error
+}
+
+// repositionWishlistItem moves an entry to a new position, shifting the entries in between
+func repositionWishlistItem(tx *gorm.DB, wishlistItem *models.WishlistItem, position int, size int) error {
+	if position >= size {
+		position = size - 1
+	}
+
+	from := wishlistItem.Position
+	if position == from {
+		return nil
+	}
+
+	shift := tx.Model(&models.WishlistItem{}).Where("wishlist_id = ? AND id <> ?", wishlistItem.WishlistID, wishlistItem.ID)
+	var err error
+	if position < from {
+		err = shift.Where("position >= ? AND position < ?", position, from).
+			UpdateColumn("position", gorm.Expr("position + 1")).Error
+	} else {
+		err = shift.Where("position > ? AND position <= ?", from, position).
+			UpdateColumn("position", gorm.Expr("position - 1")).Error
+	}
+	if err != nil {
+		return err
+	}
+
+	return tx.Model(&models.WishlistItem{}).Where("id = ?", wishlistItem.ID).Update("position", position).Error
+}
+
+// compactWishlistPositions renumbers a wishlist's entries 0..n-1, preserving their order
+func compactWishlistPositions(tx *gorm.DB, wishlistID uint) error {
+	var wishlistItems []models.WishlistItem
+	if err := tx.Where("wishlist_id = ?", wishlistID).Order("position ASC, id ASC").Find(&wishlistItems).Error; err != nil {
+		return err
+	}
+
+	for i, wishlistItem := range wishlistItems {
+		if wishlistItem.Position == i {
+			continue
+		}
+		if err := tx.Model(&models.WishlistItem{}).Where("id = ?", wishlistItem.ID).Update("position", i).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
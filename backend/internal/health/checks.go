@@ -0,0 +1,84 @@
+package health
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"time"
+)
+
+// DatabaseCheck pings the database connection pool
+func DatabaseCheck(db *sql.DB) Check {
+	return func(ctx context.Context) error {
+		return db.PingContext(ctx)
+	}
+}
+
+// SchemaVersionCheck fails if the database schema is older than the code expects,
+// e.g. when a deploy's migrations haven't run yet
+func SchemaVersionCheck(current func(ctx context.Context) (int, error), want int) Check {
+	return func(ctx context.Context) error {
+		version, err := current(ctx)
+		if err != nil {
+			return err
+		}
+		if version < want {
+			return fmt.Errorf("schema version %d is older than required version %d", version, want)
+		}
+		return nil
+	}
+}
+
+// DiskSpaceCheck fails if the database file is missing or its volume has less than minFree bytes available
+func DiskSpaceCheck(path string, minFree uint64) Check {
+	return func(ctx context.Context) error {
+		if _, err := os.Stat(path); err != nil {
+			return fmt.Errorf("database file: %w", err)
+		}
+
+		free, err := freeBytes(filepath.Dir(path))
+		if err != nil {
+			return err
+		}
+		if free < minFree {
+			return fmt.Errorf("only %d MiB free, need %d MiB", free>>20, minFree>>20)
+		}
+		return nil
+	}
+}
+
+// Heartbeat records when a background worker last proved it was alive
+type Heartbeat struct {
+	last atomic.Int64
+}
+
+// Beat records that the worker is alive now
+func (h *Heartbeat) Beat() {
+	h.last.Store(time.Now().UnixNano())
+}
+
+// Last returns the time of the most recent beat, or the zero time if there has been none
+func (h *Heartbeat) Last() time.Time {
+	nanos := h.last.Load()
+	if nanos == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, nanos)
+}
+
+// HeartbeatCheck fails if the worker hasn't beaten within maxAge
+func HeartbeatCheck(h *Heartbeat, maxAge time.Duration) Check {
+	return func(ctx context.Context) error {
+		last := h.Last()
+		if last.IsZero() {
+			return fmt.Errorf("worker has not started")
+		}
+		if age := time.Since(last); age > maxAge {
+			return fmt.Errorf("last heartbeat %s ago", age.Round(time.Second))
+		}
+		return nil
+	}
+}
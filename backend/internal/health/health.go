@@ -0,0 +1,152 @@
+package health
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultTimeout bounds a check registered without its own timeout
+const DefaultTimeout = 2 * time.Second
+
+// Status values reported for checks and overall
+const (
+	StatusOK   = "ok"
+	StatusFail = "fail"
+)
+
+// Check reports an error if the dependency it probes is unhealthy.
+// It must return promptly once ctx is done.
+type Check func(ctx context.Context) error
+
+// Result is the outcome of a single check
+type Result struct {
+	Status     string  `json:"status"`
+	Error      string  `json:"error,omitempty"`
+	DurationMs float64 `json:"duration_ms"`
+}
+
+// Report is the outcome of running every registered check
+type Report struct {
+	Status string            `json:"status"`
+	Checks map[string]Result `json:"checks"`
+}
+
+// Healthy reports whether every check passed
+func (r Report) Healthy() bool {
+	return r.Status == StatusOK
+}
+
+// registration is a named check with its timeout
+type registration struct {
+	check   Check
+	timeout time.Duration
+}
+
+var (
+	mu     sync.RWMutex
+	checks = make(map[string]registration)
+
+	shuttingDown atomic.Bool
+)
+
+// Register adds or replaces a readiness check. A timeout of zero uses DefaultTimeout.
+func Register(name string, check Check, timeout time.Duration) {
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	checks[name] = registration{check: check, timeout: timeout}
+}
+
+// Unregister removes a readiness check
+func Unregister(name string) {
+	mu.Lock()
+	defer mu.Unlock()
+	delete(checks, name)
+}
+
+// SetShuttingDown marks the process as draining so readiness fails and load balancers stop routing to it
+func SetShuttingDown(draining bool) {
+	shuttingDown.Store(draining)
+}
+
+// IsShuttingDown reports whether SetShuttingDown(true) has been called
+func IsShuttingDown() bool {
+	return shuttingDown.Load()
+}
+
+// Run executes every registered check concurrently, each under its own timeout
+func Run(ctx context.Context) Report {
+	mu.RLock()
+	registered := make(map[string]registration, len(checks))
+	names := make([]string, 0, len(checks))
+	for name, reg := range checks {
+		registered[name] = reg
+		names = append(names, name)
+	}
+	mu.RUnlock()
+	sort.Strings(names)
+
+	results := make([]Result, len(names))
+	var wg sync.WaitGroup
+	for i, name := range names {
+		wg.Add(1)
+		go func(i int, reg registration) {
+			defer wg.Done()
+			results[i] = runCheck(ctx, reg)
+		}(i, registered[name])
+	}
+	wg.Wait()
+
+	report := Report{Status: StatusOK, Checks: make(map[string]Result, len(names))}
+	for i, name := range names {
+		report.Checks[name] = results[i]
+		if results[i].Status != StatusOK {
+			report.Status = StatusFail
+		}
+	}
+
+	if IsShuttingDown() {
+		report.Status = StatusFail
+		report.Checks["shutdown"] = Result{Status: StatusFail, Error: "server is shutting down"}
+	}
+
+	return report
+}
+
+// runCheck runs one check, treating a timeout as a failure even if the check ignores ctx
+func runCheck(ctx context.Context, reg registration) Result {
+	ctx, cancel := context.WithTimeout(ctx, reg.timeout)
+	defer cancel()
+
+	start := time.Now()
+	done := make(chan error, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				done <- fmt.Errorf("check panicked: %v", r)
+			}
+		}()
+		done <- reg.check(ctx)
+	}()
+
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		err = fmt.Errorf("timed out after %s", reg.timeout)
+	}
+
+	result := Result{Status: StatusOK, DurationMs: float64(time.Since(start).Microseconds()) / 1000}
+	if err != nil {
+		result.Status = StatusFail
+		result.Error = err.Error()
+	}
+	return result
+}
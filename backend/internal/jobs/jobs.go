@@ -0,0 +1,265 @@
+// Package jobs runs background work from the database-backed jobs table.
+// Handlers are registered by type at startup; anything can then enqueue a
+// job, in the same transaction as the change that caused it, and a pool of
+// workers runs it with exponential back-off retries. Jobs that fail
+// permanently or run out of attempts are kept in the dead state until an
+// operator retries or cancels them.
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"shopease/internal/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// DefaultMaxAttempts is how many times a job runs before it is dead, unless enqueued with another limit
+const DefaultMaxAttempts = 5
+
+// Errors returned when enqueuing or managing jobs
+var (
+	ErrUnknownType    = errors.New("no handler registered for job type")
+	ErrNotRetryable   = errors.New("only queued, dead or cancelled jobs can be retried")
+	ErrNotCancellable = errors.New("only queued jobs can be cancelled")
+)
+
+// Handler runs one attempt of a job with its raw JSON payload
+type Handler func(ctx context.Context, payload json.RawMessage) error
+
+var (
+	handlersMu sync.RWMutex
+	handlers   = map[string]Handler{}
+)
+
+// Register makes fn the handler for jobs of the given type, decoding each
+// payload into a T first. Call it at startup, before Start; registering a
+// type twice panics.
+func Register[T any](kind string, fn func(ctx context.Context, payload T) error) {
+	RegisterHandler(kind, func(ctx context.Context, raw json.RawMessage) error {
+		var payload T
+		if len(raw) > 0 {
+			if err := json.Unmarshal(raw, &payload); err != nil {
+				return Permanent(fmt.Errorf("decoding %s payload: %w", kind, err))
+			}
+		}
+		return fn(ctx, payload)
+	})
+}
+
+// RegisterHandler makes h the handler for jobs of the given type
+func RegisterHandler(kind string, h Handler) {
+	handlersMu.Lock()
+	defer handlersMu.Unlock()
+
+	if _, exists := handlers[kind]; exists {
+		panic("jobs: handler already registered for " + kind)
+	}
+	handlers[kind] = h
+}
+
+// handler returns the handler registered for kind, if any
+func handler(kind string) (Handler, bool) {
+	handlersMu.RLock()
+	defer handlersMu.RUnlock()
+	h, ok := handlers[kind]
+	return h, ok
+}
+
+// jobIDKey is the context key holding the ID of the job a handler is running
+type jobIDKey struct{}
+
+// results holds the latest result reported by each job running on this
+// process, by job ID, until its outcome is recorded
+var results sync.Map
+
+// SetResult records result, such as progress or an outcome, for the job
+// running under ctx. It is kept in memory while the job runs, since the job
+// may be holding the database's write lock, and saved with the job's outcome.
+// Outside a job it does nothing.
+func SetResult(ctx context.Context, result interface{}) error {
+	id, ok := ctx.Value(jobIDKey{}).(uint)
+	if !ok {
+		return nil
+	}
+	raw, err := json.Marshal(result)
+	if err != nil {
+		return err
+	}
+	results.Store(id, json.RawMessage(raw))
+	return nil
+}
+
+// Get loads a job, with the latest result reported so far if it is running
+// on this process
+func Get(tx *gorm.DB, id uint) (*models.Job, error) {
+	var job models.Job
+	if err := tx.First(&job, id).Error; err != nil {
+		return nil, err
+	}
+	if job.State == models.JobRunning {
+		if raw, ok := results.Load(job.ID); ok {
+			job.Result = raw.(json.RawMessage)
+		}
+	}
+	return &job, nil
+}
+
+// permanentError marks a failure that retrying cannot fix
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// Permanent wraps err so the job goes straight to the dead state instead of being retried
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanentError{err: err}
+}
+
+// IsPermanent reports whether err was marked with Permanent
+func IsPermanent(err error) bool {
+	var p *permanentError
+	return errors.As(err, &p)
+}
+
+// Options control when and how often a job runs
+type Options struct {
+	RunAt       time.Time     // Earliest start; zero means now plus Delay
+	Delay       time.Duration // Used when RunAt is zero
+	MaxAttempts int           // Zero means DefaultMaxAttempts
+	Key         string        // If set, a job already enqueued with this key is returned instead of a new one
+}
+
+// Enqueue stores a job of the given type with payload encoded as JSON. Pass
+// the transaction making the change that needs the job, so the job is only
+// queued if the change commits.
+func Enqueue(tx *gorm.DB, kind string, payload interface{}, opts Options) (*models.Job, error) {
+	if _, ok := handler(kind); !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownType, kind)
+	}
+
+	raw, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("encoding %s payload: %w", kind, err)
+	}
+
+	runAt := opts.RunAt
+	if runAt.IsZero() {
+		runAt = time.Now().Add(opts.Delay)
+	}
+	maxAttempts := opts.MaxAttempts
+	if maxAttempts < 1 {
+		maxAttempts = DefaultMaxAttempts
+	}
+
+	job := models.Job{
+		Type:        kind,
+		Payload:     raw,
+		State:       models.JobQueued,
+		RunAt:       runAt.UTC(),
+		MaxAttempts: maxAttempts,
+	}
+	if opts.Key != "" {
+		job.Key = &opts.Key
+		result := tx.Clauses(clause.OnConflict{Columns: []clause.Column{{Name: "key"}}, DoNothing: true}).Create(&job)
+		if result.Error != nil {
+			return nil, result.Error
+		}
+		if result.RowsAffected == 0 {
+			var existing models.Job
+			if err := tx.Where(&models.Job{Key: &opts.Key}).First(&existing).Error; err != nil {
+				return nil, err
+			}
+			return &existing, nil
+		}
+	} else if err := tx.Create(&job).Error; err != nil {
+		return nil, err
+	}
+
+	wake()
+	return &job, nil
+}
+
+// Retry runs a queued, dead or cancelled job straight away. Dead and
+// cancelled jobs get a fresh set of attempts.
+func Retry(tx *gorm.DB, id uint) (*models.Job, error) {
+	return transition(tx, id, []models.JobState{models.JobQueued, models.JobDead, models.JobCancelled}, ErrNotRetryable, func(job *models.Job) map[string]interface{} {
+		changes := map[string]interface{}{
+			"state":       models.JobQueued,
+			"run_at":      time.Now().UTC(),
+			"finished_at": nil,
+		}
+		if job.State != models.JobQueued {
+			changes["attempts"] = 0
+		}
+		return changes
+	})
+}
+
+// Cancel stops a queued job from running. Running jobs can't be cancelled.
+func Cancel(tx *gorm.DB, id uint) (*models.Job, error) {
+	return transition(tx, id, []models.JobState{models.JobQueued}, ErrNotCancellable, func(*models.Job) map[string]interface{} {
+		now := time.Now()
+		return map[string]interface{}{
+			"state":       models.JobCancelled,
+			"finished_at": &now,
+		}
+	})
+}
+
+// transition applies the changes for a job if it is in one of the from
+// states, failing with notAllowed otherwise. The update is conditional on the
+// state and attempt count read, so it can't overwrite a worker claiming the job.
+func transition(tx *gorm.DB, id uint, from []models.JobState, notAllowed error, changesFor func(*models.Job) map[string]interface{}) (*models.Job, error) {
+	var job models.Job
+	if err := tx.First(&job, id).Error; err != nil {
+		return nil, err
+	}
+	if !inStates(job.State, from) {
+		return &job, notAllowed
+	}
+
+	changes := changesFor(&job)
+	changes["updated_at"] = time.Now()
+	result := tx.Model(&models.Job{}).
+		Where("id = ? AND state = ? AND attempts = ?", job.ID, job.State, job.Attempts).
+		Updates(changes)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		// Claimed by a worker since it was read
+		if err := tx.First(&job, id).Error; err != nil {
+			return nil, err
+		}
+		return &job, notAllowed
+	}
+
+	if err := tx.First(&job, id).Error; err != nil {
+		return nil, err
+	}
+	if job.State == models.JobQueued {
+		wake()
+	}
+	return &job, nil
+}
+
+func inStates(state models.JobState, states []models.JobState) bool {
+	for _, s := range states {
+		if s == state {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,318 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"shopease/internal/database"
+	"shopease/internal/health"
+	"shopease/internal/metrics"
+	"shopease/internal/models"
+
+	"gorm.io/gorm"
+)
+
+const (
+	pollInterval = time.Second
+	runTimeout   = 5 * time.Minute
+	baseBackoff  = 5 * time.Second
+	maxBackoff   = time.Hour
+
+	// staleAfter is when a running job is presumed abandoned by a worker that
+	// stopped, and may be claimed again
+	staleAfter = 2 * runTimeout
+
+	heartbeatInterval = 10 * time.Second
+
+	// Succeeded jobs are kept this long for inspection, then deleted
+	retention     = 7 * 24 * time.Hour
+	pruneInterval = time.Hour
+)
+
+var (
+	mu      sync.Mutex
+	stop    chan struct{}
+	workers sync.WaitGroup
+
+	// wakeup lets Enqueue start a worker without waiting for the next poll
+	wakeup = make(chan struct{}, 1)
+
+	// running holds when each busy worker started its current job, by worker name
+	running sync.Map
+
+	// Heartbeat is beaten periodically while the workers are healthy so health
+	// checks can detect a stalled queue
+	Heartbeat health.Heartbeat
+)
+
+// Start launches n workers that run due jobs
+func Start(n int) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if stop != nil {
+		return
+	}
+	if n < 1 {
+		n = 1
+	}
+
+	host, _ := os.Hostname()
+	stop = make(chan struct{})
+
+	Heartbeat.Beat()
+	workers.Add(1)
+	go beat(stop)
+	for i := 1; i <= n; i++ {
+		workers.Add(1)
+		go work(fmt.Sprintf("%s:%d/%d", host, os.Getpid(), i), stop)
+	}
+
+	log.Printf("Job queue started with %d worker(s)", n)
+}
+
+// Stop waits for jobs in progress and stops the workers. Queued jobs stay
+// in the table for the next start.
+func Stop() {
+	mu.Lock()
+	if stop == nil {
+		mu.Unlock()
+		return
+	}
+	close(stop)
+	stop = nil
+	mu.Unlock()
+
+	workers.Wait()
+}
+
+// wake nudges an idle worker to look for due jobs
+func wake() {
+	select {
+	case wakeup <- struct{}{}:
+	default:
+	}
+}
+
+// work runs due jobs until there are none, then waits for the next poll,
+// a wake-up or stop
+func work(name string, stop chan struct{}) {
+	defer workers.Done()
+
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	for {
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+
+			job, err := claim(name)
+			if err != nil {
+				log.Printf("Error claiming job: %v", err)
+				break
+			}
+			if job == nil {
+				break
+			}
+			running.Store(name, time.Now())
+			run(name, job)
+			running.Delete(name)
+		}
+
+		select {
+		case <-ticker.C:
+		case <-wakeup:
+		case <-stop:
+			return
+		}
+	}
+}
+
+// beat beats the heartbeat on its own ticker, so workers busy with long jobs
+// don't make the queue look stalled. Beats stop while any worker has been on
+// one job for longer than runTimeout, i.e. its handler ignores cancellation.
+// It also prunes old succeeded jobs.
+func beat(stop chan struct{}) {
+	defer workers.Done()
+
+	ticker := time.NewTicker(heartbeatInterval)
+	defer ticker.Stop()
+
+	var pruned time.Time
+	for {
+		select {
+		case <-ticker.C:
+			if !stalled() {
+				Heartbeat.Beat()
+			}
+			if time.Since(pruned) >= pruneInterval {
+				if err := prune(); err != nil {
+					log.Printf("Error pruning succeeded jobs: %v", err)
+				}
+				pruned = time.Now()
+			}
+		case <-stop:
+			return
+		}
+	}
+}
+
+// prune deletes jobs that succeeded longer ago than the retention period
+func prune() error {
+	return database.DB.
+		Where("state = ? AND finished_at < ?", models.JobSucceeded, time.Now().Add(-retention)).
+		Delete(&models.Job{}).Error
+}
+
+// stalled reports whether any worker has overrun runTimeout on its current job
+func stalled() bool {
+	overrun := false
+	running.Range(func(_, started interface{}) bool {
+		overrun = time.Since(started.(time.Time)) > runTimeout
+		return !overrun
+	})
+	return overrun
+}
+
+// claim marks the next due job as running on this worker and returns it,
+// or nil if nothing is due
+func claim(name string) (*models.Job, error) {
+	now := time.Now().UTC()
+
+	for {
+		var job models.Job
+		err := database.DB.
+			Where("(state = ? AND run_at <= ?) OR (state = ? AND locked_at < ?)", models.JobQueued, now, models.JobRunning, now.Add(-staleAfter)).
+			Order("run_at, id").
+			Take(&job).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		changes := map[string]interface{}{
+			"state":      models.JobRunning,
+			"attempts":   job.Attempts + 1,
+			"locked_by":  name,
+			"locked_at":  now,
+			"updated_at": now,
+		}
+		if job.State == models.JobRunning && job.Attempts >= job.MaxAttempts {
+			// Abandoned on its last attempt
+			changes = deadChanges(fmt.Sprintf("worker %s stopped during the last attempt", job.LockedBy))
+		}
+
+		// Conditional on the state and attempt count read, so only one worker wins
+		result := database.DB.Model(&models.Job{}).
+			Where("id = ? AND state = ? AND attempts = ?", job.ID, job.State, job.Attempts).
+			Updates(changes)
+		if result.Error != nil {
+			return nil, result.Error
+		}
+		if result.RowsAffected == 0 || changes["state"] != models.JobRunning {
+			continue
+		}
+
+		job.State = models.JobRunning
+		job.Attempts++
+		job.LockedBy = name
+		return &job, nil
+	}
+}
+
+// run attempts a claimed job and records the outcome: succeeded, queued
+// again after a back-off, or dead
+func run(name string, job *models.Job) {
+	start := time.Now()
+	err := call(job)
+
+	var changes map[string]interface{}
+	var result string
+	switch {
+	case err == nil:
+		finished := time.Now()
+		changes = map[string]interface{}{"state": models.JobSucceeded, "last_error": "", "finished_at": &finished}
+		result = "succeeded"
+	case IsPermanent(err) || job.Attempts >= job.MaxAttempts:
+		changes = deadChanges(err.Error())
+		result = "dead"
+		log.Printf("Error running %s job %d, giving up after %d attempt(s): %v", job.Type, job.ID, job.Attempts, err)
+	default:
+		backoff := Backoff(job.Attempts)
+		changes = map[string]interface{}{"state": models.JobQueued, "last_error": truncate(err.Error()), "run_at": time.Now().Add(backoff).UTC()}
+		result = "retried"
+		log.Printf("Error running %s job %d (attempt %d), retrying in %s: %v", job.Type, job.ID, job.Attempts, backoff, err)
+	}
+	if raw, ok := results.LoadAndDelete(job.ID); ok {
+		changes["result"] = raw
+	}
+	changes["locked_by"] = ""
+	changes["locked_at"] = nil
+	changes["updated_at"] = time.Now()
+
+	metrics.JobsProcessed.WithLabelValues(job.Type, result).Inc()
+	metrics.JobDuration.WithLabelValues(job.Type).Observe(time.Since(start).Seconds())
+
+	if err := database.DB.Model(&models.Job{}).
+		Where("id = ? AND state = ? AND locked_by = ?", job.ID, models.JobRunning, name).
+		Updates(changes).Error; err != nil {
+		log.Printf("Error recording outcome of %s job %d: %v", job.Type, job.ID, err)
+	}
+}
+
+// call runs the job's handler, turning panics into errors
+func call(job *models.Job) (err error) {
+	h, ok := handler(job.Type)
+	if !ok {
+		return Permanent(fmt.Errorf("%w: %s", ErrUnknownType, job.Type))
+	}
+
+	ctx, cancel := context.WithTimeout(context.WithValue(context.Background(), jobIDKey{}, job.ID), runTimeout)
+	defer cancel()
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return h(ctx, job.Payload)
+}
+
+// Backoff is the wait before the next attempt of a job that has failed attempts times
+func Backoff(attempts int) time.Duration {
+	if attempts < 1 {
+		return 0
+	}
+	backoff := baseBackoff
+	for i := 1; i < attempts && backoff < maxBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > maxBackoff {
+		backoff = maxBackoff
+	}
+	return backoff
+}
+
+func deadChanges(lastError string) map[string]interface{} {
+	finished := time.Now()
+	return map[string]interface{}{"state": models.JobDead, "last_error": truncate(lastError), "finished_at": &finished}
+}
+
+// truncate keeps an error message within the last_error column
+func truncate(msg string) string {
+	const limit = 2000
+	if len(msg) > limit {
+		return msg[:limit]
+	}
+	return msg
+}
@@ -4,6 +4,7 @@ import (
 	"net/http"
 	"strings"
 
+	"shopease/internal/config"
 	"shopease/internal/database"
 	"shopease/internal/models"
 	"shopease/internal/utils"
@@ -63,6 +64,60 @@ func AuthMiddleware() gin.HandlerFunc {
 	}
 }
 
+// StaffMiddleware restricts a route to staff and admin users
+// Must be used after AuthMiddleware
+func StaffMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user, exists := GetUserFromContext(c)
+		if !exists {
+			utils.ErrorResponse(c, http.StatusUnauthorized, "User not authenticated")
+			c.Abort()
+			return
+		}
+
+		if !user.IsStaff() {
+			utils.ErrorResponse(c, http.StatusForbidden, "Staff access required")
+			c.Abort()
+			return
+		}
+
+		if config.AppConfig.RequireStaff2FA && !user.HasTwoFactor() {
+			utils.ErrorResponse(c, http.StatusForbidden, "Two-factor authentication must be enabled for staff accounts")
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// AdminMiddleware restricts a route to admin users
+// Must be used after AuthMiddleware
+func AdminMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user, exists := GetUserFromContext(c)
+		if !exists {
+			utils.ErrorResponse(c, http.StatusUnauthorized, "User not authenticated")
+			c.Abort()
+			return
+		}
+
+		if !user.IsAdmin() {
+			utils.ErrorResponse(c, http.StatusForbidden, "Admin access required")
+			c.Abort()
+			return
+		}
+
+		if config.AppConfig.RequireStaff2FA && !user.HasTwoFactor() {
+			utils.ErrorResponse(c, http.StatusForbidden, "Two-factor authentication must be enabled for staff accounts")
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
 // GetUserFromContext retrieves the user from the Gin context
 func GetUserFromContext(c *gin.Context) (*models.User, bool) {
 	user, exists := c.Get("user")
@@ -118,33 +173,6 @@ func OptionalAuthMiddleware() gin.HandlerFunc {
 	}
 }
 
-// RateLimitMiddleware implements basic rate limiting (bonus feature)
-func RateLimitMiddleware() gin.HandlerFunc {
-	// Simple in-memory rate limiting (for production, use Redis)
-	return func(c *gin.Context) {
-		// For now, just pass through
-		// In production, implement proper rate limiting
-		c.Next()
-	}
-}
-
-// LoggerMiddleware logs request details
-func LoggerMiddleware() gin.HandlerFunc {
-	return func(c *gin.Context) {
-		// Log request
-		method := c.Request.Method
-		path := c.Request.URL.Path
-
-		c.Next()
-
-		// Log response status
-		status := c.Writer.Status()
-		_ = method
-		_ = path
-		_ = status
-	}
-}
-
 // SecurityHeaders adds security headers to responses
 func SecurityHeaders() gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -179,7 +207,8 @@ func CORSMiddleware(allowedOrigins string) gin.HandlerFunc {
 		}
 
 		c.Header("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
-		c.Header("Access-Control-Allow-Headers", "Origin, Content-Type, Accept, Authorization")
+		c.Header("Access-Control-Allow-Headers", "Origin, Content-Type, Accept, Authorization, X-Request-ID, X-API-Key, If-Match, If-None-Match")
+		c.Header("Access-Control-Expose-Headers", "X-Request-ID, ETag, Retry-After, RateLimit-Limit, RateLimit-Remaining, RateLimit-Reset, RateLimit-Policy")
 		c.Header("Access-Control-Allow-Credentials", "true")
 		c.Header("Access-Control-Max-Age", "86400")
 
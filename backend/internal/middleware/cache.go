@@ -0,0 +1,97 @@
+package middleware
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"shopease/internal/cache"
+	"shopease/internal/config"
+	"shopease/internal/metrics"
+	"shopease/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// cachedResponse is what the catalogue cache keeps of a response
+type cachedResponse struct {
+	ContentType string `json:"content_type"`
+	ETag        string `json:"etag,omitempty"`
+	Body        []byte `json:"body"`
+}
+
+// CatalogueCacheMiddleware serves GET requests in scope from cache.Catalogue,
+// storing successful responses for CatalogueCacheTTL seconds and letting
+// clients keep them as long. The X-Cache header says whether it was a hit.
+// Item writes call cache.InvalidateItem to drop the entries they affect.
+func CatalogueCacheMiddleware(scope string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ttl := time.Duration(config.AppConfig.CatalogueCacheTTL) * time.Second
+		if ttl <= 0 || c.Request.Method != http.MethodGet {
+			c.Next()
+			return
+		}
+		cacheControl := fmt.Sprintf("public, max-age=%d", config.AppConfig.CatalogueCacheTTL)
+
+		key, ok := catalogueKey(scope, c)
+		if !ok {
+			c.Next()
+			return
+		}
+		if raw, ok := cache.Catalogue.Get(key); ok {
+			var cached cachedResponse
+			if err := json.Unmarshal(raw, &cached); err == nil {
+				metrics.CacheLookups.WithLabelValues("catalogue", "hit").Inc()
+				if cached.ETag != "" {
+					c.Header("ETag", cached.ETag)
+				}
+				c.Header("Cache-Control", cacheControl)
+				c.Header("X-Cache", "HIT")
+				c.Data(http.StatusOK, cached.ContentType, cached.Body)
+				c.Abort()
+				return
+			}
+		}
+		metrics.CacheLookups.WithLabelValues("catalogue", "miss").Inc()
+
+		writer := &bufferedWriter{ResponseWriter: c.Writer, status: http.StatusOK}
+		c.Writer = writer
+		c.Next()
+		c.Writer = writer.ResponseWriter
+
+		if writer.status == http.StatusOK {
+			raw, err := json.Marshal(cachedResponse{
+				ContentType: c.Writer.Header().Get("Content-Type"),
+				ETag:        c.Writer.Header().Get("ETag"),
+				Body:        writer.body.Bytes(),
+			})
+			if err == nil {
+				cache.Catalogue.Set(key, raw, ttl)
+			}
+			c.Header("Cache-Control", cacheControl)
+		}
+		c.Header("X-Cache", "MISS")
+		writer.flush()
+	}
+}
+
+// catalogueKey builds the cache key for a request in scope from the values its
+// handler parses, so requests that get the same response share one entry. It
+// reports false for requests the handler will reject.
+func catalogueKey(scope string, c *gin.Context) (string, bool) {
+	switch scope {
+	case cache.ScopeItem:
+		id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+		if err != nil {
+			return "", false
+		}
+		return cache.ItemKey(uint(id)), true
+	case cache.ScopeItems:
+		return cache.ItemsKey(models.ParseItemListQuery(c.Request.URL.Query())), true
+	case cache.ScopeCategories:
+		return cache.CategoriesKey(), true
+	}
+	return "", false
+}
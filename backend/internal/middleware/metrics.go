@@ -0,0 +1,55 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strconv"
+	"time"
+
+	"shopease/internal/config"
+	"shopease/internal/metrics"
+	"shopease/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// MetricsMiddleware records request counts and latency by route template
+func MetricsMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		metrics.HTTPRequestsInFlight.Inc()
+
+		c.Next()
+
+		metrics.HTTPRequestsInFlight.Dec()
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		status := strconv.Itoa(c.Writer.Status())
+
+		metrics.HTTPRequests.WithLabelValues(c.Request.Method, route, status).Inc()
+		metrics.HTTPRequestDuration.WithLabelValues(c.Request.Method, route, status).Observe(time.Since(start).Seconds())
+	}
+}
+
+// MetricsAuthMiddleware requires the configured bearer token, if any, to scrape metrics
+func MetricsAuthMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token := config.AppConfig.MetricsToken
+		if token == "" {
+			c.Next()
+			return
+		}
+
+		provided := utils.ExtractTokenFromHeader(c.GetHeader("Authorization"))
+		if subtle.ConstantTimeCompare([]byte(provided), []byte(token)) != 1 {
+			utils.ErrorResponse(c, http.StatusUnauthorized, "Invalid metrics token")
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
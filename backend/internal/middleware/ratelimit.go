@@ -0,0 +1,123 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"shopease/internal/config"
+	"shopease/internal/ratelimit"
+	"shopease/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// APIKeyHeader identifies API clients that should be limited per key rather than per IP
+const APIKeyHeader = "X-API-Key"
+
+// Rate-limit policies applied by the router
+var (
+	// DefaultRateLimit applies to every request
+	DefaultRateLimit = ratelimit.Policy{Name: "default", Requests: 300, Period: time.Minute}
+	// LoginRateLimit guards password guessing on POST /users/login
+	LoginRateLimit = ratelimit.Policy{Name: "login", Requests: 10, Period: time.Minute}
+	// SignupRateLimit guards account creation on POST /users
+	SignupRateLimit = ratelimit.Policy{Name: "signup", Requests: 5, Period: time.Hour}
+	// BrowseRateLimit is a looser limit for catalogue browsing on GET /items
+	BrowseRateLimit = ratelimit.Policy{Name: "browse", Requests: 600, Period: time.Minute}
+	// CheckoutRateLimit applies per user to order placement
+	CheckoutRateLimit = ratelimit.Policy{Name: "checkout", Requests: 20, Period: time.Minute}
+)
+
+// rateLimitStore holds limiter state for all policies
+var rateLimitStore ratelimit.Store = ratelimit.NewMemoryStore()
+
+// SetRateLimitStore replaces the store used by all rate limiters, e.g. with a shared one
+func SetRateLimitStore(store ratelimit.Store) {
+	rateLimitStore = store
+}
+
+// RateKeyFunc derives the identity a request is limited by
+type RateKeyFunc func(c *gin.Context) string
+
+// KeyByIP limits each client IP
+func KeyByIP(c *gin.Context) string {
+	return "ip:" + c.ClientIP()
+}
+
+// KeyByUser limits each authenticated user, falling back to IP.
+// Must be used after AuthMiddleware to have any effect.
+func KeyByUser(c *gin.Context) string {
+	if userID, exists := GetUserIDFromContext(c); exists {
+		return fmt.Sprintf("user:%d", userID)
+	}
+	return KeyByIP(c)
+}
+
+// KeyByAPIKey limits each known API key, falling back to IP for requests
+// without one. Unknown keys also fall back to IP, so clients cannot mint a
+// fresh budget by making keys up.
+// Keys are hashed so they never sit in memory or a shared store in plaintext.
+func KeyByAPIKey(c *gin.Context) string {
+	if apiKey := c.GetHeader(APIKeyHeader); apiKey != "" && isKnownAPIKey(apiKey) {
+		return "key:" + utils.HashToken(apiKey)
+	}
+	return KeyByIP(c)
+}
+
+// isKnownAPIKey reports whether key is one of the configured API keys
+func isKnownAPIKey(key string) bool {
+	for _, known := range config.AppConfig.APIKeyList() {
+		if subtle.ConstantTimeCompare([]byte(key), []byte(known)) == 1 {
+			return true
+		}
+	}
+	return false
+}
+
+// RateLimitMiddleware applies the default policy to every request, keyed by known API key or IP
+func RateLimitMiddleware() gin.HandlerFunc {
+	return RateLimit(DefaultRateLimit, KeyByAPIKey)
+}
+
+// RateLimit enforces a policy for requests grouped by key.
+// Responses carry RateLimit-Limit/Remaining/Reset/Policy headers, and Retry-After when refused.
+func RateLimit(policy ratelimit.Policy, key RateKeyFunc) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !config.AppConfig.RateLimitEnabled {
+			c.Next()
+			return
+		}
+
+		result, err := rateLimitStore.Take(policy.Name+":"+key(c), policy)
+		if err != nil {
+			// Fail open: an unavailable store shouldn't take the API down
+			log.Printf("Error checking rate limit %q: %v", policy.Name, err)
+			c.Next()
+			return
+		}
+
+		c.Header("RateLimit-Limit", strconv.Itoa(result.Limit))
+		c.Header("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+		c.Header("RateLimit-Reset", strconv.Itoa(ceilSeconds(result.ResetAfter)))
+		c.Header("RateLimit-Policy", policy.String())
+
+		if !result.Allowed {
+			c.Header("Retry-After", strconv.Itoa(ceilSeconds(result.RetryAfter)))
+			utils.ErrorResponse(c, http.StatusTooManyRequests, "Rate limit exceeded. Please slow down.")
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// ceilSeconds rounds a duration up to whole seconds, as the headers require
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
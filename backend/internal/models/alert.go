@@ -0,0 +1,73 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// AlertType represents what kind of change an item alert watches for
+type AlertType string
+
+const (
+	AlertTypePriceDrop   AlertType = "price_drop"
+	AlertTypeBackInStock AlertType = "back_in_stock"
+)
+
+// ItemAlert represents a user's subscription to changes on an item
+type ItemAlert struct {
+	ID                uint           `gorm:"primaryKey" json:"id"`
+	UserID            uint           `gorm:"not null;uniqueIndex:idx_item_alerts_user_item_type" json:"user_id"`
+	ItemID            uint           `gorm:"not null;uniqueIndex:idx_item_alerts_user_item_type;index" json:"item_id"`
+	Type              AlertType      `gorm:"size:50;not null;uniqueIndex:idx_item_alerts_user_item_type" json:"type"`
+	TargetPrice       *float64       `json:"target_price,omitempty"`        // Price drop only: notify at or below this price; nil means any drop
+	LastNotifiedPrice *float64       `json:"last_notified_price,omitempty"` // Price drop only: suppresses repeats until the price falls further
+	LastNotifiedAt    *time.Time     `json:"last_notified_at,omitempty"`
+	CreatedAt         time.Time      `json:"created_at"`
+	UpdatedAt         time.Time      `json:"updated_at"`
+	DeletedAt         gorm.DeletedAt `gorm:"index" json:"-"`
+
+	// Relationships
+	Item *Item `gorm:"foreignKey:ItemID" json:"item,omitempty"`
+}
+
+// ItemAlertRequest represents the request body for subscribing to an item
+type ItemAlertRequest struct {
+	Type        AlertType `json:"type" binding:"required,oneof=price_drop back_in_stock"`
+	TargetPrice *float64  `json:"target_price" binding:"omitempty,gte=0"`
+}
+
+// ItemAlertResponse represents the item alert response
+type ItemAlertResponse struct {
+	ID             uint          `json:"id"`
+	ItemID         uint          `json:"item_id"`
+	Type           AlertType     `json:"type"`
+	TargetPrice    *float64      `json:"target_price,omitempty"`
+	LastNotifiedAt *time.Time    `json:"last_notified_at,omitempty"`
+	Item           *ItemResponse `json:"item,omitempty"`
+	CreatedAt      time.Time     `json:"created_at"`
+}
+
+// ToResponse converts ItemAlert to ItemAlertResponse
+func (a *ItemAlert) ToResponse() ItemAlertResponse {
+	resp := ItemAlertResponse{
+		ID:             a.ID,
+		ItemID:         a.ItemID,
+		Type:           a.Type,
+		TargetPrice:    a.TargetPrice,
+		LastNotifiedAt: a.LastNotifiedAt,
+		CreatedAt:      a.CreatedAt,
+	}
+
+	if a.Item != nil {
+		item := a.Item.ToResponse()
+		resp.Item = &item
+	}
+
+	return resp
+}
+
+// TableName specifies the table name for GORM
+func (ItemAlert) TableName() string {
+	return "item_alerts"
+}
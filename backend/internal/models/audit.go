@@ -0,0 +1,45 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ErrAuditAppendOnly is returned when something tries to change or delete an audit event
+var ErrAuditAppendOnly = errors.New("audit events are append-only")
+
+// AuditEvent records who did what to which record. Events are append-only and
+// hash-chained: each Hash covers the event and the previous event's Hash, so
+// editing or removing an event breaks the chain from that point on.
+//
+// For the same reason ActorName and IP are retained for the life of the log,
+// including after the actor's account is deleted: they are the security and
+// accountability record of who acted from where, and are not anonymised.
+type AuditEvent struct {
+	ID         uint            `gorm:"primaryKey" json:"id"`
+	CreatedAt  time.Time       `gorm:"index" json:"created_at"`
+	ActorID    *uint           `gorm:"index" json:"actor_id,omitempty"` // Nil for anonymous and system actors
+	ActorName  string          `gorm:"size:100;index" json:"actor_name,omitempty"`
+	Action     string          `gorm:"size:100;not null;index" json:"action"`
+	TargetType string          `gorm:"size:50;index:idx_audit_events_target" json:"target_type,omitempty"`
+	TargetID   uint            `gorm:"index:idx_audit_events_target" json:"target_id,omitempty"`
+	Changes    json.RawMessage `gorm:"type:text" json:"changes,omitempty"` // Field name to {"from", "to"}
+	Details    json.RawMessage `gorm:"type:text" json:"details,omitempty"`
+	RequestID  string          `gorm:"size:128;index" json:"request_id,omitempty"`
+	IP         string          `gorm:"size:64" json:"ip,omitempty"`
+	PrevHash   string          `gorm:"size:64" json:"prev_hash"`
+	Hash       string          `gorm:"size:64;index" json:"hash"`
+}
+
+// TableName specifies the table name for GORM
+func (AuditEvent) TableName() string {
+	return "audit_events"
+}
+
+// BeforeDelete refuses to delete audit events
+func (AuditEvent) BeforeDelete(tx *gorm.DB) error {
+	return ErrAuditAppendOnly
+}
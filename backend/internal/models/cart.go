@@ -1,6 +1,9 @@
 package models
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
 	"time"
 
 	"gorm.io/gorm"
@@ -11,6 +14,7 @@ import (
 type Cart struct {
 	ID        uint           `gorm:"primaryKey" json:"id"`
 	UserID    uint           `gorm:"uniqueIndex;not null" json:"user_id"` // One cart per user
+	Version   uint           `gorm:"not null;default:1" json:"-"`         // Bumped whenever a line changes; sent as the ETag
 	CreatedAt time.Time      `json:"created_at"`
 	UpdatedAt time.Time      `json:"updated_at"`
 	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
@@ -30,6 +34,8 @@ type CartItem struct {
 	UpdatedAt time.Time      `json:"updated_at"`
 	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
 
+	PriceAtAdd *float64 `json:"price_at_add,omitempty"` // Item price when the line was last added to; nil on lines from before it was tracked
+
 	// Relationships
 	Cart *Cart `gorm:"foreignKey:CartID" json:"-"`
 	Item *Item `gorm:"foreignKey:ItemID" json:"item,omitempty"`
@@ -41,67 +47,172 @@ type AddToCartRequest struct {
 	Quantity int  `json:"quantity" binding:"omitempty,gte=1"`
 }
 
-// CartResponse represents the cart response
+// CartResponse represents the cart response. Totals are what checkout would
+// charge now: unavailable lines are left out and quantities capped, as
+// described by Warnings. Checkout asks the client to confirm Version
+// whenever there are warnings.
 type CartResponse struct {
 	ID        uint               `json:"id"`
 	UserID    uint               `json:"user_id"`
 	Items     []CartItemResponse `json:"items"`
 	Total     float64            `json:"total"`
 	ItemCount int                `json:"item_count"`
+	Version   string             `json:"version,omitempty"`
+	Warnings  []CartWarning      `json:"warnings"`
 	CreatedAt time.Time          `json:"created_at"`
 }
 
 // CartItemResponse represents a cart item in the response
 type CartItemResponse struct {
-	ID       uint         `json:"id"`
-	CartID   uint         `json:"cart_id"`
-	ItemID   uint         `json:"item_id"`
-	Quantity int          `json:"quantity"`
-	Item     ItemResponse `json:"item"`
-	Subtotal float64      `json:"subtotal"`
+	ID         uint         `json:"id"`
+	CartID     uint         `json:"cart_id"`
+	ItemID     uint         `json:"item_id"`
+	Quantity   int          `json:"quantity"`
+	PriceAtAdd *float64     `json:"price_at_add,omitempty"`
+	Item       ItemResponse `json:"item"`
+	Subtotal   float64      `json:"subtotal"`
+}
+
+// CartWarningCode says how a cart line has changed since it was added
+type CartWarningCode string
+
+const (
+	CartWarningPriceChanged    CartWarningCode = "price_changed"
+	CartWarningUnavailable     CartWarningCode = "unavailable"
+	CartWarningQuantityReduced CartWarningCode = "quantity_reduced"
+)
+
+// CartWarning describes one change to a cart line. Only the fields relevant
+// to Code are set.
+type CartWarning struct {
+	CartItemID  uint            `json:"cart_item_id"`
+	ItemID      uint            `json:"item_id"`
+	Code        CartWarningCode `json:"code"`
+	Message     string          `json:"message"`
+	OldPrice    *float64        `json:"old_price,omitempty"`
+	NewPrice    *float64        `json:"new_price,omitempty"`
+	OldQuantity int             `json:"old_quantity,omitempty"`
+	NewQuantity int             `json:"new_quantity,omitempty"`
 }
 
-// ToResponse converts Cart to CartResponse
-func (c *Cart) ToResponse() CartResponse {
-	items := make([]CartItemResponse, len(c.CartItems))
+// CartLine is a cart line as checkout would see it now
+type CartLine struct {
+	CartItem  *CartItem
+	Quantity  int  // Capped at the per-line maximum
+	Available bool // False if the item has been deactivated or deleted
+}
+
+// Subtotal returns what the line costs at the item's current price
+func (l CartLine) Subtotal() float64 {
+	if !l.Available {
+		return 0
+	}
+	return float64(l.Quantity) * l.CartItem.Item.Price
+}
+
+// CartReview is the result of checking a cart against current prices and availability
+type CartReview struct {
+	Lines    []CartLine
+	Warnings []CartWarning
+	Version  string // Digest of the lines as reviewed; changes whenever what checkout would charge changes
+}
+
+// Review checks the cart's lines, which must have their Item preloaded,
+// against current prices and availability. maxQuantity caps each line; zero
+// means no cap.
+func (c *Cart) Review(maxQuantity int) CartReview {
+	review := CartReview{
+		Lines:    make([]CartLine, len(c.CartItems)),
+		Warnings: []CartWarning{},
+	}
+	digest := sha256.New()
+
+	for i := range c.CartItems {
+		cartItem := &c.CartItems[i]
+		line := CartLine{CartItem: cartItem, Quantity: cartItem.Quantity, Available: cartItem.Item != nil && cartItem.Item.IsActive}
+
+		if !line.Available {
+			review.Warnings = append(review.Warnings, CartWarning{
+				CartItemID: cartItem.ID,
+				ItemID:     cartItem.ItemID,
+				Code:       CartWarningUnavailable,
+				Message:    "This item is no longer available and will be left out of your order",
+			})
+			fmt.Fprintf(digest, "%d:%d:unavailable;", cartItem.ID, cartItem.ItemID)
+		} else {
+			price := cartItem.Item.Price
+			if cartItem.PriceAtAdd != nil && *cartItem.PriceAtAdd != price {
+				review.Warnings = append(review.Warnings, CartWarning{
+					CartItemID: cartItem.ID,
+					ItemID:     cartItem.ItemID,
+					Code:       CartWarningPriceChanged,
+					Message:    fmt.Sprintf("The price of %s has changed from %.2f to %.2f", cartItem.Item.Name, *cartItem.PriceAtAdd, price),
+					OldPrice:   cartItem.PriceAtAdd,
+					NewPrice:   &price,
+				})
+			}
+			if maxQuantity > 0 && line.Quantity > maxQuantity {
+				line.Quantity = maxQuantity
+				review.Warnings = append(review.Warnings, CartWarning{
+					CartItemID:  cartItem.ID,
+					ItemID:      cartItem.ItemID,
+					Code:        CartWarningQuantityReduced,
+					Message:     fmt.Sprintf("You can buy at most %d of %s", maxQuantity, cartItem.Item.Name),
+					OldQuantity: cartItem.Quantity,
+					NewQuantity: line.Quantity,
+				})
+			}
+			fmt.Fprintf(digest, "%d:%d:%d:%v;", cartItem.ID, cartItem.ItemID, line.Quantity, price)
+		}
+
+		review.Lines[i] = line
+	}
+
+	review.Version = hex.EncodeToString(digest.Sum(nil))[:16]
+	return review
+}
+
+// ToResponse converts Cart to CartResponse, reviewing it with maxQuantity
+func (c *Cart) ToResponse(maxQuantity int) CartResponse {
+	review := c.Review(maxQuantity)
+	items := make([]CartItemResponse, len(review.Lines))
 	var total float64 = 0
 	var itemCount int = 0
 
-	for i, cartItem := range c.CartItems {
-		itemResp := CartItemResponse{
-			ID:       cartItem.ID,
-			CartID:   cartItem.CartID,
-			ItemID:   cartItem.ItemID,
-			Quantity: cartItem.Quantity,
-		}
+	for i, line := range review.Lines {
+		itemResp := line.CartItem.ToItemResponse()
+		itemResp.Subtotal = line.Subtotal()
+		total += itemResp.Subtotal
 
-		if cartItem.Item != nil {
-			itemResp.Item = cartItem.Item.ToResponse()
-			itemResp.Subtotal = float64(cartItem.Quantity) * cartItem.Item.Price
-			total += itemResp.Subtotal
+		if line.Available {
+			itemCount += line.Quantity
 		}
-
-		itemCount += cartItem.Quantity
 		items[i] = itemResp
 	}
 
-	return CartResponse{
+	response := CartResponse{
 		ID:        c.ID,
 		UserID:    c.UserID,
 		Items:     items,
 		Total:     total,
 		ItemCount: itemCount,
+		Warnings:  review.Warnings,
 		CreatedAt: c.CreatedAt,
 	}
+	if len(items) > 0 {
+		response.Version = review.Version
+	}
+	return response
 }
 
 // ToItemResponse converts CartItem to CartItemResponse
 func (ci *CartItem) ToItemResponse() CartItemResponse {
 	resp := CartItemResponse{
-		ID:       ci.ID,
-		CartID:   ci.CartID,
-		ItemID:   ci.ItemID,
-		Quantity: ci.Quantity,
+		ID:         ci.ID,
+		CartID:     ci.CartID,
+		ItemID:     ci.ItemID,
+		Quantity:   ci.Quantity,
+		PriceAtAdd: ci.PriceAtAdd,
 	}
 
 	if ci.Item != nil {
@@ -1,6 +1,9 @@
 package models
 
 import (
+	"fmt"
+	"net/url"
+	"strconv"
 	"time"
 
 	"gorm.io/gorm"
@@ -9,19 +12,33 @@ import (
 // Item represents a product in the store
 type Item struct {
 	ID          uint           `gorm:"primaryKey" json:"id"`
+	SKU         *string        `gorm:"size:64;uniqueIndex" json:"sku,omitempty"` // Merchant's external ID, used to match bulk imports
 	Name        string         `gorm:"not null;size:255" json:"name"`
 	Description string         `gorm:"size:1000" json:"description"`
-	Price       float64        `gorm:"not null;default:0" json:"price"`
+	Price       float64        `gorm:"not null;default:0" json:"price"` // Price in effect, maintained from the item_prices history
 	ImageURL    string         `gorm:"size:500" json:"image_url,omitempty"`
 	Category    string         `gorm:"size:100;index" json:"category,omitempty"`
 	IsActive    bool           `gorm:"default:true" json:"is_active"`
+	Version     uint           `gorm:"not null;default:1" json:"version"` // Bumped on every change; sent as the ETag
 	CreatedAt   time.Time      `json:"created_at"`
 	UpdatedAt   time.Time      `json:"updated_at"`
 	DeletedAt   gorm.DeletedAt `gorm:"index" json:"-"`
+
+	CompareAtPrice *float64 `json:"compare_at_price,omitempty"` // Regular price during a sale
+
+	// Rating aggregates, maintained incrementally as published reviews change
+	ReviewCount  int `gorm:"not null;default:0" json:"review_count"`
+	RatingSum    int `gorm:"not null;default:0" json:"-"`
+	RatingCount1 int `gorm:"not null;default:0" json:"-"`
+	RatingCount2 int `gorm:"not null;default:0" json:"-"`
+	RatingCount3 int `gorm:"not null;default:0" json:"-"`
+	RatingCount4 int `gorm:"not null;default:0" json:"-"`
+	RatingCount5 int `gorm:"not null;default:0" json:"-"`
 }
 
 // ItemCreateRequest represents the request body for creating an item
 type ItemCreateRequest struct {
+	SKU         string  `json:"sku" binding:"max=64"`
 	Name        string  `json:"name" binding:"required,min=1,max=255"`
 	Description string  `json:"description" binding:"max=1000"`
 	Price       float64 `json:"price" binding:"required,gte=0"`
@@ -31,6 +48,7 @@ type ItemCreateRequest struct {
 
 // ItemUpdateRequest represents the request body for updating an item
 type ItemUpdateRequest struct {
+	SKU         *string  `json:"sku" binding:"omitempty,max=64"`
 	Name        *string  `json:"name" binding:"omitempty,min=1,max=255"`
 	Description *string  `json:"description" binding:"omitempty,max=1000"`
 	Price       *float64 `json:"price" binding:"omitempty,gte=0"`
@@ -39,32 +57,130 @@ type ItemUpdateRequest struct {
 	IsActive    *bool    `json:"is_active"`
 }
 
+// ItemListQuery is the parsed query of GET /items
+type ItemListQuery struct {
+	Page     int
+	PageSize int
+	Category string
+}
+
+// ParseItemListQuery reads page, page_size and category from query the way
+// the item list applies them: the first value of each, with out-of-range
+// pages falling back to the defaults
+func ParseItemListQuery(query url.Values) ItemListQuery {
+	page, _ := strconv.Atoi(query.Get("page"))
+	pageSize, _ := strconv.Atoi(query.Get("page_size"))
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+	return ItemListQuery{Page: page, PageSize: pageSize, Category: query.Get("category")}
+}
+
 // ItemResponse represents the item response
 type ItemResponse struct {
 	ID          uint      `json:"id"`
+	SKU         string    `json:"sku,omitempty"`
 	Name        string    `json:"name"`
 	Description string    `json:"description"`
 	Price       float64   `json:"price"`
 	ImageURL    string    `json:"image_url,omitempty"`
 	Category    string    `json:"category,omitempty"`
 	IsActive    bool      `json:"is_active"`
+	Version     uint      `json:"version"`
 	CreatedAt   time.Time `json:"created_at"`
+
+	CompareAtPrice *float64 `json:"compare_at_price,omitempty"` // Set while the item is on sale
+
+	AverageRating   float64     `json:"average_rating"`
+	ReviewCount     int         `json:"review_count"`
+	RatingHistogram map[int]int `json:"rating_histogram"`
 }
 
 // ToResponse converts Item to ItemResponse
 func (i *Item) ToResponse() ItemResponse {
 	return ItemResponse{
 		ID:          i.ID,
+		SKU:         i.SKUValue(),
 		Name:        i.Name,
 		Description: i.Description,
 		Price:       i.Price,
 		ImageURL:    i.ImageURL,
 		Category:    i.Category,
 		IsActive:    i.IsActive,
+		Version:     i.Version,
 		CreatedAt:   i.CreatedAt,
+
+		CompareAtPrice: i.CompareAtPrice,
+
+		AverageRating:   i.AverageRating(),
+		ReviewCount:     i.ReviewCount,
+		RatingHistogram: i.RatingHistogram(),
 	}
 }
 
+// SKUValue returns the item's SKU, or "" if it has none
+func (i *Item) SKUValue() string {
+	if i.SKU == nil {
+		return ""
+	}
+	return *i.SKU
+}
+
+// SetSKU sets the item's SKU; an empty string clears it
+func (i *Item) SetSKU(sku string) {
+	if sku == "" {
+		i.SKU = nil
+		return
+	}
+	i.SKU = &sku
+}
+
+// AverageRating returns the mean rating of published reviews, or 0 if there are none
+func (i *Item) AverageRating() float64 {
+	if i.ReviewCount == 0 {
+		return 0
+	}
+	return float64(i.RatingSum) / float64(i.ReviewCount)
+}
+
+// RatingHistogram returns the number of published reviews for each star rating
+func (i *Item) RatingHistogram() map[int]int {
+	return map[int]int{
+		1: i.RatingCount1,
+		2: i.RatingCount2,
+		3: i.RatingCount3,
+		4: i.RatingCount4,
+		5: i.RatingCount5,
+	}
+}
+
+// ItemRatingColumns lists the aggregate columns owned by review handling.
+// Item updates should omit them so they don't overwrite concurrent rating changes.
+var ItemRatingColumns = []string{
+	"review_count", "rating_sum",
+	"rating_count1", "rating_count2", "rating_count3", "rating_count4", "rating_count5",
+}
+
+// RatingCountColumn returns the histogram column holding the count for a star rating
+func RatingCountColumn(rating int) string {
+	return fmt.Sprintf("rating_count%d", rating)
+}
+
+// ApplyRatingDelta adds (delta = 1) or removes (delta = -1) a rating from an item's aggregates
+func ApplyRatingDelta(tx *gorm.DB, itemID uint, rating int, delta int) error {
+	histogramColumn := RatingCountColumn(rating)
+
+	return tx.Model(&Item{}).Where("id = ?", itemID).UpdateColumns(map[string]interface{}{
+		"review_count":  gorm.Expr("review_count + ?", delta),
+		"rating_sum":    gorm.Expr("rating_sum + ?", delta*rating),
+		histogramColumn: gorm.Expr(histogramColumn+" + ?", delta),
+		"version":       NextVersion,
+	}).Error
+}
+
 // TableName specifies the table name for GORM
 func (Item) TableName() string {
 	return "items"
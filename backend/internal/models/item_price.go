@@ -0,0 +1,69 @@
+package models
+
+import (
+	"time"
+)
+
+// PriceKind distinguishes an item's regular price from a temporary sale price
+type PriceKind string
+
+const (
+	PriceKindRegular PriceKind = "regular"
+	PriceKindSale    PriceKind = "sale"
+)
+
+// ItemPrice is one entry in an item's price history. The regular price in
+// effect is the latest regular entry that has started; a sale that has
+// started and not ended takes precedence over it. Item.Price caches the
+// result, so entries scheduled for the future take effect when the pricing
+// worker next runs after EffectiveFrom.
+type ItemPrice struct {
+	ID             uint       `gorm:"primaryKey" json:"id"`
+	ItemID         uint       `gorm:"not null;index:idx_item_prices_item_from" json:"item_id"`
+	Kind           PriceKind  `gorm:"size:20;not null;default:'regular'" json:"kind"`
+	Price          float64    `gorm:"not null" json:"price"`
+	CompareAtPrice *float64   `json:"compare_at_price,omitempty"` // Shown struck through during a sale; defaults to the regular price
+	EffectiveFrom  time.Time  `gorm:"not null;index:idx_item_prices_item_from;index" json:"effective_from"`
+	EffectiveUntil *time.Time `gorm:"index" json:"effective_until,omitempty"` // Nil while open-ended; set on regular prices when superseded
+	CreatedAt      time.Time  `json:"created_at"`
+
+	// Relationships
+	Item *Item `gorm:"foreignKey:ItemID" json:"-"`
+}
+
+// ItemPriceRequest schedules a regular or sale price for an item
+type ItemPriceRequest struct {
+	Kind           PriceKind  `json:"kind" binding:"omitempty,oneof=regular sale"` // Defaults to regular
+	Price          float64    `json:"price" binding:"required,gte=0"`
+	CompareAtPrice *float64   `json:"compare_at_price" binding:"omitempty,gt=0"` // Sales only
+	EffectiveFrom  *time.Time `json:"effective_from"`                            // Defaults to now
+	EffectiveUntil *time.Time `json:"effective_until"`                           // Sales only
+}
+
+// ItemPriceResponse is a price history entry with its status at the time of the request
+type ItemPriceResponse struct {
+	ItemPrice
+	Status string `json:"status"` // scheduled, current or ended
+}
+
+// IsActiveAt reports whether the entry's range covers t
+func (p *ItemPrice) IsActiveAt(t time.Time) bool {
+	return !p.EffectiveFrom.After(t) && (p.EffectiveUntil == nil || p.EffectiveUntil.After(t))
+}
+
+// ToResponse converts ItemPrice to ItemPriceResponse as seen at now
+func (p *ItemPrice) ToResponse(now time.Time) ItemPriceResponse {
+	status := "current"
+	switch {
+	case p.EffectiveFrom.After(now):
+		status = "scheduled"
+	case !p.IsActiveAt(now):
+		status = "ended"
+	}
+	return ItemPriceResponse{ItemPrice: *p, Status: status}
+}
+
+// TableName specifies the table name for GORM
+func (ItemPrice) TableName() string {
+	return "item_prices"
+}
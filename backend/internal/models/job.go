@@ -0,0 +1,51 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// JobState is the lifecycle stage of a queued background job
+type JobState string
+
+const (
+	JobQueued    JobState = "queued"  // Waiting for its run time, including between retries
+	JobRunning   JobState = "running" // Claimed by a worker
+	JobSucceeded JobState = "succeeded"
+	JobDead      JobState = "dead" // Failed permanently or ran out of attempts
+	JobCancelled JobState = "cancelled"
+)
+
+// IsValid reports whether s is a known job state
+func (s JobState) IsValid() bool {
+	switch s {
+	case JobQueued, JobRunning, JobSucceeded, JobDead, JobCancelled:
+		return true
+	}
+	return false
+}
+
+// Job is a unit of background work stored in the jobs table. Workers claim
+// due jobs by switching them to running, so each attempt runs on one worker.
+type Job struct {
+	ID          uint            `gorm:"primaryKey" json:"id"`
+	Type        string          `gorm:"size:100;not null;index" json:"type"`
+	Key         *string         `gorm:"size:200;uniqueIndex" json:"key,omitempty"` // Set to enqueue the job at most once
+	Payload     json.RawMessage `gorm:"type:text" json:"-"`                        // Not exposed by the API, as it may reference personal data
+	State       JobState        `gorm:"size:20;not null;index:idx_jobs_due" json:"state"`
+	RunAt       time.Time       `gorm:"not null;index:idx_jobs_due" json:"run_at"` // Earliest time the next attempt may start
+	Attempts    int             `gorm:"not null;default:0" json:"attempts"`
+	MaxAttempts int             `gorm:"not null" json:"max_attempts"`
+	LastError   string          `gorm:"size:2000" json:"last_error,omitempty"`
+	Result      json.RawMessage `gorm:"type:text" json:"result,omitempty"` // Progress or outcome reported by the handler
+	LockedBy    string          `gorm:"size:100" json:"locked_by,omitempty"`
+	LockedAt    *time.Time      `json:"locked_at,omitempty"`
+	FinishedAt  *time.Time      `json:"finished_at,omitempty"`
+	CreatedAt   time.Time       `json:"created_at"`
+	UpdatedAt   time.Time       `json:"updated_at"`
+}
+
+// TableName specifies the table name for GORM
+func (Job) TableName() string {
+	return "jobs"
+}
@@ -0,0 +1,38 @@
+package models
+
+import (
+	"time"
+)
+
+// LoginOutcome represents the result of a login attempt
+type LoginOutcome string
+
+const (
+	LoginOutcomeSuccess LoginOutcome = "success"
+	LoginOutcomeFailure LoginOutcome = "failure"
+	LoginOutcomeUnlock  LoginOutcome = "unlock" // An admin cleared the username's failures
+)
+
+// Reasons recorded for failed logins
+const (
+	LoginFailureInvalidCredentials = "invalid_credentials"
+	LoginFailureInvalidTwoFactor   = "invalid_two_factor"
+)
+
+// LoginAttempt is an audit record of a login attempt.
+// Consecutive failures since the last success or unlock drive back-off and lockout.
+type LoginAttempt struct {
+	ID        uint         `gorm:"primaryKey" json:"id"`
+	Username  string       `gorm:"size:100;not null;index:idx_login_attempts_username_time" json:"username"`
+	UserID    *uint        `gorm:"index" json:"user_id,omitempty"` // Nil for unknown usernames
+	IP        string       `gorm:"size:64;not null;index:idx_login_attempts_ip_time" json:"ip"`
+	UserAgent string       `gorm:"size:255" json:"user_agent,omitempty"`
+	Outcome   LoginOutcome `gorm:"size:20;not null" json:"outcome"`
+	Reason    string       `gorm:"size:50" json:"reason,omitempty"`
+	CreatedAt time.Time    `gorm:"index:idx_login_attempts_username_time;index:idx_login_attempts_ip_time" json:"created_at"`
+}
+
+// TableName specifies the table name for GORM
+func (LoginAttempt) TableName() string {
+	return "login_attempts"
+}
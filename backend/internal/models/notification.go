@@ -0,0 +1,37 @@
+package models
+
+import (
+	"time"
+)
+
+// NotificationType represents the kind of in-app notification
+type NotificationType string
+
+const (
+	NotificationTypePriceDrop   NotificationType = "price_drop"
+	NotificationTypeBackInStock NotificationType = "back_in_stock"
+)
+
+// Notification represents an in-app message for a user
+type Notification struct {
+	ID        uint             `gorm:"primaryKey" json:"id"`
+	UserID    uint             `gorm:"not null;index" json:"user_id"`
+	Type      NotificationType `gorm:"size:50;not null" json:"type"`
+	Title     string           `gorm:"size:255;not null" json:"title"`
+	Message   string           `gorm:"size:1000" json:"message"`
+	ItemID    *uint            `json:"item_id,omitempty"`
+	AlertID   *uint            `gorm:"uniqueIndex:idx_notifications_alert_event" json:"-"`
+	EventKey  *string          `gorm:"size:100;uniqueIndex:idx_notifications_alert_event" json:"-"` // Deduplicates deliveries of the same change per alert
+	ReadAt    *time.Time       `json:"read_at,omitempty"`
+	CreatedAt time.Time        `json:"created_at"`
+}
+
+// IsRead reports whether the user has seen the notification
+func (n *Notification) IsRead() bool {
+	return n.ReadAt != nil
+}
+
+// TableName specifies the table name for GORM
+func (Notification) TableName() string {
+	return "notifications"
+}
@@ -17,6 +17,15 @@ const (
 	OrderStatusCancelled OrderStatus = "cancelled"
 )
 
+// ParseOrderStatus returns the OrderStatus named by s, if it is one
+func ParseOrderStatus(s string) (OrderStatus, bool) {
+	switch status := OrderStatus(s); status {
+	case OrderStatusPending, OrderStatusConfirmed, OrderStatusShipped, OrderStatusDelivered, OrderStatusCancelled:
+		return status, true
+	}
+	return "", false
+}
+
 // Order represents a placed order (converted from cart)
 type Order struct {
 	ID          uint           `gorm:"primaryKey" json:"id"`
@@ -24,6 +33,8 @@ type Order struct {
 	TotalAmount float64        `gorm:"not null;default:0" json:"total_amount"`
 	Status      OrderStatus    `gorm:"size:50;default:'pending'" json:"status"`
 	Note        string         `gorm:"size:500" json:"note,omitempty"`
+	Version     uint           `gorm:"not null;default:1" json:"version"` // Bumped on every change; sent as the ETag
+	Reference   *string        `gorm:"size:64;uniqueIndex" json:"-"`      // Set on seeded orders so fixtures can be re-applied
 	CreatedAt   time.Time      `json:"created_at"`
 	UpdatedAt   time.Time      `json:"updated_at"`
 	DeletedAt   gorm.DeletedAt `gorm:"index" json:"-"`
@@ -52,8 +63,9 @@ type OrderItem struct {
 
 // CreateOrderRequest represents the request to create an order from cart
 type CreateOrderRequest struct {
-	CartID uint   `json:"cart_id" binding:"required"`
-	Note   string `json:"note" binding:"max=500"`
+	CartID      uint   `json:"cart_id" binding:"required"`
+	Note        string `json:"note" binding:"max=500"`
+	CartVersion string `json:"cart_version" binding:"max=64"` // Version of the reviewed cart; required when it has warnings
 }
 
 // OrderResponse represents the order response
@@ -63,6 +75,7 @@ type OrderResponse struct {
 	TotalAmount float64             `json:"total_amount"`
 	Status      OrderStatus         `json:"status"`
 	Note        string              `json:"note,omitempty"`
+	Version     uint                `json:"version"`
 	Items       []OrderItemResponse `json:"items"`
 	CreatedAt   time.Time           `json:"created_at"`
 }
@@ -107,6 +120,7 @@ func (o *Order) ToResponse() OrderResponse {
 		TotalAmount: o.TotalAmount,
 		Status:      o.Status,
 		Note:        o.Note,
+		Version:     o.Version,
 		Items:       items,
 		CreatedAt:   o.CreatedAt,
 	}
@@ -0,0 +1,28 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// OutboxEvent is a domain event written in the same transaction as the
+// change it describes. The dispatcher delivers pending events to subscribers
+// in ID order, one aggregate at a time, until they are dispatched or dead.
+type OutboxEvent struct {
+	ID            uint            `gorm:"primaryKey" json:"id"`
+	Type          string          `gorm:"size:100;not null;index" json:"type"`
+	AggregateType string          `gorm:"size:50;not null;index:idx_outbox_events_aggregate" json:"aggregate_type"`
+	AggregateID   uint            `gorm:"not null;index:idx_outbox_events_aggregate" json:"aggregate_id"`
+	Payload       json.RawMessage `gorm:"type:text" json:"payload"`
+	CreatedAt     time.Time       `json:"created_at"`
+	DispatchedAt  *time.Time      `gorm:"index" json:"dispatched_at,omitempty"` // Nil until every subscriber has accepted it
+	Attempts      int             `gorm:"not null;default:0" json:"attempts"`
+	LastError     string          `gorm:"size:2000" json:"last_error,omitempty"`
+	NextAttemptAt *time.Time      `json:"next_attempt_at,omitempty"` // Set after a failed delivery
+	DeadAt        *time.Time      `json:"dead_at,omitempty"`         // Set when delivery is given up after too many attempts
+}
+
+// TableName specifies the table name for GORM
+func (OutboxEvent) TableName() string {
+	return "outbox_events"
+}
@@ -0,0 +1,106 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ReviewStatus represents the moderation state of a review
+type ReviewStatus string
+
+const (
+	ReviewStatusPublished ReviewStatus = "published"
+	ReviewStatusPending   ReviewStatus = "pending"
+	ReviewStatusRejected  ReviewStatus = "rejected"
+)
+
+// Review represents a verified buyer's rating of an item
+// Each user can review an item only ONCE
+type Review struct {
+	ID           uint           `gorm:"primaryKey" json:"id"`
+	ItemID       uint           `gorm:"not null;uniqueIndex:idx_reviews_item_user" json:"item_id"`
+	UserID       uint           `gorm:"not null;uniqueIndex:idx_reviews_item_user;index" json:"user_id"`
+	Rating       int            `gorm:"not null" json:"rating"`
+	Title        string         `gorm:"size:255" json:"title"`
+	Body         string         `gorm:"size:5000" json:"body"`
+	Status       ReviewStatus   `gorm:"size:50;default:'published';index" json:"status"`
+	HelpfulCount int            `gorm:"not null;default:0" json:"helpful_count"`
+	CreatedAt    time.Time      `json:"created_at"`
+	UpdatedAt    time.Time      `json:"updated_at"`
+	DeletedAt    gorm.DeletedAt `gorm:"index" json:"-"`
+
+	// Relationships
+	User *User `gorm:"foreignKey:UserID" json:"user,omitempty"`
+	Item *Item `gorm:"foreignKey:ItemID" json:"-"`
+}
+
+// ReviewVote records a user marking a review as helpful
+type ReviewVote struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	ReviewID  uint      `gorm:"not null;uniqueIndex:idx_review_votes_review_user" json:"review_id"`
+	UserID    uint      `gorm:"not null;uniqueIndex:idx_review_votes_review_user" json:"user_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// ReviewCreateRequest represents the request body for reviewing an item
+type ReviewCreateRequest struct {
+	Rating int    `json:"rating" binding:"required,min=1,max=5"`
+	Title  string `json:"title" binding:"max=255"`
+	Body   string `json:"body" binding:"max=5000"`
+}
+
+// ReviewModerateRequest represents the request body for changing a review's status
+type ReviewModerateRequest struct {
+	Status ReviewStatus `json:"status" binding:"required,oneof=published pending rejected"`
+}
+
+// ReviewResponse represents the review response
+type ReviewResponse struct {
+	ID           uint         `json:"id"`
+	ItemID       uint         `json:"item_id"`
+	UserID       uint         `json:"user_id"`
+	Username     string       `json:"username,omitempty"`
+	Rating       int          `json:"rating"`
+	Title        string       `json:"title"`
+	Body         string       `json:"body"`
+	Status       ReviewStatus `json:"status"`
+	HelpfulCount int          `json:"helpful_count"`
+	CreatedAt    time.Time    `json:"created_at"`
+}
+
+// IsPublished reports whether the review counts towards the item's rating
+func (r *Review) IsPublished() bool {
+	return r.Status == ReviewStatusPublished
+}
+
+// ToResponse converts Review to ReviewResponse
+func (r *Review) ToResponse() ReviewResponse {
+	resp := ReviewResponse{
+		ID:           r.ID,
+		ItemID:       r.ItemID,
+		UserID:       r.UserID,
+		Rating:       r.Rating,
+		Title:        r.Title,
+		Body:         r.Body,
+		Status:       r.Status,
+		HelpfulCount: r.HelpfulCount,
+		CreatedAt:    r.CreatedAt,
+	}
+
+	if r.User != nil {
+		resp.Username = r.User.Username
+	}
+
+	return resp
+}
+
+// TableName specifies the table name for GORM
+func (Review) TableName() string {
+	return "reviews"
+}
+
+// TableName specifies the table name for GORM
+func (ReviewVote) TableName() string {
+	return "review_votes"
+}
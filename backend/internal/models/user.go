@@ -7,6 +7,15 @@ import (
 	"gorm.io/gorm"
 )
 
+// UserRole represents the privilege level of a user
+type UserRole string
+
+const (
+	UserRoleCustomer UserRole = "customer"
+	UserRoleStaff    UserRole = "staff"
+	UserRoleAdmin    UserRole = "admin"
+)
+
 // User represents the user entity in the database
 type User struct {
 	ID        uint           `gorm:"primaryKey" json:"id"`
@@ -14,14 +23,26 @@ type User struct {
 	Password  string         `gorm:"not null;size:255" json:"-"` // Never expose password in JSON
 	Email     string         `gorm:"size:255" json:"email,omitempty"`
 	Token     string         `gorm:"size:500" json:"-"` // Current active session token (for single-device login)
+	Role      UserRole       `gorm:"size:50;default:'customer'" json:"role"`
 	CreatedAt time.Time      `json:"created_at"`
 	UpdatedAt time.Time      `json:"updated_at"`
 	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
 
+	EmailVerifiedAt *time.Time `json:"email_verified_at,omitempty"`
+
+	// Two-factor authentication (TOTP)
+	TOTPSecret    string     `gorm:"size:64" json:"-"` // Set during enrolment, active once TOTPEnabledAt is set
+	TOTPEnabledAt *time.Time `json:"-"`
+	TOTPLastStep  int64      `gorm:"not null;default:0" json:"-"` // Last accepted time step, to reject replayed codes
+
+	// Set when the user deletes their account: the row is soft-deleted and
+	// anonymised straight away, then purged by the privacy worker after this time
+	PurgeAt *time.Time `gorm:"index" json:"-"`
+
 	// Relationships
-	Cart      *Cart   `gorm:"foreignKey:UserID" json:"cart,omitempty"`
-	Orders    []Order `gorm:"foreignKey:UserID" json:"orders,omitempty"`
-	Favorites []Item  `gorm:"many2many:user_favorites;" json:"favorites,omitempty"`
+	Cart      *Cart      `gorm:"foreignKey:UserID" json:"cart,omitempty"`
+	Orders    []Order    `gorm:"foreignKey:UserID" json:"orders,omitempty"`
+	Wishlists []Wishlist `gorm:"foreignKey:UserID" json:"wishlists,omitempty"`
 }
 
 // UserCreateRequest represents the request body for creating a user
@@ -31,6 +52,12 @@ type UserCreateRequest struct {
 	Email    string `json:"email" binding:"omitempty,email"`
 }
 
+// AccountDeleteRequest confirms a user's request to delete their own account
+type AccountDeleteRequest struct {
+	Password string `json:"password" binding:"required"`
+	Code     string `json:"code"` // TOTP or recovery code, required if two-factor authentication is on
+}
+
 // UserLoginRequest represents the request body for user login
 type UserLoginRequest struct {
 	Username string `json:"username" binding:"required"`
@@ -39,10 +66,13 @@ type UserLoginRequest struct {
 
 // UserResponse represents the user response (without sensitive data)
 type UserResponse struct {
-	ID        uint      `json:"id"`
-	Username  string    `json:"username"`
-	Email     string    `json:"email,omitempty"`
-	CreatedAt time.Time `json:"created_at"`
+	ID               uint      `json:"id"`
+	Username         string    `json:"username"`
+	Email            string    `json:"email,omitempty"`
+	EmailVerified    bool      `json:"email_verified"`
+	Role             UserRole  `json:"role"`
+	TwoFactorEnabled bool      `json:"two_factor_enabled"`
+	CreatedAt        time.Time `json:"created_at"`
 }
 
 // LoginResponse represents the login response with token
@@ -51,9 +81,21 @@ type LoginResponse struct {
 	User  UserResponse `json:"user"`
 }
 
+// TwoFactorChallengeResponse is returned by login when a second factor is still required
+type TwoFactorChallengeResponse struct {
+	TwoFactorRequired bool   `json:"two_factor_required"`
+	ChallengeToken    string `json:"challenge_token"`
+	ExpiresIn         int    `json:"expires_in"` // Seconds
+}
+
 // BeforeCreate hook to hash password before saving
 func (u *User) BeforeCreate(tx *gorm.DB) error {
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(u.Password), bcrypt.DefaultCost)
+	return u.SetPassword(u.Password)
+}
+
+// SetPassword hashes and stores a new plaintext password
+func (u *User) SetPassword(password string) error {
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
 	if err != nil {
 		return err
 	}
@@ -67,16 +109,49 @@ func (u *User) CheckPassword(password string) bool {
 	return err == nil
 }
 
+// dummyPasswordHash is compared against when a username doesn't exist,
+// so a failed login takes as long whether or not the account exists
+var dummyPasswordHash, _ = bcrypt.GenerateFromPassword([]byte("shopease-dummy-password"), bcrypt.DefaultCost)
+
+// CheckDummyPassword spends the same time as CheckPassword and always fails
+func CheckDummyPassword(password string) bool {
+	bcrypt.CompareHashAndPassword(dummyPasswordHash, []byte(password))
+	return false
+}
+
 // ToResponse converts User to UserResponse
 func (u *User) ToResponse() UserResponse {
 	return UserResponse{
-		ID:        u.ID,
-		Username:  u.Username,
-		Email:     u.Email,
-		CreatedAt: u.CreatedAt,
+		ID:               u.ID,
+		Username:         u.Username,
+		Email:            u.Email,
+		EmailVerified:    u.IsEmailVerified(),
+		Role:             u.Role,
+		TwoFactorEnabled: u.HasTwoFactor(),
+		CreatedAt:        u.CreatedAt,
 	}
 }
 
+// IsEmailVerified checks if the user has confirmed their email address
+func (u *User) IsEmailVerified() bool {
+	return u.Email != "" && u.EmailVerifiedAt != nil
+}
+
+// IsStaff checks if user can perform back-office actions such as moderation
+func (u *User) IsStaff() bool {
+	return u.Role == UserRoleStaff || u.Role == UserRoleAdmin
+}
+
+// IsAdmin checks if user can manage other accounts
+func (u *User) IsAdmin() bool {
+	return u.Role == UserRoleAdmin
+}
+
+// HasTwoFactor checks if the user has completed TOTP enrolment
+func (u *User) HasTwoFactor() bool {
+	return u.TOTPEnabledAt != nil && u.TOTPSecret != ""
+}
+
 // HasActiveSession checks if user has an active session (single-device enforcement)
 func (u *User) HasActiveSession() bool {
 	return u.Token != ""
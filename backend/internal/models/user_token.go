@@ -0,0 +1,96 @@
+package models
+
+import (
+	"time"
+
+	"shopease/internal/utils"
+
+	"gorm.io/gorm"
+)
+
+// UserTokenPurpose represents what a one-time user token may be used for
+type UserTokenPurpose string
+
+const (
+	UserTokenEmailVerification UserTokenPurpose = "email_verification"
+	UserTokenPasswordReset     UserTokenPurpose = "password_reset"
+)
+
+const (
+	EmailVerificationTTL = 48 * time.Hour
+	PasswordResetTTL     = time.Hour
+)
+
+// UserToken is a single-use, expiring token emailed to a user.
+// Only a SHA-256 hash of the token is stored.
+type UserToken struct {
+	ID        uint             `gorm:"primaryKey" json:"id"`
+	UserID    uint             `gorm:"not null;index" json:"user_id"`
+	Purpose   UserTokenPurpose `gorm:"size:50;not null;index" json:"purpose"`
+	TokenHash string           `gorm:"size:64;not null;uniqueIndex" json:"-"`
+	ExpiresAt time.Time        `gorm:"not null" json:"expires_at"`
+	UsedAt    *time.Time       `json:"used_at,omitempty"`
+	CreatedAt time.Time        `json:"created_at"`
+
+	// Relationships
+	User *User `gorm:"foreignKey:UserID" json:"-"`
+}
+
+// ForgotPasswordRequest represents the request body for starting a password reset
+type ForgotPasswordRequest struct {
+	Username string `json:"username" binding:"required_without=Email"`
+	Email    string `json:"email" binding:"omitempty,email"`
+}
+
+// ResetPasswordRequest represents the request body for completing a password reset
+type ResetPasswordRequest struct {
+	Token    string `json:"token" binding:"required"`
+	Password string `json:"password" binding:"required,min=6,max=100"`
+}
+
+// VerifyEmailRequest represents the request body for confirming an email address
+type VerifyEmailRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+// IsUsable reports whether the token can still be redeemed
+func (t *UserToken) IsUsable() bool {
+	return t.UsedAt == nil && time.Now().Before(t.ExpiresAt)
+}
+
+// TableName specifies the table name for GORM
+func (UserToken) TableName() string {
+	return "user_tokens"
+}
+
+// IssueUserToken creates a new single-use token, invalidating any earlier unused token for the same purpose.
+// It returns the plaintext token, which is never stored.
+func IssueUserToken(db *gorm.DB, userID uint, purpose UserTokenPurpose, ttl time.Duration) (string, time.Time, error) {
+	token, err := utils.GenerateRandomToken(32)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	now := time.Now()
+	expiresAt := now.Add(ttl)
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&UserToken{}).
+			Where("user_id = ? AND purpose = ? AND used_at IS NULL", userID, purpose).
+			Update("used_at", now).Error; err != nil {
+			return err
+		}
+
+		return tx.Create(&UserToken{
+			UserID:    userID,
+			Purpose:   purpose,
+			TokenHash: utils.HashToken(token),
+			ExpiresAt: expiresAt,
+		}).Error
+	})
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	return token, expiresAt, nil
+}
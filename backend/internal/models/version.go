@@ -0,0 +1,29 @@
+package models
+
+import (
+	"errors"
+
+	"gorm.io/gorm"
+)
+
+// ErrVersionConflict is returned by ClaimVersion when the row has changed since it was read
+var ErrVersionConflict = errors.New("modified since it was read")
+
+// NextVersion moves a row's version column on by one. Writers that don't
+// depend on what they read use it in UpdateColumns; the others use ClaimVersion.
+var NextVersion = gorm.Expr("version + 1")
+
+// ClaimVersion moves model's row on from version to version+1, failing with
+// ErrVersionConflict if another writer got there first. Call it inside the
+// transaction that makes the change, before writing the rest of the row;
+// model's Version field is updated to match.
+func ClaimVersion(tx *gorm.DB, model interface{}, version uint) error {
+	result := tx.Model(model).Where("version = ?", version).UpdateColumn("version", version+1)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrVersionConflict
+	}
+	return nil
+}
@@ -0,0 +1,131 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// DefaultWishlistName is the name of the list backing the legacy favourites endpoints
+const DefaultWishlistName = "Favorites"
+
+// Wishlist represents a named list of items saved by a user
+// Each user has exactly ONE default list, which backs /users/favorites
+type Wishlist struct {
+	ID         uint           `gorm:"primaryKey" json:"id"`
+	UserID     uint           `gorm:"not null;index" json:"user_id"`
+	Name       string         `gorm:"not null;size:100" json:"name"`
+	IsDefault  bool           `gorm:"not null;default:false" json:"is_default"`
+	ShareToken *string        `gorm:"size:64;uniqueIndex" json:"-"` // Unguessable token for public read-only access
+	CreatedAt  time.Time      `json:"created_at"`
+	UpdatedAt  time.Time      `json:"updated_at"`
+	DeletedAt  gorm.DeletedAt `gorm:"index" json:"-"`
+
+	// Relationships
+	User  *User          `gorm:"foreignKey:UserID" json:"-"`
+	Items []WishlistItem `gorm:"foreignKey:WishlistID" json:"items,omitempty"`
+}
+
+// WishlistItem represents an item saved on a wishlist
+type WishlistItem struct {
+	ID         uint      `gorm:"primaryKey" json:"id"`
+	WishlistID uint      `gorm:"not null;uniqueIndex:idx_wishlist_items_list_item" json:"wishlist_id"`
+	ItemID     uint      `gorm:"not null;uniqueIndex:idx_wishlist_items_list_item;index" json:"item_id"`
+	Position   int       `gorm:"not null;default:0" json:"position"`
+	Note       string    `gorm:"size:500" json:"note,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+
+	// Relationships
+	Wishlist *Wishlist `gorm:"foreignKey:WishlistID" json:"-"`
+	Item     *Item     `gorm:"foreignKey:ItemID" json:"item,omitempty"`
+}
+
+// WishlistCreateRequest represents the request body for creating a wishlist
+type WishlistCreateRequest struct {
+	Name string `json:"name" binding:"required,min=1,max=100"`
+}
+
+// WishlistUpdateRequest represents the request body for updating a wishlist
+type WishlistUpdateRequest struct {
+	Name      *string `json:"name" binding:"omitempty,min=1,max=100"`
+	IsDefault *bool   `json:"is_default"`
+}
+
+// WishlistItemRequest represents the request body for adding an item to a wishlist
+type WishlistItemRequest struct {
+	ItemID   uint   `json:"item_id" binding:"required"`
+	Note     string `json:"note" binding:"max=500"`
+	Position *int   `json:"position" binding:"omitempty,gte=0"`
+}
+
+// WishlistItemUpdateRequest represents the request body for editing a saved item
+type WishlistItemUpdateRequest struct {
+	Note     *string `json:"note" binding:"omitempty,max=500"`
+	Position *int    `json:"position" binding:"omitempty,gte=0"`
+}
+
+// WishlistMoveRequest represents the request body for moving an item to another list
+type WishlistMoveRequest struct {
+	TargetWishlistID uint `json:"target_wishlist_id" binding:"required"`
+}
+
+// WishlistResponse represents the wishlist response
+type WishlistResponse struct {
+	ID        uint                   `json:"id"`
+	Name      string                 `json:"name"`
+	IsDefault bool                   `json:"is_default"`
+	IsShared  bool                   `json:"is_shared"`
+	ItemCount int                    `json:"item_count"`
+	Items     []WishlistItemResponse `json:"items"`
+	CreatedAt time.Time              `json:"created_at"`
+}
+
+// WishlistItemResponse represents a saved item in the response
+type WishlistItemResponse struct {
+	ID       uint         `json:"id"`
+	ItemID   uint         `json:"item_id"`
+	Position int          `json:"position"`
+	Note     string       `json:"note,omitempty"`
+	Item     ItemResponse `json:"item"`
+	AddedAt  time.Time    `json:"added_at"`
+}
+
+// ToResponse converts Wishlist to WishlistResponse
+func (w *Wishlist) ToResponse() WishlistResponse {
+	items := make([]WishlistItemResponse, len(w.Items))
+
+	for i, wishlistItem := range w.Items {
+		items[i] = WishlistItemResponse{
+			ID:       wishlistItem.ID,
+			ItemID:   wishlistItem.ItemID,
+			Position: wishlistItem.Position,
+			Note:     wishlistItem.Note,
+			AddedAt:  wishlistItem.CreatedAt,
+		}
+
+		if wishlistItem.Item != nil {
+			items[i].Item = wishlistItem.Item.ToResponse()
+		}
+	}
+
+	return WishlistResponse{
+		ID:        w.ID,
+		Name:      w.Name,
+		IsDefault: w.IsDefault,
+		IsShared:  w.ShareToken != nil,
+		ItemCount: len(w.Items),
+		Items:     items,
+		CreatedAt: w.CreatedAt,
+	}
+}
+
+// TableName specifies the table name for GORM
+func (Wishlist) TableName() string {
+	return "wishlists"
+}
+
+// TableName specifies the table name for GORM
+func (WishlistItem) TableName() string {
+	return "wishlist_items"
+}
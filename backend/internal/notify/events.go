@@ -0,0 +1,38 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+
+	"shopease/internal/database"
+	"shopease/internal/events"
+	"shopease/internal/jobs"
+)
+
+// Order confirmations, cancellations and welcome emails are sent in response
+// to domain events, so every path that places or cancels an order, or signs
+// up a user, emails the customer exactly once: each mail is keyed by the
+// event it answers, and redeliveries find it already queued.
+func init() {
+	events.Subscribe("mail", orderPlaced)
+	events.Subscribe("mail", orderCancelled)
+	events.Subscribe("mail", userRegistered)
+}
+
+func orderPlaced(ctx context.Context, env events.Envelope, event events.OrderPlaced) error {
+	return sendFor(ctx, env, Mail{Template: TemplateOrderPlaced, UserID: event.UserID, OrderID: event.OrderID})
+}
+
+func orderCancelled(ctx context.Context, env events.Envelope, event events.OrderCancelled) error {
+	return sendFor(ctx, env, Mail{Template: TemplateOrderCancelled, UserID: event.UserID, OrderID: event.OrderID})
+}
+
+func userRegistered(ctx context.Context, env events.Envelope, event events.UserRegistered) error {
+	return sendFor(ctx, env, Mail{Template: TemplateWelcome, UserID: event.UserID})
+}
+
+// sendFor queues mail in answer to an event, once however often the event is delivered
+func sendFor(ctx context.Context, env events.Envelope, mail Mail) error {
+	_, err := jobs.Enqueue(database.DB.WithContext(ctx), MailJob, mail, jobs.Options{Key: fmt.Sprintf("event:%d", env.ID)})
+	return err
+}
@@ -0,0 +1,69 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Message is a rendered email ready for delivery
+type Message struct {
+	To       string
+	Subject  string
+	HTMLBody string
+}
+
+// Mailer delivers email messages
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// LogMailer writes messages to the application log instead of sending them (development)
+type LogMailer struct{}
+
+// Send logs the message
+func (LogMailer) Send(ctx context.Context, msg Message) error {
+	log.Printf("📧 Mail to %s: %s\n%s", msg.To, msg.Subject, msg.HTMLBody)
+	return nil
+}
+
+// FileMailer writes each message as an HTML file in Dir (development)
+type FileMailer struct {
+	Dir string
+}
+
+var unsafeFileChars = regexp.MustCompile(`[^a-zA-Z0-9._-]+`)
+
+// Send writes the message to a new file named after its timestamp and recipient
+func (m FileMailer) Send(ctx context.Context, msg Message) error {
+	if err := os.MkdirAll(m.Dir, 0o755); err != nil {
+		return err
+	}
+
+	name := fmt.Sprintf("%s_%s.html", time.Now().Format("20060102T150405.000000000"), unsafeFileChars.ReplaceAllString(msg.To, "_"))
+	content := fmt.Sprintf("<!-- To: %s -->\n<!-- Subject: %s -->\n%s", msg.To, msg.Subject, msg.HTMLBody)
+
+	return os.WriteFile(filepath.Join(m.Dir, name), []byte(content), 0o644)
+}
+
+// NewMailer creates the mailer selected by driver ("smtp", "file" or "log")
+func NewMailer(driver string, smtp SMTPMailer, dir string) (Mailer, error) {
+	switch strings.ToLower(driver) {
+	case "smtp":
+		if smtp.Host == "" {
+			return nil, fmt.Errorf("smtp mailer requires a host")
+		}
+		return smtp, nil
+	case "file":
+		return FileMailer{Dir: dir}, nil
+	case "", "log":
+		return LogMailer{}, nil
+	default:
+		return nil, fmt.Errorf("unknown mail driver %q", driver)
+	}
+}
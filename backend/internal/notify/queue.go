@@ -0,0 +1,154 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"shopease/internal/database"
+	"shopease/internal/jobs"
+	"shopease/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// MailJob is the type of the background job that delivers one message
+const MailJob = "notify.mail"
+
+// sendTimeout bounds one delivery attempt
+const sendTimeout = 30 * time.Second
+
+var (
+	mailerMu sync.RWMutex
+	mailer   Mailer = LogMailer{}
+)
+
+// Mail identifies an email to send: its template, the user it goes to and,
+// for the order templates, the order. Queued jobs hold only these references;
+// the message is rendered, and any single-use code issued, on delivery, so no
+// address or code is stored with the job.
+type Mail struct {
+	Template Template `json:"template"`
+	UserID   uint     `json:"user_id"`
+	OrderID  uint     `json:"order_id,omitempty"`
+}
+
+func init() {
+	jobs.Register(MailJob, deliver)
+}
+
+// SetMailer makes m deliver queued messages. Call it at startup, before the
+// job workers start.
+func SetMailer(m Mailer) {
+	mailerMu.Lock()
+	defer mailerMu.Unlock()
+	mailer = m
+}
+
+// Send queues mail for delivery within tx, so it is only sent if the change
+// that caused it commits. Messages are delivered by the job workers, which
+// retry failures with back-off, so they survive restarts. Users without an
+// email address, or deleted by the time it is delivered, are skipped.
+func Send(tx *gorm.DB, mail Mail) error {
+	_, err := jobs.Enqueue(tx, MailJob, mail, jobs.Options{})
+	return err
+}
+
+// Discard deletes the mail waiting to be sent to a user, within tx, for when
+// their account is deleted. Mail already being delivered still goes out.
+func Discard(tx *gorm.DB, userID uint) error {
+	var queued []models.Job
+	if err := tx.Select("id", "payload").
+		Where("type = ? AND state <> ?", MailJob, models.JobRunning).
+		Find(&queued).Error; err != nil {
+		return err
+	}
+
+	var ids []uint
+	for _, job := range queued {
+		var mail Mail
+		if err := json.Unmarshal(job.Payload, &mail); err == nil && mail.UserID == userID {
+			ids = append(ids, job.ID)
+		}
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+	return tx.Where("id IN ?", ids).Delete(&models.Job{}).Error
+}
+
+// deliver renders queued mail and sends it with the configured mailer
+func deliver(ctx context.Context, mail Mail) error {
+	msg, err := compose(ctx, mail)
+	if err != nil || msg == nil {
+		return err
+	}
+
+	mailerMu.RLock()
+	m := mailer
+	mailerMu.RUnlock()
+
+	ctx, cancel := context.WithTimeout(ctx, sendTimeout)
+	defer cancel()
+	return m.Send(ctx, *msg)
+}
+
+// compose loads what mail refers to and renders it, or returns nil if there
+// is no longer anyone to send it to
+func compose(ctx context.Context, mail Mail) (*Message, error) {
+	db := database.DB.WithContext(ctx)
+
+	var user models.User
+	if err := db.First(&user, mail.UserID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if user.Email == "" {
+		return nil, nil
+	}
+
+	var data interface{}
+	switch mail.Template {
+	case TemplateWelcome:
+		data = WelcomeData{Username: user.Username}
+
+	case TemplateOrderPlaced, TemplateOrderShipped, TemplateOrderCancelled:
+		var order models.Order
+		if err := db.Preload("OrderItems").Where("user_id = ?", user.ID).First(&order, mail.OrderID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, nil
+			}
+			return nil, err
+		}
+		data = OrderData{Username: user.Username, Order: order.ToResponse()}
+
+	case TemplatePasswordReset, TemplateVerifyEmail:
+		purpose, ttl := models.UserTokenPasswordReset, models.PasswordResetTTL
+		if mail.Template == TemplateVerifyEmail {
+			if user.IsEmailVerified() {
+				return nil, nil
+			}
+			purpose, ttl = models.UserTokenEmailVerification, models.EmailVerificationTTL
+		}
+		// Issued per attempt, so a failed delivery's code is replaced by the next
+		token, expiresAt, err := models.IssueUserToken(db, user.ID, purpose, ttl)
+		if err != nil {
+			return nil, err
+		}
+		data = TokenData{Username: user.Username, Token: token, ExpiresAt: expiresAt}
+
+	default:
+		return nil, jobs.Permanent(fmt.Errorf("unknown email template %q", mail.Template))
+	}
+
+	msg, err := Render(mail.Template, user.Email, data)
+	if err != nil {
+		return nil, jobs.Permanent(err)
+	}
+	return &msg, nil
+}
@@ -0,0 +1,93 @@
+package notify
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"html"
+	"html/template"
+	"strings"
+	"time"
+
+	"shopease/internal/models"
+)
+
+//go:embed templates/*.html
+var templateFS embed.FS
+
+// Template identifies an email template
+type Template string
+
+const (
+	TemplateWelcome        Template = "welcome"
+	TemplateOrderPlaced    Template = "order_placed"
+	TemplateOrderShipped   Template = "order_shipped"
+	TemplateOrderCancelled Template = "order_cancelled"
+	TemplatePasswordReset  Template = "password_reset"
+	TemplateVerifyEmail    Template = "verify_email"
+)
+
+// WelcomeData is the data for TemplateWelcome
+type WelcomeData struct {
+	Username string
+}
+
+// OrderData is the data for the order templates
+type OrderData struct {
+	Username string
+	Order    models.OrderResponse
+}
+
+// TokenData is the data for TemplatePasswordReset and TemplateVerifyEmail
+type TokenData struct {
+	Username  string
+	Token     string
+	ExpiresAt time.Time
+}
+
+var templateFuncs = template.FuncMap{
+	"money": func(amount float64) string {
+		return fmt.Sprintf("$%.2f", amount)
+	},
+}
+
+var templates = parseTemplates(
+	TemplateWelcome,
+	TemplateOrderPlaced,
+	TemplateOrderShipped,
+	TemplateOrderCancelled,
+	TemplatePasswordReset,
+	TemplateVerifyEmail,
+)
+
+// parseTemplates parses each named template together with the shared layout
+func parseTemplates(names ...Template) map[Template]*template.Template {
+	parsed := make(map[Template]*template.Template, len(names))
+	for _, name := range names {
+		parsed[name] = template.Must(template.New(string(name)).Funcs(templateFuncs).
+			ParseFS(templateFS, "templates/layout.html", "templates/"+string(name)+".html"))
+	}
+	return parsed
+}
+
+// Render produces the email for a template addressed to the given recipient
+func Render(name Template, to string, data interface{}) (Message, error) {
+	tmpl, ok := templates[name]
+	if !ok {
+		return Message{}, fmt.Errorf("unknown email template %q", name)
+	}
+
+	var subject, body bytes.Buffer
+	if err := tmpl.ExecuteTemplate(&subject, "subject", data); err != nil {
+		return Message{}, err
+	}
+	if err := tmpl.ExecuteTemplate(&body, "layout", data); err != nil {
+		return Message{}, err
+	}
+
+	return Message{
+		To:       to,
+		Subject:  strings.TrimSpace(html.UnescapeString(subject.String())),
+		HTMLBody: body.String(),
+	}, nil
+}
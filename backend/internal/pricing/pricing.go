@@ -0,0 +1,257 @@
+// Package pricing maintains item price history: regular prices, scheduled
+// price changes and time-limited sales. Item.Price always holds the price in
+// effect, so the rest of the shop never needs to consult the history.
+package pricing
+
+import (
+	"errors"
+	"sort"
+	"time"
+
+	"shopease/internal/events"
+	"shopease/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// Errors returned for schedules that don't make sense
+var (
+	ErrUnknownKind     = errors.New("kind must be regular or sale")
+	ErrStartsInPast    = errors.New("effective_from must not be in the past")
+	ErrEmptyRange      = errors.New("effective_until must be after effective_from")
+	ErrSaleOnly        = errors.New("compare_at_price and effective_until are only allowed on sale prices")
+	ErrCompareAtTooLow = errors.New("compare_at_price must be above the sale price")
+	ErrStartTaken      = errors.New("another regular price starts at the same time")
+	ErrAlreadyStarted  = errors.New("only prices that have not taken effect can be cancelled")
+)
+
+// scheduleTolerance lets a schedule "from now" arrive a little after it was made
+const scheduleTolerance = time.Minute
+
+// History returns an item's price entries, oldest first
+func History(tx *gorm.DB, itemID uint) ([]models.ItemPrice, error) {
+	var prices []models.ItemPrice
+	if err := tx.Where("item_id = ?", itemID).Order("id").Find(&prices).Error; err != nil {
+		return nil, err
+	}
+	// Ordered here rather than in SQL: SQLite compares timestamps as text,
+	// which misorders fractional seconds of different lengths
+	sort.SliceStable(prices, func(i, j int) bool {
+		return prices[i].EffectiveFrom.Before(prices[j].EffectiveFrom)
+	})
+	return prices, nil
+}
+
+// inEffect returns the latest-starting entry of kind whose range covers t
+func inEffect(prices []models.ItemPrice, kind models.PriceKind, t time.Time) *models.ItemPrice {
+	var found *models.ItemPrice
+	for i := range prices {
+		if prices[i].Kind == kind && prices[i].IsActiveAt(t) {
+			found = &prices[i]
+		}
+	}
+	return found
+}
+
+// resolve works out the price and compare-at price in effect at t
+func resolve(prices []models.ItemPrice, t time.Time) (float64, *float64, bool) {
+	regular := inEffect(prices, models.PriceKindRegular, t)
+	sale := inEffect(prices, models.PriceKindSale, t)
+
+	switch {
+	case sale != nil:
+		compareAt := sale.CompareAtPrice
+		if compareAt == nil && regular != nil && regular.Price > sale.Price {
+			compareAt = &regular.Price
+		}
+		return sale.Price, compareAt, true
+	case regular != nil:
+		return regular.Price, nil, true
+	}
+	return 0, nil, false
+}
+
+// Refresh updates item's cached price to what is in effect at t and reports
+// whether it changed. Items without any history are left alone.
+func Refresh(tx *gorm.DB, item *models.Item, t time.Time) (bool, error) {
+	prices, err := History(tx, item.ID)
+	if err != nil {
+		return false, err
+	}
+	price, compareAt, found := resolve(prices, t)
+	if !found || (price == item.Price && samePrice(compareAt, item.CompareAtPrice)) {
+		return false, nil
+	}
+
+	if price != item.Price {
+		if err := events.Publish(tx, events.ItemPriceChanged{ItemID: item.ID, OldPrice: item.Price, NewPrice: price}); err != nil {
+			return false, err
+		}
+	}
+	if err := tx.Model(&models.Item{}).Unscoped().Where("id = ?", item.ID).UpdateColumns(map[string]interface{}{
+		"price":            price,
+		"compare_at_price": compareAt,
+		"updated_at":       time.Now(),
+		"version":          models.NextVersion,
+	}).Error; err != nil {
+		return false, err
+	}
+	item.Price, item.CompareAtPrice = price, compareAt
+	item.Version++
+	return true, nil
+}
+
+func samePrice(a, b *float64) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+// SetRegular makes price the item's regular price from at onwards, ending
+// the previous one, then refreshes the item. It records nothing if the
+// regular price in effect at that time is already price.
+func SetRegular(tx *gorm.DB, item *models.Item, price float64, at time.Time) error {
+	at = at.UTC()
+	prices, err := History(tx, item.ID)
+	if err != nil {
+		return err
+	}
+	if current := inEffect(prices, models.PriceKindRegular, at); current == nil || current.Price != price {
+		entry := models.ItemPrice{ItemID: item.ID, Kind: models.PriceKindRegular, Price: price, EffectiveFrom: at}
+		if err := insertRegular(tx, prices, &entry); err != nil {
+			return err
+		}
+	}
+	_, err = Refresh(tx, item, time.Now())
+	return err
+}
+
+// insertRegular adds a regular price, fitting it between the regular
+// prices already in the history by adjusting their end times
+func insertRegular(tx *gorm.DB, prices []models.ItemPrice, entry *models.ItemPrice) error {
+	var previous, next *models.ItemPrice
+	for i := range prices {
+		p := &prices[i]
+		if p.Kind != models.PriceKindRegular {
+			continue
+		}
+		switch {
+		case p.EffectiveFrom.Equal(entry.EffectiveFrom):
+			return ErrStartTaken
+		case p.EffectiveFrom.Before(entry.EffectiveFrom):
+			previous = p
+		case next == nil:
+			next = p
+		}
+	}
+
+	if next != nil {
+		until := next.EffectiveFrom
+		entry.EffectiveUntil = &until
+	}
+	if err := tx.Create(entry).Error; err != nil {
+		return err
+	}
+	if previous != nil {
+		return tx.Model(previous).Update("effective_until", entry.EffectiveFrom).Error
+	}
+	return nil
+}
+
+// Schedule adds a regular or sale price to an item's history and refreshes
+// the item, which changes its price at once if the new entry starts now.
+func Schedule(tx *gorm.DB, item *models.Item, req models.ItemPriceRequest, now time.Time) (*models.ItemPrice, error) {
+	now = now.UTC()
+	entry := models.ItemPrice{
+		ItemID:         item.ID,
+		Kind:           req.Kind,
+		Price:          req.Price,
+		CompareAtPrice: req.CompareAtPrice,
+		EffectiveFrom:  now,
+	}
+	if entry.Kind == "" {
+		entry.Kind = models.PriceKindRegular
+	}
+	if req.EffectiveFrom != nil {
+		if req.EffectiveFrom.Before(now.Add(-scheduleTolerance)) {
+			return nil, ErrStartsInPast
+		}
+		if req.EffectiveFrom.After(now) {
+			entry.EffectiveFrom = req.EffectiveFrom.UTC()
+		}
+	}
+	if req.EffectiveUntil != nil {
+		until := req.EffectiveUntil.UTC()
+		if !until.After(entry.EffectiveFrom) {
+			return nil, ErrEmptyRange
+		}
+		entry.EffectiveUntil = &until
+	}
+
+	switch entry.Kind {
+	case models.PriceKindRegular:
+		if entry.CompareAtPrice != nil || entry.EffectiveUntil != nil {
+			return nil, ErrSaleOnly
+		}
+		prices, err := History(tx, item.ID)
+		if err != nil {
+			return nil, err
+		}
+		if err := insertRegular(tx, prices, &entry); err != nil {
+			return nil, err
+		}
+	case models.PriceKindSale:
+		if entry.CompareAtPrice != nil && *entry.CompareAtPrice <= entry.Price {
+			return nil, ErrCompareAtTooLow
+		}
+		if err := tx.Create(&entry).Error; err != nil {
+			return nil, err
+		}
+	default:
+		return nil, ErrUnknownKind
+	}
+
+	if _, err := Refresh(tx, item, now); err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+// Cancel removes a price that has not taken effect yet, reopening the
+// regular price it would have ended
+func Cancel(tx *gorm.DB, item *models.Item, priceID uint, now time.Time) (*models.ItemPrice, error) {
+	var entry models.ItemPrice
+	if err := tx.Where("id = ? AND item_id = ?", priceID, item.ID).First(&entry).Error; err != nil {
+		return nil, err
+	}
+	if !entry.EffectiveFrom.After(now) {
+		return nil, ErrAlreadyStarted
+	}
+
+	if entry.Kind == models.PriceKindRegular {
+		prices, err := History(tx, item.ID)
+		if err != nil {
+			return nil, err
+		}
+		var previous *models.ItemPrice
+		for i := range prices {
+			if prices[i].Kind == models.PriceKindRegular && prices[i].EffectiveFrom.Before(entry.EffectiveFrom) {
+				previous = &prices[i]
+			}
+		}
+		if previous != nil {
+			if err := tx.Model(previous).Update("effective_until", entry.EffectiveUntil).Error; err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if err := tx.Delete(&entry).Error; err != nil {
+		return nil, err
+	}
+	if _, err := Refresh(tx, item, now); err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
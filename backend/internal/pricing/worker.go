@@ -0,0 +1,126 @@
+package pricing
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"shopease/internal/audit"
+	"shopease/internal/cache"
+	"shopease/internal/database"
+	"shopease/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// activateInterval is how often the worker applies prices that have started or ended
+const activateInterval = time.Minute
+
+// Actor is recorded in the audit log for prices changed by the worker
+var Actor = audit.System("pricing")
+
+var (
+	stop    chan struct{}
+	stopped sync.WaitGroup
+)
+
+// Start launches the background worker that applies scheduled prices and
+// ends sales on time. Its first run catches up on anything that fell due
+// while the server was down.
+func Start() {
+	stop = make(chan struct{})
+	stopped.Add(1)
+
+	go func() {
+		defer stopped.Done()
+
+		ticker := time.NewTicker(activateInterval)
+		defer ticker.Stop()
+
+		var since time.Time
+		for {
+			now := time.Now()
+			if changed, err := Activate(context.Background(), since, now); err != nil {
+				log.Printf("Error applying scheduled prices: %v", err)
+			} else {
+				since = now
+				if changed > 0 {
+					log.Printf("Applied scheduled prices to %d items", changed)
+				}
+			}
+
+			select {
+			case <-ticker.C:
+			case <-stop:
+				return
+			}
+		}
+	}()
+
+	log.Println("Pricing worker started")
+}
+
+// Stop waits for any run in progress and stops the worker
+func Stop() {
+	if stop == nil {
+		return
+	}
+	close(stop)
+	stopped.Wait()
+	stop = nil
+}
+
+// Activate refreshes every item with a price that started or ended after
+// since and by now, and returns how many items changed price. Changes are
+// audited, and Refresh publishes the price changes for the alerts.
+func Activate(ctx context.Context, since, now time.Time) (int, error) {
+	// Widened by a second either side: timestamps compare as text in SQLite,
+	// and refreshing an item that hasn't changed is harmless
+	lower, upper := since.Add(-time.Second).UTC(), now.Add(time.Second).UTC()
+
+	var itemIDs []uint
+	if err := database.DB.WithContext(ctx).Model(&models.ItemPrice{}).Distinct().
+		Where("(effective_from > ? AND effective_from <= ?) OR (effective_until > ? AND effective_until <= ?)", lower, upper, lower, upper).
+		Pluck("item_id", &itemIDs).Error; err != nil {
+		return 0, err
+	}
+
+	changed := 0
+	for _, id := range itemIDs {
+		priceChanged := false
+		err := database.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			var item models.Item
+			if err := tx.First(&item, id).Error; err != nil {
+				if errors.Is(err, gorm.ErrRecordNotFound) {
+					return nil // Deleted since the price was scheduled
+				}
+				return err
+			}
+
+			before := item.ToResponse()
+			updated, err := Refresh(tx, &item, now)
+			if err != nil || !updated {
+				return err
+			}
+			priceChanged = item.Price != before.Price
+			return audit.Record(tx, Actor, audit.Event{
+				Action:     audit.ActionItemUpdated,
+				TargetType: audit.TargetItem,
+				TargetID:   item.ID,
+				Before:     before,
+				After:      item.ToResponse(),
+				Details:    map[string]interface{}{"scheduled": true},
+			})
+		})
+		if err != nil {
+			return changed, err
+		}
+		cache.InvalidateItem(id)
+		if priceChanged {
+			changed++
+		}
+	}
+	return changed, nil
+}
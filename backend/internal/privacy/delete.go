@@ -0,0 +1,153 @@
+package privacy
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"shopease/internal/audit"
+	"shopease/internal/cache"
+	"shopease/internal/database"
+	"shopease/internal/models"
+	"shopease/internal/notify"
+	"shopease/internal/utils"
+
+	"gorm.io/gorm"
+)
+
+// Actor is recorded in the audit log for accounts purged by the worker
+var Actor = audit.System("privacy")
+
+// Anonymise deletes a user's account within tx. Personal data is removed at
+// once: the profile is scrubbed, the row soft-deleted so the account can no
+// longer be used, and the cart, wishlists, alerts, notifications, tokens,
+// unsent mail and login history are deleted. Orders are kept for their
+// financial record but lose their free-text note. Reviews stay, unattributed,
+// until Purge removes them along with the account row after purgeAt. Audit events are kept as
+// recorded, with the username and IP address they name; see models.AuditEvent.
+func Anonymise(tx *gorm.DB, user *models.User, actor audit.Actor, purgeAt time.Time) error {
+	suffix, err := utils.GenerateRandomToken(6)
+	if err != nil {
+		return err
+	}
+	originalUsername := user.Username
+
+	if err := tx.Model(&models.User{}).Where("id = ?", user.ID).Updates(map[string]interface{}{
+		"username":          fmt.Sprintf("deleted-%s", suffix), // Frees the username for someone else
+		"email":             "",
+		"email_verified_at": nil,
+		"password":          "", // Matches no password
+		"token":             "",
+		"totp_secret":       "",
+		"totp_enabled_at":   nil,
+		"purge_at":          purgeAt,
+	}).Error; err != nil {
+		return err
+	}
+	if err := tx.Delete(&models.User{}, user.ID).Error; err != nil {
+		return err
+	}
+
+	if err := tx.Unscoped().Model(&models.Order{}).Where("user_id = ?", user.ID).Updates(map[string]interface{}{"note": "", "version": models.NextVersion}).Error; err != nil {
+		return err
+	}
+
+	carts := tx.Unscoped().Model(&models.Cart{}).Select("id").Where("user_id = ?", user.ID)
+	wishlists := tx.Unscoped().Model(&models.Wishlist{}).Select("id").Where("user_id = ?", user.ID)
+	for _, owned := range []struct {
+		model interface{}
+		query string
+		args  []interface{}
+	}{
+		{&models.CartItem{}, "cart_id IN (?)", []interface{}{carts}},
+		{&models.Cart{}, "user_id = ?", []interface{}{user.ID}},
+		{&models.WishlistItem{}, "wishlist_id IN (?)", []interface{}{wishlists}},
+		{&models.Wishlist{}, "user_id = ?", []interface{}{user.ID}},
+		{&models.ItemAlert{}, "user_id = ?", []interface{}{user.ID}},
+		{&models.Notification{}, "user_id = ?", []interface{}{user.ID}},
+		{&models.UserToken{}, "user_id = ?", []interface{}{user.ID}},
+		{&models.RecoveryCode{}, "user_id = ?", []interface{}{user.ID}},
+		{&models.LoginAttempt{}, "user_id = ? OR username = ?", []interface{}{user.ID, originalUsername}},
+	} {
+		if err := tx.Unscoped().Where(owned.query, owned.args...).Delete(owned.model).Error; err != nil {
+			return err
+		}
+	}
+	if err := notify.Discard(tx, user.ID); err != nil {
+		return err
+	}
+
+	return audit.Record(tx, actor, audit.Event{
+		Action:     audit.ActionAccountDeleted,
+		TargetType: audit.TargetUser,
+		TargetID:   user.ID,
+		Details:    map[string]interface{}{"purge_at": purgeAt.UTC().Format(time.RFC3339)},
+	})
+}
+
+// Purge permanently removes accounts whose grace period ended by now, along
+// with their reviews and helpful votes, and returns how many it removed.
+// Orders keep their user ID so financial records still add up.
+func Purge(ctx context.Context, now time.Time) (int, error) {
+	var users []models.User
+	if err := database.DB.WithContext(ctx).Unscoped().
+		Where("purge_at IS NOT NULL AND purge_at <= ?", now).
+		Find(&users).Error; err != nil {
+		return 0, err
+	}
+
+	purged := 0
+	for i := range users {
+		if err := database.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			return purgeUser(tx, &users[i])
+		}); err != nil {
+			return purged, fmt.Errorf("purging user %d: %w", users[i].ID, err)
+		}
+		purged++
+	}
+	if purged > 0 {
+		cache.InvalidateAll() // Rating aggregates of reviewed items changed
+	}
+	return purged, nil
+}
+
+// purgeUser hard-deletes one anonymised account and the content it authored
+func purgeUser(tx *gorm.DB, user *models.User) error {
+	// Withdraw the user's helpful votes from other people's reviews
+	votedOn := tx.Model(&models.ReviewVote{}).Select("review_id").Where("user_id = ?", user.ID)
+	if err := tx.Unscoped().Model(&models.Review{}).Where("id IN (?)", votedOn).
+		UpdateColumn("helpful_count", gorm.Expr("helpful_count - 1")).Error; err != nil {
+		return err
+	}
+	if err := tx.Where("user_id = ?", user.ID).Delete(&models.ReviewVote{}).Error; err != nil {
+		return err
+	}
+
+	var reviews []models.Review
+	if err := tx.Unscoped().Where("user_id = ?", user.ID).Find(&reviews).Error; err != nil {
+		return err
+	}
+	for _, review := range reviews {
+		if review.IsPublished() && !review.DeletedAt.Valid {
+			if err := models.ApplyRatingDelta(tx, review.ItemID, review.Rating, -1); err != nil {
+				return err
+			}
+		}
+		if err := tx.Where("review_id = ?", review.ID).Delete(&models.ReviewVote{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Delete(&review).Error; err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Unscoped().Delete(user).Error; err != nil {
+		return err
+	}
+	return audit.Record(tx, Actor, audit.Event{
+		Action:     audit.ActionAccountPurged,
+		TargetType: audit.TargetUser,
+		TargetID:   user.ID,
+		Details:    map[string]interface{}{"reviews": len(reviews)},
+	})
+}
@@ -0,0 +1,128 @@
+// Package privacy implements data subject requests: exporting everything
+// stored about a user, and deleting their account by anonymising it at once
+// and purging what remains after a grace period.
+package privacy
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"time"
+
+	"shopease/internal/config"
+	"shopease/internal/database"
+	"shopease/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// Bundle is everything stored about one user
+type Bundle struct {
+	ExportedAt    time.Time                  `json:"exported_at"`
+	Profile       models.UserResponse        `json:"profile"`
+	Cart          *models.CartResponse       `json:"cart"`
+	Orders        []models.OrderResponse     `json:"orders"`
+	Wishlists     []models.WishlistResponse  `json:"wishlists"` // Includes favourites, the default wishlist
+	Reviews       []models.ReviewResponse    `json:"reviews"`
+	Alerts        []models.ItemAlertResponse `json:"alerts"`
+	Notifications []models.Notification      `json:"notifications"`
+	LoginHistory  []models.LoginAttempt      `json:"login_history"`
+}
+
+// Export collects a user's data
+func Export(ctx context.Context, user *models.User) (*Bundle, error) {
+	db := database.DB.WithContext(ctx)
+	bundle := &Bundle{
+		ExportedAt: time.Now().UTC(),
+		Profile:    user.ToResponse(),
+	}
+
+	var cart models.Cart
+	err := db.Preload("CartItems.Item").Where("user_id = ?", user.ID).First(&cart).Error
+	switch {
+	case err == nil:
+		response := cart.ToResponse(config.AppConfig.CartMaxQuantity)
+		bundle.Cart = &response
+	case !errors.Is(err, gorm.ErrRecordNotFound):
+		return nil, err
+	}
+
+	var orders []models.Order
+	if err := db.Preload("OrderItems").Where("user_id = ?", user.ID).Order("created_at").Find(&orders).Error; err != nil {
+		return nil, err
+	}
+	bundle.Orders = make([]models.OrderResponse, len(orders))
+	for i := range orders {
+		bundle.Orders[i] = orders[i].ToResponse()
+	}
+
+	var wishlists []models.Wishlist
+	if err := db.Preload("Items", func(db *gorm.DB) *gorm.DB {
+		return db.Order("position ASC, id ASC")
+	}).Preload("Items.Item").Where("user_id = ?", user.ID).Order("id").Find(&wishlists).Error; err != nil {
+		return nil, err
+	}
+	bundle.Wishlists = make([]models.WishlistResponse, len(wishlists))
+	for i := range wishlists {
+		bundle.Wishlists[i] = wishlists[i].ToResponse()
+	}
+
+	// Every review, including ones held back or rejected by moderation
+	var reviews []models.Review
+	if err := db.Where("user_id = ?", user.ID).Order("created_at").Find(&reviews).Error; err != nil {
+		return nil, err
+	}
+	bundle.Reviews = make([]models.ReviewResponse, len(reviews))
+	for i := range reviews {
+		bundle.Reviews[i] = reviews[i].ToResponse()
+	}
+
+	var alerts []models.ItemAlert
+	if err := db.Preload("Item").Where("user_id = ?", user.ID).Order("id").Find(&alerts).Error; err != nil {
+		return nil, err
+	}
+	bundle.Alerts = make([]models.ItemAlertResponse, len(alerts))
+	for i := range alerts {
+		bundle.Alerts[i] = alerts[i].ToResponse()
+	}
+
+	if err := db.Where("user_id = ?", user.ID).Order("created_at").Find(&bundle.Notifications).Error; err != nil {
+		return nil, err
+	}
+	if err := db.Where("user_id = ?", user.ID).Order("created_at").Find(&bundle.LoginHistory).Error; err != nil {
+		return nil, err
+	}
+
+	return bundle, nil
+}
+
+// WriteZip writes the bundle as a ZIP archive with one JSON file per section
+func (b *Bundle) WriteZip(w io.Writer) error {
+	archive := zip.NewWriter(w)
+	for _, section := range []struct {
+		name string
+		data interface{}
+	}{
+		{"profile.json", b.Profile},
+		{"cart.json", b.Cart},
+		{"orders.json", b.Orders},
+		{"wishlists.json", b.Wishlists},
+		{"reviews.json", b.Reviews},
+		{"alerts.json", b.Alerts},
+		{"notifications.json", b.Notifications},
+		{"login_history.json", b.LoginHistory},
+	} {
+		file, err := archive.CreateHeader(&zip.FileHeader{Name: section.name, Method: zip.Deflate, Modified: b.ExportedAt})
+		if err != nil {
+			return err
+		}
+		encoder := json.NewEncoder(file)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(section.data); err != nil {
+			return err
+		}
+	}
+	return archive.Close()
+}
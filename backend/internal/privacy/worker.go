@@ -0,0 +1,61 @@
+package privacy
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+)
+
+// purgeInterval is how often the worker looks for accounts due to be purged
+const purgeInterval = time.Hour
+
+var (
+	stop    chan struct{}
+	stopped sync.WaitGroup
+)
+
+// Start launches the background worker that purges deleted accounts once
+// their grace period ends. It runs once straight away, then every hour.
+func Start() {
+	stop = make(chan struct{})
+	stopped.Add(1)
+
+	go func() {
+		defer stopped.Done()
+
+		ticker := time.NewTicker(purgeInterval)
+		defer ticker.Stop()
+
+		for {
+			purgeDue()
+			select {
+			case <-ticker.C:
+			case <-stop:
+				return
+			}
+		}
+	}()
+
+	log.Println("Account purge worker started")
+}
+
+// Stop waits for any purge in progress and stops the worker
+func Stop() {
+	if stop == nil {
+		return
+	}
+	close(stop)
+	stopped.Wait()
+	stop = nil
+}
+
+func purgeDue() {
+	purged, err := Purge(context.Background(), time.Now())
+	if err != nil {
+		log.Printf("Error purging deleted accounts: %v", err)
+	}
+	if purged > 0 {
+		log.Printf("Purged %d deleted accounts", purged)
+	}
+}
@@ -0,0 +1,40 @@
+package ratelimit
+
+import (
+	"fmt"
+	"time"
+)
+
+// Policy describes how many requests a single key may make per period.
+// Requests are limited with a token bucket: the bucket holds up to Requests
+// tokens and refills continuously at Requests per Period, so short bursts are
+// allowed while the long-run rate is capped.
+type Policy struct {
+	Name     string // Namespaces keys so one client's budgets are independent per policy
+	Requests int
+	Period   time.Duration
+}
+
+// String formats the policy for the RateLimit-Policy header, e.g. "10;w=60"
+func (p Policy) String() string {
+	return fmt.Sprintf("%d;w=%d", p.Requests, int(p.Period.Seconds()))
+}
+
+// rate returns the refill rate in tokens per second
+func (p Policy) rate() float64 {
+	return float64(p.Requests) / p.Period.Seconds()
+}
+
+// Result is the outcome of taking a token for a key
+type Result struct {
+	Allowed    bool
+	Limit      int
+	Remaining  int
+	ResetAfter time.Duration // Until the bucket is full again
+	RetryAfter time.Duration // Until the next request would be allowed; zero when allowed
+}
+
+// Store keeps rate-limit state. Implementations must be safe for concurrent use.
+type Store interface {
+	Take(key string, policy Policy) (Result, error)
+}
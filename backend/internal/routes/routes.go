@@ -1,8 +1,12 @@
 package routes
 
 import (
+	"log"
+
+	"shopease/internal/cache"
 	"shopease/internal/config"
 	"shopease/internal/handlers"
+	"shopease/internal/metrics"
 	"shopease/internal/middleware"
 
 	"github.com/gin-gonic/gin"
@@ -13,9 +17,20 @@ func SetupRouter() *gin.Engine {
 	// Set Gin mode
 	gin.SetMode(config.AppConfig.GinMode)
 
-	router := gin.Default()
+	router := gin.New()
+
+	// Only believe forwarded client addresses from configured proxies; the
+	// client IP feeds rate limits, the login throttle and audit records
+	if err := router.SetTrustedProxies(config.AppConfig.TrustedProxyList()); err != nil {
+		log.Printf("Error setting trusted proxies: %v", err)
+	}
 
 	// Global middleware
+	router.Use(gin.Recovery())
+	router.Use(middleware.RequestIDMiddleware())
+	router.Use(middleware.TracingMiddleware())
+	router.Use(middleware.LoggerMiddleware())
+	router.Use(middleware.MetricsMiddleware())
 	router.Use(middleware.CORSMiddleware(config.AppConfig.AllowedOrigins))
 	router.Use(middleware.SecurityHeaders())
 	router.Use(middleware.RateLimitMiddleware())
@@ -25,14 +40,28 @@ func SetupRouter() *gin.Engine {
 	itemHandler := handlers.NewItemHandler()
 	cartHandler := handlers.NewCartHandler()
 	orderHandler := handlers.NewOrderHandler()
+	reviewHandler := handlers.NewReviewHandler()
+	wishlistHandler := handlers.NewWishlistHandler()
+	alertHandler := handlers.NewAlertHandler()
+	notificationHandler := handlers.NewNotificationHandler()
+	healthHandler := handlers.NewHealthHandler()
+	adminHandler := handlers.NewAdminHandler()
 
-	// Health check endpoint
-	router.GET("/health", func(c *gin.Context) {
-		c.JSON(200, gin.H{
-			"status":  "healthy",
-			"message": "ShopEase API is running",
-		})
-	})
+	// Per-route rate limits, on top of the global default
+	loginLimit := middleware.RateLimit(middleware.LoginRateLimit, middleware.KeyByIP)
+	signupLimit := middleware.RateLimit(middleware.SignupRateLimit, middleware.KeyByIP)
+	browseLimit := middleware.RateLimit(middleware.BrowseRateLimit, middleware.KeyByAPIKey)
+	checkoutLimit := middleware.RateLimit(middleware.CheckoutRateLimit, middleware.KeyByUser)
+
+	// Health check endpoints
+	router.GET("/livez", healthHandler.Livez)   // GET /livez - Process is up
+	router.GET("/readyz", healthHandler.Readyz) // GET /readyz - Dependencies are healthy
+	router.GET("/health", healthHandler.Health) // GET /health - Legacy summary of /readyz
+
+	// Prometheus metrics, unless served on a separate address
+	if config.AppConfig.MetricsAddr == "" {
+		router.GET("/metrics", middleware.MetricsAuthMiddleware(), gin.WrapH(metrics.Handler()))
+	}
 
 	// API v1 group
 	api := router.Group("/api/v1")
@@ -43,31 +72,121 @@ func SetupRouter() *gin.Engine {
 		users := api.Group("/users")
 		{
 			// Public routes
-			users.POST("", userHandler.CreateUser)          // POST /users - Create user
-			users.GET("", userHandler.ListUsers)            // GET /users - List users
-			users.POST("/login", userHandler.Login)         // POST /users/login - Login
+			users.POST("", signupLimit, userHandler.CreateUser) // POST /users - Create user
+			users.GET("", userHandler.ListUsers)                // GET /users - List users
+			users.POST("/login", loginLimit, userHandler.Login) // POST /users/login - Login
+
+			// Account recovery & verification
+			users.POST("/password/forgot", loginLimit, userHandler.ForgotPassword) // POST /users/password/forgot
+			users.POST("/password/reset", userHandler.ResetPassword)               // POST /users/password/reset
+			users.POST("/email/verify", userHandler.VerifyEmail)                   // POST /users/email/verify
+			users.POST("/login/2fa", loginLimit, userHandler.LoginTwoFactor)       // POST /users/login/2fa - Second login step
 
 			// Protected routes
-			users.POST("/logout", middleware.AuthMiddleware(), userHandler.Logout)         // POST /users/logout
-			users.GET("/me", middleware.AuthMiddleware(), userHandler.GetCurrentUser)      // GET /users/me
-			users.GET("/favorites", middleware.AuthMiddleware(), userHandler.GetFavorites) // GET /users/favorites
-			users.POST("/favorites", middleware.AuthMiddleware(), userHandler.ToggleFavorite) // POST /users/favorites
+			users.POST("/logout", middleware.AuthMiddleware(), userHandler.Logout)                            // POST /users/logout
+			users.GET("/me", middleware.AuthMiddleware(), userHandler.GetCurrentUser)                         // GET /users/me
+			users.GET("/me/export", middleware.AuthMiddleware(), userHandler.ExportMyData)                    // GET /users/me/export - Data export
+			users.DELETE("/me", middleware.AuthMiddleware(), userHandler.DeleteMyAccount)                     // DELETE /users/me - Delete account
+			users.GET("/favorites", middleware.AuthMiddleware(), userHandler.GetFavorites)                    // GET /users/favorites
+			users.POST("/favorites", middleware.AuthMiddleware(), userHandler.ToggleFavorite)                 // POST /users/favorites
+			users.POST("/email/verification", middleware.AuthMiddleware(), userHandler.SendEmailVerification) // POST /users/email/verification
+
+			// Two-factor authentication
+			users.POST("/2fa/setup", middleware.AuthMiddleware(), userHandler.SetupTwoFactor)                   // POST /users/2fa/setup
+			users.POST("/2fa/confirm", middleware.AuthMiddleware(), userHandler.ConfirmTwoFactor)               // POST /users/2fa/confirm
+			users.POST("/2fa/disable", middleware.AuthMiddleware(), userHandler.DisableTwoFactor)               // POST /users/2fa/disable
+			users.POST("/2fa/recovery-codes", middleware.AuthMiddleware(), userHandler.RegenerateRecoveryCodes) // POST /users/2fa/recovery-codes
+
+			// Admin: login audit and lockout management
+			users.GET("/login-attempts", middleware.AuthMiddleware(), middleware.AdminMiddleware(), userHandler.ListLoginAttempts) // GET /users/login-attempts
+			users.POST("/:id/unlock", middleware.AuthMiddleware(), middleware.AdminMiddleware(), userHandler.UnlockUser)           // POST /users/:id/unlock
 		}
 
 		// ==================
 		// Item Routes
 		// ==================
 		items := api.Group("/items")
+		items.Use(middleware.ConditionalGetMiddleware()) // Catalogue reads answer If-None-Match with 304
 		{
 			// Public routes (anyone can view items)
-			items.GET("", itemHandler.ListItems)                // GET /items - List items
-			items.GET("/categories", itemHandler.GetCategories) // GET /items/categories
-			items.GET("/:id", itemHandler.GetItem)              // GET /items/:id
-
-			// Admin routes (no auth for simplicity, in production add admin check)
-			items.POST("", itemHandler.CreateItem)         // POST /items - Create item
-			items.PUT("/:id", itemHandler.UpdateItem)      // PUT /items/:id - Update item
-			items.DELETE("/:id", itemHandler.DeleteItem)   // DELETE /items/:id - Delete item
+			items.GET("", browseLimit, middleware.CatalogueCacheMiddleware(cache.ScopeItems), itemHandler.ListItems)        // GET /items - List items
+			items.GET("/categories", middleware.CatalogueCacheMiddleware(cache.ScopeCategories), itemHandler.GetCategories) // GET /items/categories
+			items.GET("/:id", middleware.CatalogueCacheMiddleware(cache.ScopeItem), itemHandler.GetItem)                    // GET /items/:id
+			items.GET("/:id/reviews", reviewHandler.ListItemReviews)                                                        // GET /items/:id/reviews
+
+			// Scheduled prices are only listed for staff
+			items.GET("/:id/price-history", middleware.OptionalAuthMiddleware(), itemHandler.GetPriceHistory) // GET /items/:id/price-history
+
+			// Verified buyers only
+			items.POST("/:id/reviews", middleware.AuthMiddleware(), reviewHandler.CreateReview) // POST /items/:id/reviews
+
+			// Price-drop and back-in-stock subscriptions
+			items.POST("/:id/alerts", middleware.AuthMiddleware(), alertHandler.CreateAlert) // POST /items/:id/alerts
+
+			// Admin routes (no auth for simplicity, in production add admin check).
+			// Optional auth lets the audit log name the user when a token is sent.
+			items.POST("", middleware.OptionalAuthMiddleware(), itemHandler.CreateItem)       // POST /items - Create item
+			items.PUT("/:id", middleware.OptionalAuthMiddleware(), itemHandler.UpdateItem)    // PUT /items/:id - Update item
+			items.DELETE("/:id", middleware.OptionalAuthMiddleware(), itemHandler.DeleteItem) // DELETE /items/:id - Delete item
+
+			// Price scheduling (staff)
+			items.POST("/:id/prices", middleware.AuthMiddleware(), middleware.StaffMiddleware(), itemHandler.SchedulePrice)          // POST /items/:id/prices
+			items.DELETE("/:id/prices/:priceId", middleware.AuthMiddleware(), middleware.StaffMiddleware(), itemHandler.CancelPrice) // DELETE /items/:id/prices/:priceId
+		}
+
+		// ==================
+		// Review Routes (Protected)
+		// ==================
+		reviews := api.Group("/reviews")
+		reviews.Use(middleware.AuthMiddleware())
+		{
+			reviews.POST("/:id/helpful", reviewHandler.MarkHelpful) // POST /reviews/:id/helpful
+
+			// Staff moderation
+			reviews.GET("", middleware.StaffMiddleware(), reviewHandler.ListReviews)                 // GET /reviews
+			reviews.PATCH("/:id/status", middleware.StaffMiddleware(), reviewHandler.ModerateReview) // PATCH /reviews/:id/status
+		}
+
+		// ==================
+		// Wishlist Routes
+		// ==================
+		wishlists := api.Group("/wishlists")
+		{
+			// Public read-only view via share link
+			wishlists.GET("/shared/:token", wishlistHandler.GetSharedWishlist) // GET /wishlists/shared/:token
+
+			owned := wishlists.Group("")
+			owned.Use(middleware.AuthMiddleware())
+			owned.GET("", wishlistHandler.ListWishlists)                            // GET /wishlists
+			owned.POST("", wishlistHandler.CreateWishlist)                          // POST /wishlists
+			owned.GET("/:id", wishlistHandler.GetWishlist)                          // GET /wishlists/:id
+			owned.PATCH("/:id", wishlistHandler.UpdateWishlist)                     // PATCH /wishlists/:id
+			owned.DELETE("/:id", wishlistHandler.DeleteWishlist)                    // DELETE /wishlists/:id
+			owned.POST("/:id/items", wishlistHandler.AddWishlistItem)               // POST /wishlists/:id/items
+			owned.PATCH("/:id/items/:itemId", wishlistHandler.UpdateWishlistItem)   // PATCH /wishlists/:id/items/:itemId
+			owned.DELETE("/:id/items/:itemId", wishlistHandler.RemoveWishlistItem)  // DELETE /wishlists/:id/items/:itemId
+			owned.POST("/:id/items/:itemId/move", wishlistHandler.MoveWishlistItem) // POST /wishlists/:id/items/:itemId/move
+			owned.POST("/:id/share", wishlistHandler.ShareWishlist)                 // POST /wishlists/:id/share
+			owned.DELETE("/:id/share", wishlistHandler.UnshareWishlist)             // DELETE /wishlists/:id/share
+			owned.POST("/:id/cart", wishlistHandler.MoveToCart)                     // POST /wishlists/:id/cart
+		}
+
+		// ==================
+		// Alert & Notification Routes (Protected)
+		// ==================
+		alerts := api.Group("/alerts")
+		alerts.Use(middleware.AuthMiddleware())
+		{
+			alerts.GET("", alertHandler.ListAlerts)         // GET /alerts
+			alerts.DELETE("/:id", alertHandler.DeleteAlert) // DELETE /alerts/:id
+		}
+
+		notifications := api.Group("/notifications")
+		notifications.Use(middleware.AuthMiddleware())
+		{
+			notifications.GET("", notificationHandler.ListNotifications)              // GET /notifications
+			notifications.POST("/read", notificationHandler.MarkAllNotificationsRead) // POST /notifications/read
+			notifications.POST("/:id/read", notificationHandler.MarkNotificationRead) // POST /notifications/:id/read
 		}
 
 		// ==================
@@ -76,11 +195,11 @@ func SetupRouter() *gin.Engine {
 		carts := api.Group("/carts")
 		carts.Use(middleware.AuthMiddleware())
 		{
-			carts.POST("", cartHandler.AddToCart)                // POST /carts - Add to cart
-			carts.GET("", cartHandler.ListCarts)                 // GET /carts - List all carts
-			carts.GET("/my", cartHandler.GetMyCart)              // GET /carts/my - Get my cart
-			carts.DELETE("/my", cartHandler.ClearCart)           // DELETE /carts/my - Clear cart
-			carts.PUT("/items/:id", cartHandler.UpdateCartItem)  // PUT /carts/items/:id - Update item
+			carts.POST("", cartHandler.AddToCart)                  // POST /carts - Add to cart
+			carts.GET("", cartHandler.ListCarts)                   // GET /carts - List all carts
+			carts.GET("/my", cartHandler.GetMyCart)                // GET /carts/my - Get my cart
+			carts.DELETE("/my", cartHandler.ClearCart)             // DELETE /carts/my - Clear cart
+			carts.PUT("/items/:id", cartHandler.UpdateCartItem)    // PUT /carts/items/:id - Update item
 			carts.DELETE("/items/:id", cartHandler.RemoveFromCart) // DELETE /carts/items/:id
 		}
 
@@ -90,12 +209,30 @@ func SetupRouter() *gin.Engine {
 		orders := api.Group("/orders")
 		orders.Use(middleware.AuthMiddleware())
 		{
-			orders.POST("", orderHandler.CreateOrder)              // POST /orders - Create order
-			orders.GET("", orderHandler.ListOrders)                // GET /orders - List all orders
-			orders.GET("/my", orderHandler.GetMyOrders)            // GET /orders/my - My orders
-			orders.GET("/:id", orderHandler.GetOrder)              // GET /orders/:id - Order details
-			orders.PATCH("/:id/status", orderHandler.UpdateOrderStatus) // PATCH /orders/:id/status
-			orders.POST("/:id/cancel", orderHandler.CancelOrder)   // POST /orders/:id/cancel
+			orders.POST("", checkoutLimit, orderHandler.CreateOrder)                                  // POST /orders - Create order
+			orders.GET("", orderHandler.ListOrders)                                                   // GET /orders - List all orders
+			orders.GET("/my", orderHandler.GetMyOrders)                                               // GET /orders/my - My orders
+			orders.GET("/:id", orderHandler.GetOrder)                                                 // GET /orders/:id - Order details
+			orders.PATCH("/:id/status", middleware.StaffMiddleware(), orderHandler.UpdateOrderStatus) // PATCH /orders/:id/status
+			orders.POST("/:id/cancel", orderHandler.CancelOrder)                                      // POST /orders/:id/cancel
+		}
+
+		// ==================
+		// Admin Routes (Protected)
+		// ==================
+		admin := api.Group("/admin")
+		admin.Use(middleware.AuthMiddleware(), middleware.AdminMiddleware())
+		{
+			admin.GET("/config", adminHandler.GetConfig)              // GET /admin/config - Redacted effective configuration
+			admin.POST("/items/import", adminHandler.ImportItems)     // POST /admin/items/import - Bulk upsert from CSV/NDJSON
+			admin.GET("/items/import/:id", adminHandler.GetImportJob) // GET /admin/items/import/:id - Poll a background import
+			admin.GET("/items/export", adminHandler.ExportItems)      // GET /admin/items/export - Stream the catalogue
+			admin.GET("/audit", adminHandler.ListAuditEvents)         // GET /admin/audit - Search the audit log
+			admin.GET("/audit/verify", adminHandler.VerifyAuditLog)   // GET /admin/audit/verify - Check the hash chain
+			admin.GET("/jobs", adminHandler.ListJobs)                 // GET /admin/jobs - List background jobs
+			admin.GET("/jobs/:id", adminHandler.GetJob)               // GET /admin/jobs/:id - Job details
+			admin.POST("/jobs/:id/retry", adminHandler.RetryJob)      // POST /admin/jobs/:id/retry - Run again now
+			admin.POST("/jobs/:id/cancel", adminHandler.CancelJob)    // POST /admin/jobs/:id/cancel - Cancel a queued job
 		}
 	}
 
@@ -103,25 +240,39 @@ func SetupRouter() *gin.Engine {
 	legacy := router.Group("")
 	{
 		// User routes
-		legacy.POST("/users", userHandler.CreateUser)
+		legacy.POST("/users", signupLimit, userHandler.CreateUser)
 		legacy.GET("/users", userHandler.ListUsers)
-		legacy.POST("/users/login", userHandler.Login)
+		legacy.POST("/users/login", loginLimit, userHandler.Login)
 		legacy.POST("/users/logout", middleware.AuthMiddleware(), userHandler.Logout)
 		legacy.GET("/users/favorites", middleware.AuthMiddleware(), userHandler.GetFavorites)
 		legacy.POST("/users/favorites", middleware.AuthMiddleware(), userHandler.ToggleFavorite)
 
 		// Item routes
-		legacy.POST("/items", itemHandler.CreateItem)
-		legacy.GET("/items", itemHandler.ListItems)
+		legacy.POST("/items", middleware.OptionalAuthMiddleware(), itemHandler.CreateItem)
+		legacy.GET("/items", browseLimit, middleware.ConditionalGetMiddleware(), middleware.CatalogueCacheMiddleware(cache.ScopeItems), itemHandler.ListItems)
 
 		// Cart routes (protected)
 		legacy.POST("/carts", middleware.AuthMiddleware(), cartHandler.AddToCart)
 		legacy.GET("/carts", middleware.AuthMiddleware(), cartHandler.ListCarts)
 
 		// Order routes (protected)
-		legacy.POST("/orders", middleware.AuthMiddleware(), orderHandler.CreateOrder)
+		legacy.POST("/orders", middleware.AuthMiddleware(), checkoutLimit, orderHandler.CreateOrder)
 		legacy.GET("/orders", middleware.AuthMiddleware(), orderHandler.ListOrders)
 	}
 
 	return router
 }
+
+// SetupMetricsRouter configures a router serving only /metrics, for use on a separate address
+func SetupMetricsRouter() *gin.Engine {
+	router := gin.New()
+
+	// Only believe forwarded client addresses from configured proxies; the
+	// client IP feeds rate limits, the login throttle and audit records
+	if err := router.SetTrustedProxies(config.AppConfig.TrustedProxyList()); err != nil {
+		log.Printf("Error setting trusted proxies: %v", err)
+	}
+	router.Use(gin.Recovery())
+	router.GET("/metrics", middleware.MetricsAuthMiddleware(), gin.WrapH(metrics.Handler()))
+	return router
+}
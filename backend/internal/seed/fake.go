@@ -0,0 +1,98 @@
+package seed
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"strings"
+
+	"shopease/internal/models"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// FakePassword is the password of every generated user
+const FakePassword = "password123"
+
+// FakeOptions size a generated data set. The same options always produce
+// the same fixture, so load tests can be repeated against identical data.
+type FakeOptions struct {
+	Seed   int64
+	Users  int
+	Items  int
+	Orders int
+}
+
+var (
+	fakeCategories  = []string{"Electronics", "Accessories", "Home", "Office", "Outdoors", "Kitchen"}
+	fakeAdjectives  = []string{"Compact", "Deluxe", "Eco", "Ergonomic", "Portable", "Smart", "Vintage", "Wireless", "Heavy-Duty", "Minimal"}
+	fakeNouns       = []string{"Lamp", "Speaker", "Backpack", "Kettle", "Chair", "Charger", "Notebook", "Camera", "Blender", "Tent", "Monitor", "Clock"}
+	fakeOrderStatus = []models.OrderStatus{
+		models.OrderStatusDelivered, models.OrderStatusDelivered, models.OrderStatusDelivered,
+		models.OrderStatusShipped, models.OrderStatusConfirmed, models.OrderStatusPending, models.OrderStatusCancelled,
+	}
+)
+
+// Fake generates users, items and orders for load testing. Generated records
+// use their own SKU, username and ref prefixes, so they never collide with
+// the fixture files and re-applying them is idempotent.
+func Fake(opts FakeOptions) (*Fixture, error) {
+	if opts.Users < 0 || opts.Items < 0 || opts.Orders < 0 {
+		return nil, fmt.Errorf("fake data counts must not be negative")
+	}
+	if opts.Orders > 0 && (opts.Users == 0 || opts.Items == 0) {
+		return nil, fmt.Errorf("fake orders need fake users and items")
+	}
+
+	rng := rand.New(rand.NewSource(opts.Seed))
+	fixture := &Fixture{Version: FixtureVersion, Categories: fakeCategories}
+
+	if opts.Users > 0 {
+		// One hash for everyone; bcrypt per user would dominate the run time
+		hash, err := bcrypt.GenerateFromPassword([]byte(FakePassword), bcrypt.DefaultCost)
+		if err != nil {
+			return nil, err
+		}
+		for i := 1; i <= opts.Users; i++ {
+			username := fmt.Sprintf("fake-user-%05d", i)
+			fixture.Users = append(fixture.Users, UserFixture{
+				Username:      username,
+				Email:         username + "@example.com",
+				PasswordHash:  string(hash),
+				EmailVerified: rng.Intn(4) > 0,
+			})
+		}
+	}
+
+	for i := 1; i <= opts.Items; i++ {
+		active := rng.Intn(20) > 0
+		adjective := fakeAdjectives[rng.Intn(len(fakeAdjectives))]
+		noun := fakeNouns[rng.Intn(len(fakeNouns))]
+		fixture.Items = append(fixture.Items, ItemFixture{
+			SKU:         fmt.Sprintf("FAKE-%06d", i),
+			Name:        fmt.Sprintf("%s %s %d", adjective, noun, i),
+			Description: fmt.Sprintf("A %s %s for everyday use", strings.ToLower(adjective), strings.ToLower(noun)),
+			Price:       math.Round((4.99+rng.Float64()*495)*100) / 100,
+			Category:    fakeCategories[rng.Intn(len(fakeCategories))],
+			IsActive:    &active,
+		})
+	}
+
+	for i := 1; i <= opts.Orders; i++ {
+		order := OrderFixture{
+			Ref:     fmt.Sprintf("fake-%06d", i),
+			User:    fixture.Users[rng.Intn(len(fixture.Users))].Username,
+			Status:  fakeOrderStatus[rng.Intn(len(fakeOrderStatus))],
+			DaysAgo: rng.Intn(365),
+		}
+		for lines := 1 + rng.Intn(4); lines > 0; lines-- {
+			order.Items = append(order.Items, OrderLine{
+				SKU:      fixture.Items[rng.Intn(len(fixture.Items))].SKU,
+				Quantity: 1 + rng.Intn(3),
+			})
+		}
+		fixture.Orders = append(fixture.Orders, order)
+	}
+
+	return fixture, nil
+}
@@ -0,0 +1,113 @@
+package seed
+
+import (
+	"context"
+	"embed"
+	"fmt"
+	"log"
+	"sort"
+
+	"shopease/internal/database"
+	"shopease/internal/models"
+)
+
+//go:embed fixtures/*.yaml
+var fixtureFiles embed.FS
+
+// Profile is a named set of fixture files, optionally followed by generated data
+type Profile struct {
+	Name        string
+	Description string
+	Files       []string // Under fixtures/, applied in order
+	Fake        *FakeOptions
+}
+
+// Profiles are the built-in seed profiles
+var Profiles = map[string]Profile{
+	"test": {
+		Name:        "test",
+		Description: "The starter catalogue only; safe for any environment",
+		Files:       []string{"catalog.yaml"},
+	},
+	"dev": {
+		Name:        "dev",
+		Description: "The catalogue plus well-known admin, staff and customer accounts with sample orders",
+		Files:       []string{"catalog.yaml", "accounts.yaml", "orders.yaml"},
+	},
+	"demo": {
+		Name:        "demo",
+		Description: "Everything in dev plus a few hundred generated items and orders",
+		Files:       []string{"catalog.yaml", "accounts.yaml", "orders.yaml"},
+		Fake:        &FakeOptions{Seed: 1, Users: 25, Items: 200, Orders: 400},
+	},
+}
+
+// ProfileNames lists the built-in profiles alphabetically
+func ProfileNames() []string {
+	names := make([]string, 0, len(Profiles))
+	for name := range Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Fixtures loads the profile's fixture files and generates its fake data
+func (p Profile) Fixtures() ([]*Fixture, error) {
+	var fixtures []*Fixture
+	for _, name := range p.Files {
+		data, err := fixtureFiles.ReadFile("fixtures/" + name)
+		if err != nil {
+			return nil, err
+		}
+		fixture, err := Parse(data)
+		if err != nil {
+			return nil, fmt.Errorf("fixture %s: %w", name, err)
+		}
+		fixtures = append(fixtures, fixture)
+	}
+
+	if p.Fake != nil {
+		fixture, err := Fake(*p.Fake)
+		if err != nil {
+			return nil, err
+		}
+		fixtures = append(fixtures, fixture)
+	}
+	return fixtures, nil
+}
+
+// ApplyProfile applies the named built-in profile
+func ApplyProfile(ctx context.Context, name string) (*Result, error) {
+	profile, ok := Profiles[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown seed profile %q (want one of %v)", name, ProfileNames())
+	}
+
+	fixtures, err := profile.Fixtures()
+	if err != nil {
+		return nil, err
+	}
+	return Apply(ctx, fixtures...)
+}
+
+// Bootstrap applies the named profile to a database with no items, as on
+// first start. It does nothing if the profile is "none" or the catalogue
+// already has items, so restarts never overwrite later edits.
+func Bootstrap(ctx context.Context, name string) (*Result, error) {
+	if name == "none" {
+		return nil, nil
+	}
+
+	var count int64
+	if err := database.DB.WithContext(ctx).Unscoped().Model(&models.Item{}).Count(&count).Error; err != nil {
+		return nil, err
+	}
+	if count > 0 {
+		log.Println("Items already exist, skipping seed")
+		return nil, nil
+	}
+
+	log.Printf("Seeding the %s profile...", name)
+	return ApplyProfile(ctx, name)
+}
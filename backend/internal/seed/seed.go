@@ -0,0 +1,379 @@
+// Package seed loads users, items and orders from fixture files. Fixtures are
+// applied as idempotent upserts, so a profile can be re-applied to bring a
+// database back to its seeded state without duplicating anything.
+package seed
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"shopease/internal/audit"
+	"shopease/internal/database"
+	"shopease/internal/models"
+	"shopease/internal/pricing"
+
+	"gopkg.in/yaml.v3"
+	"gorm.io/gorm"
+)
+
+// Actor is recorded in the audit log when fixtures are applied
+var Actor = audit.System("seed")
+
+// FixtureVersion is the newest fixture format this build understands
+const FixtureVersion = 1
+
+// Fixture is the contents of one fixture file
+type Fixture struct {
+	Version    int            `yaml:"version"`
+	Categories []string       `yaml:"categories"`
+	Users      []UserFixture  `yaml:"users"`
+	Items      []ItemFixture  `yaml:"items"`
+	Orders     []OrderFixture `yaml:"orders"`
+}
+
+// UserFixture is a user account, matched by username
+type UserFixture struct {
+	Username      string          `yaml:"username"`
+	Email         string          `yaml:"email"`
+	Password      string          `yaml:"password"`      // Only used when the account is created
+	PasswordHash  string          `yaml:"password_hash"` // A bcrypt hash, used instead of Password if set
+	Role          models.UserRole `yaml:"role"`
+	EmailVerified bool            `yaml:"email_verified"`
+}
+
+// ItemFixture is a catalogue item, matched by SKU
+type ItemFixture struct {
+	SKU         string  `yaml:"sku"`
+	Name        string  `yaml:"name"`
+	Description string  `yaml:"description"`
+	Price       float64 `yaml:"price"`
+	ImageURL    string  `yaml:"image_url"`
+	Category    string  `yaml:"category"`
+	IsActive    *bool   `yaml:"is_active"` // Defaults to true
+}
+
+// OrderFixture is a past order, matched by ref. Lines are priced at the
+// item's current price when the order is created.
+type OrderFixture struct {
+	Ref     string             `yaml:"ref"`
+	User    string             `yaml:"user"`
+	Status  models.OrderStatus `yaml:"status"` // Defaults to pending
+	Note    string             `yaml:"note"`
+	DaysAgo int                `yaml:"days_ago"`
+	Items   []OrderLine        `yaml:"items"`
+}
+
+// OrderLine is one item in an OrderFixture
+type OrderLine struct {
+	SKU      string `yaml:"sku"`
+	Quantity int    `yaml:"quantity"`
+}
+
+// Result counts what applying fixtures changed
+type Result struct {
+	UsersCreated  int `json:"users_created"`
+	UsersUpdated  int `json:"users_updated"`
+	ItemsCreated  int `json:"items_created"`
+	ItemsUpdated  int `json:"items_updated"`
+	OrdersCreated int `json:"orders_created"`
+}
+
+func (r Result) String() string {
+	return fmt.Sprintf("users: %d created, %d updated; items: %d created, %d updated; orders: %d created",
+		r.UsersCreated, r.UsersUpdated, r.ItemsCreated, r.ItemsUpdated, r.OrdersCreated)
+}
+
+// LoadFile reads a YAML or JSON fixture file
+func LoadFile(path string) (*Fixture, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	fixture, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("fixture %s: %w", filepath.Base(path), err)
+	}
+	return fixture, nil
+}
+
+// Parse decodes a fixture, rejecting unknown keys. JSON is accepted as it is
+// valid YAML.
+func Parse(data []byte) (*Fixture, error) {
+	var fixture Fixture
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&fixture); err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+
+	switch {
+	case fixture.Version == 0:
+		return nil, errors.New("missing version")
+	case fixture.Version > FixtureVersion:
+		return nil, fmt.Errorf("version %d is newer than this build supports (%d)", fixture.Version, FixtureVersion)
+	}
+	return &fixture, nil
+}
+
+// Apply upserts the fixtures, in order, in one transaction. Users are matched
+// by username and items by SKU (or, for items seeded before SKUs existed, by
+// name). Existing orders and existing users' passwords are left alone.
+func Apply(ctx context.Context, fixtures ...*Fixture) (*Result, error) {
+	if err := validate(fixtures); err != nil {
+		return nil, err
+	}
+
+	result := &Result{}
+	err := database.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for _, fixture := range fixtures {
+			for _, user := range fixture.Users {
+				if err := upsertUser(tx, user, result); err != nil {
+					return fmt.Errorf("user %s: %w", user.Username, err)
+				}
+			}
+			for _, item := range fixture.Items {
+				if err := upsertItem(tx, item, result); err != nil {
+					return fmt.Errorf("item %s: %w", item.SKU, err)
+				}
+			}
+			for _, order := range fixture.Orders {
+				if err := createOrder(tx, order, result); err != nil {
+					return fmt.Errorf("order %s: %w", order.Ref, err)
+				}
+			}
+		}
+		return audit.Record(tx, Actor, audit.Event{
+			Action: audit.ActionSeedApplied,
+			Details: map[string]interface{}{
+				"users_created":  result.UsersCreated,
+				"users_updated":  result.UsersUpdated,
+				"items_created":  result.ItemsCreated,
+				"items_updated":  result.ItemsUpdated,
+				"orders_created": result.OrdersCreated,
+			},
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// validate checks the fixtures as a set, so a later file can use categories
+// declared in an earlier one
+func validate(fixtures []*Fixture) error {
+	categories := map[string]bool{}
+	for _, fixture := range fixtures {
+		for _, category := range fixture.Categories {
+			categories[category] = true
+		}
+	}
+
+	users, items, orders := map[string]bool{}, map[string]bool{}, map[string]bool{}
+	for _, fixture := range fixtures {
+		for _, user := range fixture.Users {
+			switch {
+			case len(user.Username) < 3:
+				return fmt.Errorf("user %q: username must be at least 3 characters", user.Username)
+			case user.Password == "" && user.PasswordHash == "":
+				return fmt.Errorf("user %s: password or password_hash is required", user.Username)
+			case user.Role != "" && user.Role != models.UserRoleCustomer && user.Role != models.UserRoleStaff && user.Role != models.UserRoleAdmin:
+				return fmt.Errorf("user %s: unknown role %q", user.Username, user.Role)
+			case users[user.Username]:
+				return fmt.Errorf("user %s: listed twice", user.Username)
+			}
+			users[user.Username] = true
+		}
+
+		for _, item := range fixture.Items {
+			switch {
+			case item.SKU == "":
+				return fmt.Errorf("item %q: sku is required", item.Name)
+			case item.Name == "":
+				return fmt.Errorf("item %s: name is required", item.SKU)
+			case item.Price < 0:
+				return fmt.Errorf("item %s: price must not be negative", item.SKU)
+			case len(categories) > 0 && !categories[item.Category]:
+				return fmt.Errorf("item %s: category %q is not declared", item.SKU, item.Category)
+			case items[item.SKU]:
+				return fmt.Errorf("item %s: listed twice", item.SKU)
+			}
+			items[item.SKU] = true
+		}
+
+		for _, order := range fixture.Orders {
+			switch {
+			case order.Ref == "":
+				return errors.New("order without a ref")
+			case order.User == "":
+				return fmt.Errorf("order %s: user is required", order.Ref)
+			case len(order.Items) == 0:
+				return fmt.Errorf("order %s: at least one item is required", order.Ref)
+			case orders[order.Ref]:
+				return fmt.Errorf("order %s: listed twice", order.Ref)
+			}
+			if order.Status != "" {
+				if _, ok := models.ParseOrderStatus(string(order.Status)); !ok {
+					return fmt.Errorf("order %s: unknown status %q", order.Ref, order.Status)
+				}
+			}
+			for _, line := range order.Items {
+				if line.SKU == "" || line.Quantity < 1 {
+					return fmt.Errorf("order %s: each line needs a sku and a positive quantity", order.Ref)
+				}
+			}
+			orders[order.Ref] = true
+		}
+	}
+	return nil
+}
+
+func upsertUser(tx *gorm.DB, fixture UserFixture, result *Result) error {
+	role := fixture.Role
+	if role == "" {
+		role = models.UserRoleCustomer
+	}
+
+	var user models.User
+	err := tx.Where("username = ?", fixture.Username).First(&user).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+	exists := err == nil
+
+	user.Username = fixture.Username
+	user.Email = fixture.Email
+	user.Role = role
+	switch {
+	case !fixture.EmailVerified || fixture.Email == "":
+		user.EmailVerifiedAt = nil
+	case user.EmailVerifiedAt == nil:
+		now := time.Now()
+		user.EmailVerifiedAt = &now
+	}
+
+	if exists {
+		result.UsersUpdated++
+		return tx.Save(&user).Error
+	}
+
+	result.UsersCreated++
+	if fixture.PasswordHash != "" {
+		// Skip BeforeCreate, which would hash the hash
+		user.Password = fixture.PasswordHash
+		return tx.Session(&gorm.Session{SkipHooks: true}).Create(&user).Error
+	}
+	user.Password = fixture.Password // Hashed by BeforeCreate
+	return tx.Create(&user).Error
+}
+
+func upsertItem(tx *gorm.DB, fixture ItemFixture, result *Result) error {
+	var item models.Item
+	err := tx.Where("sku = ?", fixture.SKU).First(&item).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		err = tx.Where("sku IS NULL AND name = ?", fixture.Name).First(&item).Error
+	}
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+	exists := err == nil
+
+	active := fixture.IsActive == nil || *fixture.IsActive
+	item.SetSKU(fixture.SKU)
+	item.Name = fixture.Name
+	item.Description = fixture.Description
+	item.Price = fixture.Price
+	item.ImageURL = fixture.ImageURL
+	item.Category = fixture.Category
+	item.IsActive = active
+
+	if exists {
+		result.ItemsUpdated++
+		if err := models.ClaimVersion(tx, &item, item.Version); err != nil {
+			return err
+		}
+		if err := tx.Omit(models.ItemRatingColumns...).Save(&item).Error; err != nil {
+			return err
+		}
+		return pricing.SetRegular(tx, &item, fixture.Price, time.Now())
+	}
+
+	result.ItemsCreated++
+	if err := tx.Create(&item).Error; err != nil {
+		return err
+	}
+	if err := pricing.SetRegular(tx, &item, item.Price, item.CreatedAt); err != nil {
+		return err
+	}
+	// Create applies the column default, turning a false IsActive into true
+	if !active {
+		return tx.Model(&item).Update("is_active", false).Error
+	}
+	return nil
+}
+
+func createOrder(tx *gorm.DB, fixture OrderFixture, result *Result) error {
+	var count int64
+	if err := tx.Model(&models.Order{}).Where("reference = ?", fixture.Ref).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return nil
+	}
+
+	var user models.User
+	if err := tx.Where("username = ?", fixture.User).First(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return fmt.Errorf("unknown user %q", fixture.User)
+		}
+		return err
+	}
+
+	status := fixture.Status
+	if status == "" {
+		status = models.OrderStatusPending
+	}
+	ref := fixture.Ref
+	placedAt := time.Now().AddDate(0, 0, -fixture.DaysAgo)
+	order := models.Order{
+		UserID:    user.ID,
+		Status:    status,
+		Note:      fixture.Note,
+		Reference: &ref,
+		CreatedAt: placedAt,
+		UpdatedAt: placedAt,
+	}
+
+	for _, line := range fixture.Items {
+		var item models.Item
+		if err := tx.Where("sku = ?", line.SKU).First(&item).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return fmt.Errorf("unknown item %q", line.SKU)
+			}
+			return err
+		}
+
+		subtotal := item.Price * float64(line.Quantity)
+		order.TotalAmount += subtotal
+		order.OrderItems = append(order.OrderItems, models.OrderItem{
+			ItemID:    item.ID,
+			ItemName:  item.Name,
+			ItemPrice: item.Price,
+			Quantity:  line.Quantity,
+			Subtotal:  subtotal,
+			CreatedAt: placedAt,
+		})
+	}
+
+	if err := tx.Create(&order).Error; err != nil {
+		return err
+	}
+	result.OrdersCreated++
+	return nil
+}
@@ -0,0 +1,99 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"shopease/internal/config"
+)
+
+// New creates an http.Server for handler using the timeouts from config.
+// Zero or negative timeouts leave the corresponding limit disabled.
+func New(addr string, handler http.Handler) *http.Server {
+	return &http.Server{
+		Addr:              addr,
+		Handler:           handler,
+		ReadTimeout:       seconds(config.AppConfig.HTTPReadTimeout),
+		ReadHeaderTimeout: seconds(config.AppConfig.HTTPReadHeaderTimeout),
+		WriteTimeout:      seconds(config.AppConfig.HTTPWriteTimeout),
+		IdleTimeout:       seconds(config.AppConfig.HTTPIdleTimeout),
+	}
+}
+
+// ShutdownTimeout returns the configured drain deadline for in-flight requests
+func ShutdownTimeout() time.Duration {
+	return seconds(config.AppConfig.ShutdownTimeout)
+}
+
+// Serve accepts connections on ln until ctx is cancelled, then stops accepting
+// new ones and waits up to drain for in-flight requests to complete. Connections
+// still open after the deadline are closed forcibly and an error is returned.
+// A zero drain waits indefinitely.
+//
+// Serve only returns once every handler it started has returned, so callers
+// can safely release resources such as the database afterwards. Handlers cut
+// off by a forced close see their request context cancelled.
+func Serve(ctx context.Context, srv *http.Server, ln net.Listener, drain time.Duration) error {
+	var active sync.WaitGroup
+	next := srv.Handler
+	if next == nil {
+		next = http.DefaultServeMux
+	}
+	srv.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		active.Add(1)
+		defer active.Done()
+		next.ServeHTTP(w, r)
+	})
+	defer active.Wait()
+
+	served := make(chan error, 1)
+	go func() {
+		served <- srv.Serve(ln)
+	}()
+
+	select {
+	case err := <-served:
+		// The listener failed before we were asked to stop
+		return err
+	case <-ctx.Done():
+	}
+
+	shutdownCtx := context.Background()
+	if drain > 0 {
+		var cancel context.CancelFunc
+		shutdownCtx, cancel = context.WithTimeout(shutdownCtx, drain)
+		defer cancel()
+	}
+
+	err := srv.Shutdown(shutdownCtx)
+	if err != nil {
+		srv.Close()
+		err = fmt.Errorf("draining connections on %s: %w", ln.Addr(), err)
+	}
+
+	if serveErr := <-served; !errors.Is(serveErr, http.ErrServerClosed) && err == nil {
+		err = serveErr
+	}
+	return err
+}
+
+// ListenAndServe listens on srv.Addr and calls Serve
+func ListenAndServe(ctx context.Context, srv *http.Server, drain time.Duration) error {
+	ln, err := net.Listen("tcp", srv.Addr)
+	if err != nil {
+		return err
+	}
+	return Serve(ctx, srv, ln, drain)
+}
+
+func seconds(n int) time.Duration {
+	if n <= 0 {
+		return 0
+	}
+	return time.Duration(n) * time.Second
+}
@@ -9,20 +9,40 @@ import (
 	"github.com/golang-jwt/jwt/v5"
 )
 
+// Token purposes other than a normal session
+const (
+	TokenPurposeTwoFactor = "2fa" // Short-lived token proving the password step of a two-step login
+)
+
+// TwoFactorChallengeTTL is how long a user has to enter their second factor after the password
+const TwoFactorChallengeTTL = 5 * time.Minute
+
 // Claims represents the JWT claims
 type Claims struct {
 	UserID   uint   `json:"user_id"`
 	Username string `json:"username"`
+	Purpose  string `json:"purpose,omitempty"` // Empty for session tokens
 	jwt.RegisteredClaims
 }
 
 // GenerateToken generates a new JWT token for a user
 func GenerateToken(userID uint, username string) (string, error) {
-	expirationTime := time.Now().Add(time.Duration(config.AppConfig.JWTExpiryHours) * time.Hour)
+	return generateToken(userID, username, "", time.Duration(config.AppConfig.JWTExpiryHours)*time.Hour)
+}
+
+// GenerateChallengeToken generates a short-lived token for completing a two-factor login
+func GenerateChallengeToken(userID uint, username string) (string, error) {
+	return generateToken(userID, username, TokenPurposeTwoFactor, TwoFactorChallengeTTL)
+}
+
+// generateToken signs a token with the given purpose and lifetime
+func generateToken(userID uint, username, purpose string, ttl time.Duration) (string, error) {
+	expirationTime := time.Now().Add(ttl)
 
 	claims := &Claims{
 		UserID:   userID,
 		Username: username,
+		Purpose:  purpose,
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(expirationTime),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
@@ -42,8 +62,18 @@ func GenerateToken(userID uint, username string) (string, error) {
 	return tokenString, nil
 }
 
-// ValidateToken validates a JWT token and returns the claims
+// ValidateToken validates a session JWT token and returns the claims
 func ValidateToken(tokenString string) (*Claims, error) {
+	return validateToken(tokenString, "")
+}
+
+// ValidateChallengeToken validates a two-factor challenge token and returns the claims
+func ValidateChallengeToken(tokenString string) (*Claims, error) {
+	return validateToken(tokenString, TokenPurposeTwoFactor)
+}
+
+// validateToken parses a token and checks it was issued for the expected purpose
+func validateToken(tokenString, purpose string) (*Claims, error) {
 	claims := &Claims{}
 
 	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
@@ -61,6 +91,10 @@ func ValidateToken(tokenString string) (*Claims, error) {
 		return nil, errors.New("invalid token")
 	}
 
+	if claims.Purpose != purpose {
+		return nil, errors.New("token issued for a different purpose")
+	}
+
 	return claims, nil
 }
 
@@ -39,6 +39,15 @@ func ErrorResponse(c *gin.Context, statusCode int, message string) {
 	})
 }
 
+// ErrorDataResponse sends an error response with data the client needs to recover from it
+func ErrorDataResponse(c *gin.Context, statusCode int, message string, data interface{}) {
+	c.JSON(statusCode, Response{
+		Success: false,
+		Error:   message,
+		Data:    data,
+	})
+}
+
 // ValidationErrorResponse sends a validation error response
 func ValidationErrorResponse(c *gin.Context, message string, errors interface{}) {
 	c.JSON(400, gin.H{
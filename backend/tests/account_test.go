@@ -0,0 +1,107 @@
+package tests
+
+import (
+	"net/http"
+
+	"shopease/internal/audit"
+	"shopease/internal/config"
+	"shopease/internal/database"
+	"shopease/internal/jobs"
+	"shopease/internal/models"
+	"shopease/internal/notify"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Account recovery", func() {
+	var mailer *captureMailer
+	var username, email, token string
+
+	BeforeEach(func() {
+		mailer = &captureMailer{}
+		notify.SetMailer(mailer)
+		jobs.Start(1)
+
+		username = uniqueName("recover")
+		email = username + "@example.com"
+
+		w := doRequest("POST", "/api/v1/users", map[string]string{
+			"username": username,
+			"password": "password123",
+			"email":    email,
+		}, "")
+		Expect(w.Code).To(Equal(http.StatusCreated))
+
+		w = doRequest("POST", "/api/v1/users/login", map[string]string{
+			"username": username,
+			"password": "password123",
+		}, "")
+		token = decodeData(w)["token"].(string)
+	})
+
+	AfterEach(func() {
+		jobs.Stop()
+		notify.SetMailer(notify.LogMailer{})
+		config.AppConfig.RequireVerifiedEmail = false
+	})
+
+	Describe("POST /users/password/reset", func() {
+		It("should reset the password once and revoke the active session", func() {
+			w := doRequest("POST", "/api/v1/users/password/forgot", map[string]string{"username": username}, "")
+			Expect(w.Code).To(Equal(http.StatusOK))
+			code := mailer.tokenFor(email, "Reset your ShopEase password")
+
+			w = doRequest("POST", "/api/v1/users/password/reset", map[string]string{
+				"token":    code,
+				"password": "newpassword456",
+			}, "")
+			Expect(w.Code).To(Equal(http.StatusOK), w.Body.String())
+
+			w = doRequest("GET", "/api/v1/users/me", nil, token)
+			Expect(w.Code).To(Equal(http.StatusUnauthorized))
+
+			w = doRequest("POST", "/api/v1/users/password/reset", map[string]string{
+				"token":    code,
+				"password": "anotherpassword",
+			}, "")
+			Expect(w.Code).To(Equal(http.StatusBadRequest))
+
+			w = doRequest("POST", "/api/v1/users/login", map[string]string{
+				"username": username,
+				"password": "newpassword456",
+			}, "")
+			Expect(w.Code).To(Equal(http.StatusOK))
+		})
+
+		It("should not reveal whether an account exists", func() {
+			w := doRequest("POST", "/api/v1/users/password/forgot", map[string]string{"username": "nobody-here"}, "")
+			Expect(w.Code).To(Equal(http.StatusOK))
+		})
+	})
+
+	Describe("POST /users/email/verify", func() {
+		It("should gate checkout until the email is verified", func() {
+			config.AppConfig.RequireVerifiedEmail = true
+
+			w := doRequest("POST", "/api/v1/carts", map[string]interface{}{"item_id": 1}, token)
+			Expect(w.Code).To(Equal(http.StatusOK))
+			cartID := decodeData(w)["id"]
+
+			w = doRequest("POST", "/api/v1/orders", map[string]interface{}{"cart_id": cartID}, token)
+			Expect(w.Code).To(Equal(http.StatusForbidden))
+
+			code := mailer.tokenFor(email, "Confirm your email address")
+			w = doRequest("POST", "/api/v1/users/email/verify", map[string]string{"token": code}, "")
+			Expect(w.Code).To(Equal(http.StatusOK), w.Body.String())
+			Expect(decodeData(w)["email_verified"]).To(BeTrue())
+
+			var verified models.AuditEvent
+			Expect(database.DB.Where("action = ? AND actor_name = ?", audit.ActionEmailVerified, username).First(&verified).Error).To(Succeed())
+			Expect(string(verified.Details)).NotTo(ContainSubstring(email))
+
+			w = doRequest("POST", "/api/v1/orders", map[string]interface{}{"cart_id": cartID}, token)
+			Expect(w.Code).To(Equal(http.StatusCreated))
+		})
+	})
+})
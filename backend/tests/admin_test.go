@@ -0,0 +1,89 @@
+package tests
+
+import (
+	"context"
+	"net/http"
+
+	"shopease/internal/admin"
+	"shopease/internal/audit"
+	"shopease/internal/database"
+	"shopease/internal/models"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Admin tasks", func() {
+	login := func(username, password string) int {
+		return doRequest("POST", "/api/v1/users/login", map[string]string{"username": username, "password": password}, "").Code
+	}
+
+	It("should create admins that can use admin endpoints", func() {
+		username := uniqueName("root")
+		user, err := admin.CreateAdmin(username, username+"@example.com", "hunter22")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(user.IsAdmin()).To(BeTrue())
+		Expect(user.IsEmailVerified()).To(BeTrue())
+
+		var created models.AuditEvent
+		Expect(database.DB.Where("action = ? AND target_id = ?", audit.ActionAdminCreated, user.ID).First(&created).Error).To(Succeed())
+		Expect(string(created.Changes)).To(ContainSubstring("email_verified"))
+		Expect(string(created.Changes) + string(created.Details)).NotTo(ContainSubstring("@example.com"))
+
+		_, err = admin.CreateAdmin(username, "", "hunter22")
+		Expect(err).To(MatchError(ContainSubstring("already exists")))
+		_, err = admin.CreateAdmin(uniqueName("root"), "", "short")
+		Expect(err).To(HaveOccurred())
+
+		w := doRequest("POST", "/api/v1/users/login", map[string]string{"username": username, "password": "hunter22"}, "")
+		Expect(w.Code).To(Equal(http.StatusOK))
+		Expect(doRequest("GET", "/api/v1/admin/config", nil, decodeData(w)["token"].(string)).Code).To(Equal(http.StatusOK))
+	})
+
+	It("should reset sessions and passwords", func() {
+		username := uniqueName("locked")
+		token, _ := registerAndLogin(username)
+
+		Expect(admin.ResetSession(username)).To(Succeed())
+		Expect(doRequest("GET", "/api/v1/users/me", nil, token).Code).To(Equal(http.StatusUnauthorized))
+		Expect(admin.ResetSession(uniqueName("ghost"))).To(MatchError(admin.ErrUserNotFound))
+
+		Expect(admin.ResetPassword(username, "brand-new-pass")).To(Succeed())
+		Expect(login(username, "password123")).To(Equal(http.StatusBadRequest))
+		Expect(login(username, "brand-new-pass")).To(Equal(http.StatusOK))
+	})
+
+	It("should list orders and change their status", func() {
+		username := uniqueName("buyer")
+		token, _ := registerAndLogin(username)
+		orderID := placeOrder(token, 1, 2)
+
+		orders, err := admin.ListOrders(admin.OrderFilter{Username: username})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(orders).To(HaveLen(1))
+		Expect(orders[0].ID).To(Equal(orderID))
+		Expect(orders[0].User.Username).To(Equal(username))
+
+		order, err := admin.SetOrderStatus(orderID, "shipped")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(order.Status).To(Equal(models.OrderStatusShipped))
+
+		orders, err = admin.ListOrders(admin.OrderFilter{Username: username, Status: models.OrderStatusPending})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(orders).To(BeEmpty())
+
+		_, err = admin.SetOrderStatus(orderID, "teleported")
+		Expect(err).To(HaveOccurred())
+		_, err = admin.SetOrderStatus(999999, "shipped")
+		Expect(err).To(MatchError(admin.ErrOrderNotFound))
+	})
+
+	It("should report schema status", func() {
+		status, err := admin.GetSchemaStatus(context.Background())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(status.UpToDate()).To(BeTrue())
+		Expect(status.Applied).To(HaveLen(1))
+		Expect(status.Tables).To(HaveKey("items"))
+		Expect(status.TableNames()).To(ContainElement("schema_migrations"))
+	})
+})
@@ -0,0 +1,77 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	_ "shopease/internal/alerts" // Subscribes to the item events dispatched below
+	"shopease/internal/events"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Item Alerts", func() {
+	var token string
+	var itemID uint
+
+	notificationCount := func() int {
+		w := doRequest("GET", "/api/v1/notifications", nil, token)
+		Expect(w.Code).To(Equal(http.StatusOK))
+
+		var response map[string]interface{}
+		Expect(json.Unmarshal(w.Body.Bytes(), &response)).To(Succeed())
+		return int(response["total_items"].(float64))
+	}
+
+	updateItem := func(changes map[string]interface{}) {
+		w := doRequest("PUT", fmt.Sprintf("/api/v1/items/%d", itemID), changes, "")
+		Expect(w.Code).To(Equal(http.StatusOK))
+		_, err := events.Dispatch(context.Background())
+		Expect(err).NotTo(HaveOccurred())
+	}
+
+	BeforeEach(func() {
+		token, _ = registerAndLogin(uniqueName("watcher"))
+
+		w := doRequest("POST", "/api/v1/items", map[string]interface{}{
+			"name":  "Watched Item",
+			"price": 100.0,
+		}, "")
+		Expect(w.Code).To(Equal(http.StatusCreated))
+		itemID = uint(decodeData(w)["id"].(float64))
+	})
+
+	It("should notify once when the price drops below the target", func() {
+		w := doRequest("POST", fmt.Sprintf("/api/v1/items/%d/alerts", itemID), map[string]interface{}{
+			"type":         "price_drop",
+			"target_price": 80.0,
+		}, token)
+		Expect(w.Code).To(Equal(http.StatusCreated))
+
+		updateItem(map[string]interface{}{"price": 90.0})
+		Expect(notificationCount()).To(Equal(0))
+
+		updateItem(map[string]interface{}{"price": 75.0})
+		Expect(notificationCount()).To(Equal(1))
+
+		// Bouncing back up and down to the same price is not news
+		updateItem(map[string]interface{}{"price": 95.0})
+		updateItem(map[string]interface{}{"price": 75.0})
+		Expect(notificationCount()).To(Equal(1))
+	})
+
+	It("should notify when an inactive item is reactivated", func() {
+		updateItem(map[string]interface{}{"is_active": false})
+
+		w := doRequest("POST", fmt.Sprintf("/api/v1/items/%d/alerts", itemID), map[string]interface{}{
+			"type": "back_in_stock",
+		}, token)
+		Expect(w.Code).To(Equal(http.StatusCreated))
+
+		updateItem(map[string]interface{}{"is_active": true})
+		Expect(notificationCount()).To(Equal(1))
+	})
+})
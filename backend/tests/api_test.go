@@ -2,6 +2,7 @@ package tests
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
@@ -10,6 +11,7 @@ import (
 	"shopease/internal/config"
 	"shopease/internal/database"
 	"shopease/internal/routes"
+	"shopease/internal/seed"
 
 	"github.com/gin-gonic/gin"
 	. "github.com/onsi/ginkgo/v2"
@@ -44,7 +46,7 @@ var _ = BeforeSuite(func() {
 	Expect(err).NotTo(HaveOccurred())
 
 	// Seed test items
-	err = database.SeedItems()
+	_, err = seed.ApplyProfile(context.Background(), "test")
 	Expect(err).NotTo(HaveOccurred())
 
 	// Setup router
@@ -0,0 +1,127 @@
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"shopease/internal/audit"
+	"shopease/internal/database"
+	"shopease/internal/models"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Audit log", func() {
+	var (
+		adminToken string
+		adminID    uint
+		adminName  string
+	)
+
+	BeforeEach(func() {
+		adminName = uniqueName("auditor")
+		adminToken, adminID = registerWithRole(adminName, models.UserRoleAdmin)
+	})
+
+	// listEvents queries GET /admin/audit and returns the matching events
+	listEvents := func(query url.Values) []models.AuditEvent {
+		w := doRequest("GET", "/api/v1/admin/audit?"+query.Encode(), nil, adminToken)
+		Expect(w.Code).To(Equal(http.StatusOK), w.Body.String())
+
+		var response struct {
+			Data []models.AuditEvent `json:"data"`
+		}
+		Expect(json.Unmarshal(w.Body.Bytes(), &response)).To(Succeed())
+		return response.Data
+	}
+
+	It("should record item changes with the acting admin and a field diff", func() {
+		itemID := createItem(adminToken, map[string]interface{}{"name": "Audited Lamp", "price": 10})
+
+		w := doRequest("PUT", fmt.Sprintf("/api/v1/items/%d", itemID), map[string]interface{}{"price": 12.5}, adminToken)
+		Expect(w.Code).To(Equal(http.StatusOK), w.Body.String())
+
+		events := listEvents(url.Values{"target_type": {audit.TargetItem}, "target_id": {fmt.Sprint(itemID)}})
+		Expect(events).To(HaveLen(2))
+
+		update := events[0] // Newest first
+		Expect(update.Action).To(Equal(audit.ActionItemUpdated))
+		Expect(*update.ActorID).To(Equal(adminID))
+		Expect(update.ActorName).To(Equal(adminName))
+		Expect(update.RequestID).NotTo(BeEmpty())
+
+		var changes map[string]audit.Change
+		Expect(json.Unmarshal(update.Changes, &changes)).To(Succeed())
+		Expect(changes).To(HaveLen(1))
+		Expect(changes["price"]).To(Equal(audit.Change{From: 10.0, To: 12.5}))
+
+		Expect(events[1].Action).To(Equal(audit.ActionItemCreated))
+	})
+
+	It("should record failed logins and order status changes", func() {
+		username := uniqueName("target")
+		token, userID := registerAndLogin(username)
+
+		w := doRequest("POST", "/api/v1/users/login", map[string]string{"username": username, "password": "wrong-password"}, "")
+		Expect(w.Code).To(Equal(http.StatusBadRequest))
+
+		events := listEvents(url.Values{"action": {"auth."}, "target_id": {fmt.Sprint(userID)}})
+		Expect(events).To(HaveLen(2))
+		Expect(events[0].Action).To(Equal(audit.ActionLoginFailed))
+		Expect(events[1].Action).To(Equal(audit.ActionLogin))
+
+		orderID := placeOrder(token, 1, 1)
+		w = doRequest("PATCH", fmt.Sprintf("/api/v1/orders/%d/status", orderID), map[string]string{"status": "cancelled"}, adminToken)
+		Expect(w.Code).To(Equal(http.StatusOK), w.Body.String())
+
+		events = listEvents(url.Values{"action": {audit.ActionOrderStatusChanged}, "target_id": {fmt.Sprint(orderID)}})
+		Expect(events).To(HaveLen(1))
+		Expect(*events[0].ActorID).To(Equal(adminID))
+		Expect(string(events[0].Changes)).To(MatchJSON(`{"status": {"from": "confirmed", "to": "cancelled"}}`))
+	})
+
+	It("should reject bad filters and non-admins", func() {
+		w := doRequest("GET", "/api/v1/admin/audit?since=yesterday", nil, adminToken)
+		Expect(w.Code).To(Equal(http.StatusBadRequest))
+
+		customerToken, _ := registerAndLogin(uniqueName("nosy"))
+		w = doRequest("GET", "/api/v1/admin/audit", nil, customerToken)
+		Expect(w.Code).To(Equal(http.StatusForbidden))
+		w = doRequest("GET", "/api/v1/admin/audit/verify", nil, customerToken)
+		Expect(w.Code).To(Equal(http.StatusForbidden))
+	})
+
+	It("should verify an intact chain and refuse changes to recorded events", func() {
+		w := doRequest("GET", "/api/v1/admin/audit/verify", nil, adminToken)
+		Expect(w.Code).To(Equal(http.StatusOK), w.Body.String())
+		Expect(decodeData(w)["valid"]).To(BeTrue())
+		Expect(decodeData(w)["head"]).NotTo(BeEmpty())
+
+		var event models.AuditEvent
+		Expect(database.DB.Order("id DESC").First(&event).Error).To(Succeed())
+
+		Expect(database.DB.Model(&event).Update("action", "item.delete").Error).To(MatchError(ContainSubstring("append-only")))
+		Expect(database.DB.Exec("DELETE FROM audit_events WHERE id = ?", event.ID).Error).To(MatchError(ContainSubstring("append-only")))
+		Expect(database.DB.Delete(&event).Error).To(MatchError(models.ErrAuditAppendOnly))
+	})
+
+	It("should detect an event edited behind the triggers' back", func() {
+		var event models.AuditEvent
+		Expect(database.DB.Order("id").First(&event).Error).To(Succeed())
+
+		// DDL is transactional in SQLite, so rolling back restores the trigger and the row
+		tx := database.DB.Begin()
+		defer tx.Rollback()
+		Expect(tx.Exec("DROP TRIGGER audit_events_no_update").Error).To(Succeed())
+		Expect(tx.Exec("UPDATE audit_events SET actor_name = ? WHERE id = ?", "someone-else", event.ID).Error).To(Succeed())
+
+		result, err := audit.Verify(tx)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result.Valid).To(BeFalse())
+		Expect(*result.BrokenAt).To(Equal(event.ID))
+		Expect(result.Problem).To(ContainSubstring("modified"))
+	})
+})
@@ -0,0 +1,155 @@
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"shopease/internal/cache"
+	"shopease/internal/config"
+	"shopease/internal/models"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Catalogue cache", func() {
+	var (
+		staffToken string
+		itemID     uint
+		itemPath   string
+	)
+
+	BeforeEach(func() {
+		config.AppConfig.CatalogueCacheTTL = 60
+		cache.Catalogue = cache.NewLRU(100)
+
+		staffToken, _ = registerWithRole(uniqueName("cataloguer"), models.UserRoleStaff)
+		itemID = createItem(staffToken, map[string]interface{}{"name": "Cached Clock", "price": 30, "category": uniqueName("Clocks")})
+		itemPath = fmt.Sprintf("/api/v1/items/%d", itemID)
+	})
+
+	AfterEach(func() {
+		config.AppConfig.CatalogueCacheTTL = 0
+	})
+
+	It("should serve repeat reads from the cache with caching headers", func() {
+		w := doRequest("GET", itemPath, nil, "")
+		Expect(w.Code).To(Equal(http.StatusOK))
+		Expect(w.Header().Get("X-Cache")).To(Equal("MISS"))
+		Expect(w.Header().Get("Cache-Control")).To(Equal("public, max-age=60"))
+		etag, body := w.Header().Get("ETag"), w.Body.String()
+
+		w = doRequest("GET", itemPath, nil, "")
+		Expect(w.Header().Get("X-Cache")).To(Equal("HIT"))
+		Expect(w.Header().Get("ETag")).To(Equal(etag))
+		Expect(w.Body.String()).To(Equal(body))
+
+		// Same item, differently spelt
+		w = doRequest("GET", fmt.Sprintf("/api/v1/items/0%d", itemID), nil, "")
+		Expect(w.Header().Get("X-Cache")).To(Equal("HIT"))
+
+		// Conditional reads still work on cached responses
+		w = doRequestWithHeaders("GET", itemPath, nil, "", map[string]string{"If-None-Match": etag})
+		Expect(w.Code).To(Equal(http.StatusNotModified))
+
+		w = doRequest("GET", "/metrics", nil, "")
+		Expect(w.Body.String()).To(ContainSubstring(`shopease_cache_lookups_total{cache="catalogue",result="hit"}`))
+	})
+
+	It("should key lists on normalised query parameters", func() {
+		w := doRequest("GET", "/api/v1/items?page_size=5&category=Clocks", nil, "")
+		Expect(w.Header().Get("X-Cache")).To(Equal("MISS"))
+
+		w = doRequest("GET", "/api/v1/items?category=Clocks&sort=&page_size=5", nil, "")
+		Expect(w.Header().Get("X-Cache")).To(Equal("HIT"))
+
+		w = doRequest("GET", "/api/v1/items?category=Clocks&page_size=6", nil, "")
+		Expect(w.Header().Get("X-Cache")).To(Equal("MISS"))
+	})
+
+	It("should not let ignored or repeated parameters poison another page's entry", func() {
+		category := uniqueName("Sundials")
+		first := createItem(staffToken, map[string]interface{}{"name": "First Sundial", "price": 10, "category": category})
+		second := createItem(staffToken, map[string]interface{}{"name": "Second Sundial", "price": 12, "category": category})
+		pageIDs := func(w *httptest.ResponseRecorder) []uint {
+			var response struct {
+				Data []models.ItemResponse `json:"data"`
+			}
+			Expect(json.Unmarshal(w.Body.Bytes(), &response)).To(Succeed())
+			ids := make([]uint, len(response.Data))
+			for i, item := range response.Data {
+				ids[i] = item.ID
+			}
+			return ids
+		}
+		list := "/api/v1/items?page_size=1&category=" + category
+
+		// The handler ignores Page and reads the first page value, so both
+		// requests get page 1 and must not be stored as page 2
+		w := doRequest("GET", list+"&Page=2", nil, "")
+		Expect(w.Header().Get("X-Cache")).To(Equal("MISS"))
+		Expect(pageIDs(w)).To(Equal([]uint{second}))
+		w = doRequest("GET", list+"&page=1&page=2", nil, "")
+		Expect(w.Header().Get("X-Cache")).To(Equal("HIT"))
+
+		w = doRequest("GET", list+"&page=2", nil, "")
+		Expect(w.Header().Get("X-Cache")).To(Equal("MISS"))
+		Expect(pageIDs(w)).To(Equal([]uint{first}))
+	})
+
+	It("should drop affected entries when an item changes", func() {
+		doRequest("GET", itemPath, nil, "")
+		doRequest("GET", "/api/v1/items/categories", nil, "")
+		Expect(doRequest("GET", "/api/v1/items/categories", nil, "").Header().Get("X-Cache")).To(Equal("HIT"))
+
+		category := uniqueName("Timepieces")
+		w := doRequest("PUT", itemPath, map[string]interface{}{"name": "Renamed Clock", "category": category}, staffToken)
+		Expect(w.Code).To(Equal(http.StatusOK))
+
+		w = doRequest("GET", itemPath, nil, "")
+		Expect(w.Header().Get("X-Cache")).To(Equal("MISS"))
+		Expect(decodeData(w)["name"]).To(Equal("Renamed Clock"))
+
+		w = doRequest("GET", "/api/v1/items/categories", nil, "")
+		Expect(w.Header().Get("X-Cache")).To(Equal("MISS"))
+		Expect(w.Body.String()).To(ContainSubstring(category))
+
+		w = doRequest("DELETE", itemPath, nil, staffToken)
+		Expect(w.Code).To(Equal(http.StatusOK))
+		Expect(doRequest("GET", itemPath, nil, "").Code).To(Equal(http.StatusNotFound))
+	})
+
+	It("should not cache when the TTL is zero", func() {
+		config.AppConfig.CatalogueCacheTTL = 0
+
+		w := doRequest("GET", itemPath, nil, "")
+		Expect(w.Header().Get("X-Cache")).To(BeEmpty())
+		Expect(w.Header().Get("Cache-Control")).To(BeEmpty())
+	})
+})
+
+var _ = Describe("LRU cache", func() {
+	It("should evict the least recently used entry and expire old ones", func() {
+		lru := cache.NewLRU(2)
+		lru.Set("a", []byte("1"), time.Minute)
+		lru.Set("b", []byte("2"), time.Minute)
+		_, _ = lru.Get("a")
+		lru.Set("c", []byte("3"), time.Minute)
+
+		_, ok := lru.Get("b")
+		Expect(ok).To(BeFalse())
+		value, ok := lru.Get("a")
+		Expect(ok).To(BeTrue())
+		Expect(string(value)).To(Equal("1"))
+
+		lru.Set("d", []byte("4"), -time.Second)
+		_, ok = lru.Get("d")
+		Expect(ok).To(BeFalse())
+
+		lru.DeletePrefix("")
+		Expect(lru.Len()).To(BeZero())
+	})
+})
@@ -0,0 +1,175 @@
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+
+	"shopease/internal/config"
+	"shopease/internal/models"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Cart revalidation", func() {
+	var (
+		staffToken    string
+		customerToken string
+	)
+
+	BeforeEach(func() {
+		staffToken, _ = registerWithRole(uniqueName("merchant"), models.UserRoleStaff)
+
+		customerToken, _ = registerAndLogin(uniqueName("shopper"))
+	})
+
+	updateItem := func(itemID uint, changes map[string]interface{}) {
+		w := doRequest("PUT", fmt.Sprintf("/api/v1/items/%d", itemID), changes, staffToken)
+		Expect(w.Code).To(Equal(http.StatusOK), w.Body.String())
+	}
+
+	addToCart := func(itemID uint, quantity int) *httptest.ResponseRecorder {
+		return doRequest("POST", "/api/v1/carts", map[string]interface{}{"item_id": itemID, "quantity": quantity}, customerToken)
+	}
+
+	myCart := func() models.CartResponse {
+		w := doRequest("GET", "/api/v1/carts/my", nil, customerToken)
+		Expect(w.Code).To(Equal(http.StatusOK))
+
+		var response struct {
+			Data models.CartResponse `json:"data"`
+		}
+		Expect(json.Unmarshal(w.Body.Bytes(), &response)).To(Succeed())
+		return response.Data
+	}
+
+	checkout := func(cartID uint, version string) *httptest.ResponseRecorder {
+		body := map[string]interface{}{"cart_id": cartID}
+		if version != "" {
+			body["cart_version"] = version
+		}
+		return doRequest("POST", "/api/v1/orders", body, customerToken)
+	}
+
+	It("should report a price change and require the new version at checkout", func() {
+		itemID := createItem(staffToken, map[string]interface{}{"name": "Revalidated Lamp", "price": 20})
+		Expect(addToCart(itemID, 2).Code).To(Equal(http.StatusOK))
+
+		cart := myCart()
+		Expect(cart.Warnings).To(BeEmpty())
+		Expect(cart.Items[0].PriceAtAdd).NotTo(BeNil())
+		Expect(*cart.Items[0].PriceAtAdd).To(Equal(20.0))
+		oldVersion := cart.Version
+
+		updateItem(itemID, map[string]interface{}{"price": 25})
+
+		cart = myCart()
+		Expect(cart.Total).To(Equal(50.0))
+		Expect(cart.Version).NotTo(Equal(oldVersion))
+		Expect(cart.Warnings).To(HaveLen(1))
+		Expect(cart.Warnings[0].Code).To(Equal(models.CartWarningPriceChanged))
+		Expect(*cart.Warnings[0].OldPrice).To(Equal(20.0))
+		Expect(*cart.Warnings[0].NewPrice).To(Equal(25.0))
+
+		// Unconfirmed and stale versions are both rejected with the reviewed cart
+		for _, version := range []string{"", oldVersion} {
+			w := checkout(cart.ID, version)
+			Expect(w.Code).To(Equal(http.StatusConflict), w.Body.String())
+			Expect(decodeData(w)["version"]).To(Equal(cart.Version))
+			Expect(decodeData(w)["warnings"]).To(HaveLen(1))
+		}
+
+		w := checkout(cart.ID, cart.Version)
+		Expect(w.Code).To(Equal(http.StatusCreated), w.Body.String())
+		Expect(decodeData(w)["total_amount"]).To(Equal(50.0))
+	})
+
+	It("should leave unavailable items out of the order once confirmed", func() {
+		keptID := createItem(staffToken, map[string]interface{}{"name": "Available Mug", "price": 5})
+		goneID := createItem(staffToken, map[string]interface{}{"name": "Retired Mug", "price": 7})
+		Expect(addToCart(keptID, 1).Code).To(Equal(http.StatusOK))
+		Expect(addToCart(goneID, 1).Code).To(Equal(http.StatusOK))
+
+		updateItem(goneID, map[string]interface{}{"is_active": false})
+
+		cart := myCart()
+		Expect(cart.Warnings).To(HaveLen(1))
+		Expect(cart.Warnings[0].Code).To(Equal(models.CartWarningUnavailable))
+		Expect(cart.Warnings[0].ItemID).To(Equal(goneID))
+		Expect(cart.Total).To(Equal(5.0))
+		Expect(cart.ItemCount).To(Equal(1))
+
+		Expect(checkout(cart.ID, "").Code).To(Equal(http.StatusConflict))
+
+		w := checkout(cart.ID, cart.Version)
+		Expect(w.Code).To(Equal(http.StatusCreated), w.Body.String())
+		items := decodeData(w)["items"].([]interface{})
+		Expect(items).To(HaveLen(1))
+		Expect(items[0].(map[string]interface{})["item_id"]).To(Equal(float64(keptID)))
+	})
+
+	It("should cap quantities at the per-line limit", func() {
+		itemID := createItem(staffToken, map[string]interface{}{"name": "Bulk Pencil", "price": 1})
+		Expect(addToCart(itemID, 5).Code).To(Equal(http.StatusOK))
+
+		config.AppConfig.CartMaxQuantity = 3
+		defer func() { config.AppConfig.CartMaxQuantity = 0 }()
+
+		Expect(addToCart(itemID, 1).Code).To(Equal(http.StatusBadRequest))
+
+		cart := myCart()
+		Expect(cart.Warnings).To(HaveLen(1))
+		Expect(cart.Warnings[0].Code).To(Equal(models.CartWarningQuantityReduced))
+		Expect(cart.Warnings[0].OldQuantity).To(Equal(5))
+		Expect(cart.Warnings[0].NewQuantity).To(Equal(3))
+		Expect(cart.Total).To(Equal(3.0))
+
+		w := checkout(cart.ID, cart.Version)
+		Expect(w.Code).To(Equal(http.StatusCreated), w.Body.String())
+		items := decodeData(w)["items"].([]interface{})
+		Expect(items[0].(map[string]interface{})["quantity"]).To(Equal(3.0))
+	})
+
+	It("should check out an unchanged cart without a version", func() {
+		itemID := createItem(staffToken, map[string]interface{}{"name": "Steady Chair", "price": 40})
+		Expect(addToCart(itemID, 1).Code).To(Equal(http.StatusOK))
+
+		cart := myCart()
+		Expect(cart.Warnings).To(BeEmpty())
+		Expect(cart.Version).NotTo(BeEmpty())
+
+		w := checkout(cart.ID, "")
+		Expect(w.Code).To(Equal(http.StatusCreated), w.Body.String())
+	})
+	It("should place only one order when the same cart is checked out concurrently", func() {
+		itemID := createItem(staffToken, map[string]interface{}{"name": "Contested Lamp", "price": 25})
+		Expect(addToCart(itemID, 1).Code).To(Equal(http.StatusOK))
+		cart := myCart()
+
+		codes := make(chan int, 4)
+		var wg sync.WaitGroup
+		for i := 0; i < 4; i++ {
+			wg.Add(1)
+			go func() {
+				defer GinkgoRecover()
+				defer wg.Done()
+				codes <- checkout(cart.ID, "").Code
+			}()
+		}
+		wg.Wait()
+		close(codes)
+
+		created := 0
+		for code := range codes {
+			if code == http.StatusCreated {
+				created++
+				continue
+			}
+			Expect(code).To(BeElementOf(http.StatusConflict, http.StatusBadRequest))
+		}
+		Expect(created).To(Equal(1))
+	})
+})
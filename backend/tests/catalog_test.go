@@ -0,0 +1,261 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"time"
+
+	"shopease/internal/catalog"
+	"shopease/internal/database"
+	"shopease/internal/jobs"
+	"shopease/internal/models"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Catalogue import and export", func() {
+	var adminToken string
+
+	BeforeEach(func() {
+		adminToken, _ = registerWithRole(uniqueName("catalog"), models.UserRoleAdmin)
+	})
+
+	upload := func(query, contentType, body string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("POST", "/api/v1/admin/items/import"+query, strings.NewReader(body))
+		req.Header.Set("Content-Type", contentType)
+		req.Header.Set("Authorization", "Bearer "+adminToken)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	itemBySKU := func(sku string) (models.Item, error) {
+		var item models.Item
+		err := database.DB.Where("sku = ?", sku).First(&item).Error
+		return item, err
+	}
+
+	It("should only be available to admins", func() {
+		token, _ := registerAndLogin(uniqueName("shopper"))
+		Expect(doRequest("GET", "/api/v1/admin/items/export", nil, token).Code).To(Equal(http.StatusForbidden))
+	})
+
+	It("should validate a CSV file without saving it on a dry run", func() {
+		sku := uniqueName("DRY-")
+		w := upload("?dry_run=true", "text/csv", "sku,name,price\n"+sku+",Dry Lamp,12.50\n")
+		Expect(w.Code).To(Equal(http.StatusOK), w.Body.String())
+
+		report := decodeData(w)
+		Expect(report["dry_run"]).To(BeTrue())
+		Expect(report["applied"]).To(BeFalse())
+		Expect(report["created"]).To(BeNumerically("==", 1))
+
+		_, err := itemBySKU(sku)
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should reject the whole file when any row is invalid", func() {
+		good, bad := uniqueName("OK-"), uniqueName("BAD-")
+		w := upload("?format=csv", "application/octet-stream",
+			"sku,name,price\n"+good+",Good Lamp,10\n"+bad+",,-3\n"+uniqueName("NAN-")+",Odd Lamp,cheap\n")
+		Expect(w.Code).To(Equal(http.StatusUnprocessableEntity), w.Body.String())
+
+		report := decodeData(w)
+		Expect(report["failed"]).To(BeNumerically("==", 2))
+		errs := report["errors"].([]interface{})
+		Expect(errs).To(HaveLen(2))
+		first := errs[0].(map[string]interface{})
+		Expect(first["row"]).To(BeNumerically("==", 2))
+		Expect(first["sku"]).To(Equal(bad))
+		Expect(first["errors"]).To(ConsistOf("name: required", "price: must not be negative"))
+		Expect(errs[1].(map[string]interface{})["errors"]).To(ConsistOf(ContainSubstring(`"cheap" is not a number`)))
+
+		_, err := itemBySKU(good)
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should apply valid rows in best effort mode", func() {
+		good := uniqueName("BEST-")
+		body := fmt.Sprintf("{\"sku\":%q,\"name\":\"Best Lamp\",\"price\":7}\n{\"name\":\"Typo\",\"prise\":1}\n", good)
+		w := upload("?mode=best_effort", "application/x-ndjson", body)
+		Expect(w.Code).To(Equal(http.StatusOK), w.Body.String())
+
+		report := decodeData(w)
+		Expect(report["applied"]).To(BeTrue())
+		Expect(report["created"]).To(BeNumerically("==", 1))
+		Expect(report["failed"]).To(BeNumerically("==", 1))
+
+		item, err := itemBySKU(good)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(item.Price).To(Equal(7.0))
+	})
+
+	It("should commit large best-effort imports in batches and export them in chunks", func() {
+		prefix := uniqueName("BULK") + "-"
+		rows := catalog.BatchSize + catalog.ExportChunkSize/2
+		var body strings.Builder
+		body.WriteString("sku,name,price\n")
+		for i := 0; i < rows; i++ {
+			fmt.Fprintf(&body, "%s%d,Bulk Peg %d,1\n", prefix, i, i)
+		}
+		body.WriteString(prefix + "bad,,1\n")
+
+		w := upload("?mode=best_effort", "text/csv", body.String())
+		Expect(w.Code).To(Equal(http.StatusOK), w.Body.String())
+		report := decodeData(w)
+		Expect(report["created"]).To(BeNumerically("==", rows))
+		Expect(report["failed"]).To(BeNumerically("==", 1))
+
+		var stored int64
+		Expect(database.DB.Model(&models.Item{}).Where("sku LIKE ?", prefix+"%").Count(&stored).Error).To(Succeed())
+		Expect(stored).To(BeNumerically("==", rows))
+
+		w = doRequest("GET", "/api/v1/admin/items/export?format=ndjson", nil, adminToken)
+		Expect(w.Code).To(Equal(http.StatusOK))
+		exported := 0
+		for _, line := range strings.Split(strings.TrimSpace(w.Body.String()), "\n") {
+			if strings.Contains(line, prefix) {
+				exported++
+			}
+		}
+		Expect(exported).To(Equal(rows))
+	})
+
+	It("should update existing items by SKU", func() {
+		sku := uniqueName("UPS-")
+		Expect(upload("", "text/csv", "sku,name,price,category\n"+sku+",Desk Lamp,20,Home\n").Code).To(Equal(http.StatusOK))
+		original, err := itemBySKU(sku)
+		Expect(err).NotTo(HaveOccurred())
+
+		w := upload("", "text/csv", "sku,name,price,category,is_active\n"+sku+",Desk Lamp v2,18,Home,false\n")
+		Expect(w.Code).To(Equal(http.StatusOK), w.Body.String())
+		Expect(decodeData(w)["updated"]).To(BeNumerically("==", 1))
+
+		updated, err := itemBySKU(sku)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(updated.ID).To(Equal(original.ID))
+		Expect(updated.Name).To(Equal("Desk Lamp v2"))
+		Expect(updated.IsActive).To(BeFalse())
+	})
+
+	It("should publish price changes made by an import", func() {
+		sku := uniqueName("DROP-")
+		Expect(upload("", "text/csv", "sku,name,price,category\n"+sku+",Kettle,40,Home\n").Code).To(Equal(http.StatusOK))
+		item, err := itemBySKU(sku)
+		Expect(err).NotTo(HaveOccurred())
+
+		w := upload("", "text/csv", "sku,name,price,category\n"+sku+",Kettle,32,Home\n")
+		Expect(w.Code).To(Equal(http.StatusOK), w.Body.String())
+		Expect(decodeData(w)["updated"]).To(BeNumerically("==", 1))
+
+		var event models.OutboxEvent
+		Expect(database.DB.Where("type = ? AND aggregate_id = ?", "item.price_changed", item.ID).Last(&event).Error).To(Succeed())
+		Expect(string(event.Payload)).To(MatchJSON(fmt.Sprintf(`{"item_id": %d, "old_price": 40, "new_price": 32}`, item.ID)))
+
+		updated, err := itemBySKU(sku)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(updated.Price).To(Equal(32.0))
+	})
+
+	It("should reject SKUs that are already taken through the item API", func() {
+		sku := uniqueName("API-")
+		w := doRequest("POST", "/api/v1/items", map[string]interface{}{"name": "Api Lamp", "price": 5, "sku": sku}, adminToken)
+		Expect(w.Code).To(Equal(http.StatusCreated), w.Body.String())
+		Expect(decodeData(w)["sku"]).To(Equal(sku))
+
+		w = doRequest("POST", "/api/v1/items", map[string]interface{}{"name": "Copy Lamp", "price": 5, "sku": sku}, adminToken)
+		Expect(w.Code).To(Equal(http.StatusConflict))
+	})
+
+	It("should run multipart uploads as background jobs", func() {
+		jobs.Start(1)
+		DeferCleanup(jobs.Stop)
+
+		sku := uniqueName("JOB-")
+		var body bytes.Buffer
+		form := multipart.NewWriter(&body)
+		part, _ := form.CreateFormFile("file", "items.csv")
+		fmt.Fprintf(part, "sku,name,price\n%s,Job Lamp,3\n", sku)
+		form.Close()
+
+		w := upload("?async=true", form.FormDataContentType(), body.String())
+		Expect(w.Code).To(Equal(http.StatusAccepted), w.Body.String())
+		location := w.Header().Get("Location")
+		Expect(location).To(HavePrefix("/api/v1/admin/items/import/"))
+
+		var job map[string]interface{}
+		Eventually(func() interface{} {
+			w := doRequest("GET", location, nil, adminToken)
+			Expect(w.Code).To(Equal(http.StatusOK))
+			job = decodeData(w)
+			return job["state"]
+		}, 5*time.Second, 20*time.Millisecond).Should(Equal("succeeded"))
+		Expect(job["progress"]).To(BeNumerically("==", 1))
+		Expect(job["report"].(map[string]interface{})["created"]).To(BeNumerically("==", 1))
+
+		w = doRequest("GET", fmt.Sprintf("/api/v1/admin/jobs/%.0f", job["id"]), nil, adminToken)
+		Expect(w.Code).To(Equal(http.StatusOK))
+		Expect(decodeData(w)["type"]).To(Equal(catalog.ImportJob))
+
+		_, err := itemBySKU(sku)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(doRequest("GET", "/api/v1/admin/items/import/unknown", nil, adminToken).Code).To(Equal(http.StatusNotFound))
+	})
+
+	It("should stream the catalogue as CSV or NDJSON", func() {
+		sku := uniqueName("EXP-")
+		Expect(upload("", "text/csv", "sku,name,price,is_active\n"+sku+",Hidden Lamp,4,false\n").Code).To(Equal(http.StatusOK))
+
+		w := doRequest("GET", "/api/v1/admin/items/export", nil, adminToken)
+		Expect(w.Code).To(Equal(http.StatusOK))
+		Expect(w.Header().Get("Content-Type")).To(HavePrefix("text/csv"))
+		Expect(w.Body.String()).To(HavePrefix("sku,id,name,description,price,image_url,category,is_active\n"))
+		Expect(w.Body.String()).To(MatchRegexp(sku + `,\d+,Hidden Lamp,,4,,,false\n`))
+
+		w = doRequest("GET", "/api/v1/admin/items/export?format=ndjson", nil, adminToken)
+		Expect(w.Code).To(Equal(http.StatusOK))
+		var found bool
+		for _, line := range strings.Split(strings.TrimSpace(w.Body.String()), "\n") {
+			var record map[string]interface{}
+			Expect(json.Unmarshal([]byte(line), &record)).To(Succeed())
+			if record["sku"] == sku {
+				found = true
+				Expect(record["is_active"]).To(BeFalse())
+			}
+		}
+		Expect(found).To(BeTrue())
+
+		Expect(doRequest("GET", "/api/v1/admin/items/export?format=xml", nil, adminToken).Code).To(Equal(http.StatusBadRequest))
+	})
+
+	It("should round-trip the catalogue through shopctl's JSON format", func() {
+		var exported bytes.Buffer
+		count, err := catalog.Export(context.Background(), &exported, catalog.FormatJSON)
+		Expect(err).NotTo(HaveOccurred())
+
+		var records []catalog.Record
+		Expect(json.Unmarshal(exported.Bytes(), &records)).To(Succeed())
+		Expect(records).To(HaveLen(count))
+
+		name := uniqueName("Imported Lamp")
+		inactive := false
+		records = append(records, catalog.Record{Name: name, Price: 12.5, Category: "Home", IsActive: &inactive})
+		raw, _ := json.Marshal(records)
+
+		report, err := catalog.Import(context.Background(), bytes.NewReader(raw), catalog.Options{Format: catalog.FormatJSON})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(report.Failed).To(BeZero())
+		Expect(report.Created).To(Equal(1))
+
+		var lamp models.Item
+		Expect(database.DB.Where("name = ?", name).First(&lamp).Error).To(Succeed())
+		Expect(lamp.IsActive).To(BeFalse())
+	})
+})
@@ -0,0 +1,125 @@
+package tests
+
+import (
+	"flag"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"shopease/internal/config"
+	"shopease/internal/models"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Configuration", func() {
+	var dir string
+
+	// load runs config.Load with a fresh flag set
+	load := func(args ...string) (*config.Config, error) {
+		fs := flag.NewFlagSet("test", flag.ContinueOnError)
+		fs.SetOutput(GinkgoWriter)
+		return config.Load(fs, args)
+	}
+
+	// write creates a file in the spec's temp directory
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		Expect(os.WriteFile(path, []byte(content), 0o600)).To(Succeed())
+		return path
+	}
+
+	BeforeEach(func() {
+		dir = GinkgoT().TempDir()
+	})
+
+	AfterEach(func() {
+		for _, name := range []string{"CONFIG_FILE", "PORT", "LOG_LEVEL", "SLOW_QUERY_MS", "JWT_SECRET", "JWT_SECRET_FILE"} {
+			os.Unsetenv(name)
+		}
+	})
+
+	It("should layer defaults, file, environment and flags", func() {
+		path := write("shopease.yaml", "port: 9000\nlog_level: debug\nslow_query_ms: 50\nrate_limit_enabled: false\n")
+		os.Setenv("LOG_LEVEL", "warn")
+		os.Setenv("SLOW_QUERY_MS", "75")
+
+		cfg, err := load("--config", path, "--slow-query-ms", "100")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(cfg.Port).To(Equal("9000"))            // file
+		Expect(cfg.LogLevel).To(Equal("warn"))        // env beats file
+		Expect(cfg.SlowQueryThreshold).To(Equal(100)) // flag beats env
+		Expect(cfg.RateLimitEnabled).To(BeFalse())
+		Expect(cfg.JobWorkers).To(Equal(4))       // default
+		Expect(cfg.SeedProfile).To(Equal("test")) // no published accounts unless asked for
+		Expect(cfg.Validate()).To(Succeed())
+	})
+
+	It("should read TOML files named by CONFIG_FILE", func() {
+		os.Setenv("CONFIG_FILE", write("shopease.toml", "port = \"9100\"\ntracing_sample_ratio = 0.25\n"))
+
+		cfg, err := load()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(cfg.Port).To(Equal("9100"))
+		Expect(cfg.TracingSampleRatio).To(Equal(0.25))
+	})
+
+	It("should report malformed values and unknown keys instead of ignoring them", func() {
+		os.Setenv("SLOW_QUERY_MS", "fast")
+		path := write("shopease.yaml", "prot: 9000\n")
+
+		_, err := load("--config", path)
+		Expect(err).To(MatchError(ContainSubstring(`SLOW_QUERY_MS: "fast" is not an integer`)))
+		Expect(err.Error()).To(ContainSubstring(`unknown setting "prot"`))
+	})
+
+	It("should read secrets from files", func() {
+		os.Setenv("JWT_SECRET_FILE", write("jwt", "  from-a-file\n"))
+
+		cfg, err := load()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(cfg.JWTSecret).To(Equal("from-a-file"))
+
+		os.Setenv("JWT_SECRET", "inline")
+		_, err = load()
+		Expect(err).To(MatchError(ContainSubstring("JWT_SECRET and JWT_SECRET_FILE are both set")))
+	})
+
+	It("should refuse insecure settings in release mode", func() {
+		cfg := config.Defaults()
+		cfg.GinMode = "release"
+		cfg.AllowedOrigins = "https://shop.example.com, *"
+		cfg.SeedProfile = "dev"
+
+		err := cfg.Validate()
+		Expect(err).To(MatchError(ContainSubstring("default secret is not allowed")))
+		Expect(err.Error()).To(ContainSubstring("wildcard CORS"))
+		Expect(err.Error()).To(ContainSubstring("seed_profile: dev creates accounts with published passwords"))
+
+		cfg.JWTSecret = strings.Repeat("s", config.MinJWTSecretLength)
+		cfg.AllowedOrigins = "https://shop.example.com"
+		cfg.SeedProfile = "test"
+		Expect(cfg.Validate()).To(Succeed())
+
+		cfg.GinMode = "debug"
+		cfg.JWTSecret = config.DefaultJWTSecret
+		Expect(cfg.Validate()).To(Succeed())
+		Expect(cfg.Warnings()).NotTo(BeEmpty())
+	})
+
+	It("should only show admins a redacted configuration", func() {
+		token, _ := registerAndLogin(uniqueName("operator"))
+		Expect(doRequest("GET", "/api/v1/admin/config", nil, token).Code).To(Equal(http.StatusForbidden))
+
+		token, _ = registerWithRole(uniqueName("operator"), models.UserRoleAdmin)
+		w := doRequest("GET", "/api/v1/admin/config", nil, token)
+		Expect(w.Code).To(Equal(http.StatusOK))
+
+		data := decodeData(w)
+		Expect(data["jwt_secret"]).To(Equal("[REDACTED]"))
+		Expect(data["db_path"]).To(Equal(":memory:"))
+		Expect(w.Body.String()).NotTo(ContainSubstring(config.AppConfig.JWTSecret))
+	})
+})
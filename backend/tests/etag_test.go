@@ -0,0 +1,128 @@
+package tests
+
+import (
+	"fmt"
+	"net/http"
+
+	"shopease/internal/models"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Optimistic concurrency", func() {
+	var staffToken string
+
+	BeforeEach(func() {
+		staffToken, _ = registerWithRole(uniqueName("editor"), models.UserRoleStaff)
+	})
+
+	It("should reject an item edit made against an older version", func() {
+		path := fmt.Sprintf("/api/v1/items/%d", createItem(staffToken, map[string]interface{}{"name": "Contested Desk", "price": 12}))
+
+		w := doRequest("GET", path, nil, "")
+		Expect(w.Code).To(Equal(http.StatusOK))
+		etag := w.Header().Get("ETag")
+		Expect(etag).NotTo(BeEmpty())
+
+		// The first editor saves; the second still holds the old ETag
+		w = doRequestWithHeaders("PUT", path, map[string]interface{}{"name": "First Edit"}, staffToken, map[string]string{"If-Match": etag})
+		Expect(w.Code).To(Equal(http.StatusOK), w.Body.String())
+		newETag := w.Header().Get("ETag")
+		Expect(newETag).NotTo(Equal(etag))
+
+		w = doRequestWithHeaders("PUT", path, map[string]interface{}{"name": "Second Edit"}, staffToken, map[string]string{"If-Match": etag})
+		Expect(w.Code).To(Equal(http.StatusPreconditionFailed))
+		Expect(w.Header().Get("ETag")).To(Equal(newETag))
+
+		w = doRequestWithHeaders("DELETE", path, nil, staffToken, map[string]string{"If-Match": etag})
+		Expect(w.Code).To(Equal(http.StatusPreconditionFailed))
+
+		w = doRequest("GET", path, nil, "")
+		Expect(decodeData(w)["name"]).To(Equal("First Edit"))
+
+		// Edits without If-Match still apply
+		w = doRequest("PUT", path, map[string]interface{}{"name": "Unconditional Edit"}, staffToken)
+		Expect(w.Code).To(Equal(http.StatusOK))
+	})
+
+	It("should answer conditional catalogue reads with 304 until something changes", func() {
+		itemID := createItem(staffToken, map[string]interface{}{"name": "Cached Shelf", "price": 12})
+		path := fmt.Sprintf("/api/v1/items/%d", itemID)
+
+		w := doRequest("GET", path, nil, "")
+		etag := w.Header().Get("ETag")
+
+		w = doRequestWithHeaders("GET", path, nil, "", map[string]string{"If-None-Match": etag})
+		Expect(w.Code).To(Equal(http.StatusNotModified))
+		Expect(w.Body.Len()).To(BeZero())
+
+		w = doRequest("GET", "/api/v1/items/categories", nil, "")
+		Expect(w.Code).To(Equal(http.StatusOK))
+		listETag := w.Header().Get("ETag")
+		Expect(listETag).To(HavePrefix(`W/"`))
+
+		w = doRequestWithHeaders("GET", "/api/v1/items/categories", nil, "", map[string]string{"If-None-Match": listETag})
+		Expect(w.Code).To(Equal(http.StatusNotModified))
+
+		w = doRequest("PUT", path, map[string]interface{}{"category": uniqueName("Cached")}, staffToken)
+		Expect(w.Code).To(Equal(http.StatusOK))
+
+		w = doRequestWithHeaders("GET", path, nil, "", map[string]string{"If-None-Match": etag})
+		Expect(w.Code).To(Equal(http.StatusOK))
+		w = doRequestWithHeaders("GET", "/api/v1/items/categories", nil, "", map[string]string{"If-None-Match": listETag})
+		Expect(w.Code).To(Equal(http.StatusOK))
+	})
+
+	It("should stop a second tab from editing a cart it has not reloaded", func() {
+		token, _ := registerAndLogin(uniqueName("tabs"))
+		itemID := createItem(staffToken, map[string]interface{}{"name": "Two Tab Vase", "price": 12})
+		w := doRequest("POST", "/api/v1/carts", map[string]interface{}{"item_id": itemID, "quantity": 1}, token)
+		Expect(w.Code).To(Equal(http.StatusOK))
+
+		w = doRequest("GET", "/api/v1/carts/my", nil, token)
+		etag := w.Header().Get("ETag")
+		Expect(etag).NotTo(BeEmpty())
+		lineID := uint(decodeData(w)["items"].([]interface{})[0].(map[string]interface{})["id"].(float64))
+		linePath := fmt.Sprintf("/api/v1/carts/items/%d", lineID)
+
+		w = doRequestWithHeaders("PUT", linePath, map[string]interface{}{"quantity": 2}, token, map[string]string{"If-Match": etag})
+		Expect(w.Code).To(Equal(http.StatusOK), w.Body.String())
+		Expect(w.Header().Get("ETag")).NotTo(Equal(etag))
+
+		w = doRequestWithHeaders("PUT", linePath, map[string]interface{}{"quantity": 5}, token, map[string]string{"If-Match": etag})
+		Expect(w.Code).To(Equal(http.StatusPreconditionFailed))
+		w = doRequestWithHeaders("DELETE", "/api/v1/carts/my", nil, token, map[string]string{"If-Match": etag})
+		Expect(w.Code).To(Equal(http.StatusPreconditionFailed))
+
+		w = doRequest("GET", "/api/v1/carts/my", nil, token)
+		Expect(decodeData(w)["item_count"]).To(Equal(2.0))
+
+		w = doRequestWithHeaders("DELETE", "/api/v1/carts/my", nil, token, map[string]string{"If-Match": w.Header().Get("ETag")})
+		Expect(w.Code).To(Equal(http.StatusOK))
+	})
+
+	It("should version orders as their status changes", func() {
+		token, _ := registerAndLogin(uniqueName("versioned"))
+		orderPath := fmt.Sprintf("/api/v1/orders/%d", placeOrder(token, createItem(staffToken, map[string]interface{}{"name": "Versioned Rug", "price": 12}), 1))
+
+		w := doRequest("GET", orderPath, nil, token)
+		Expect(w.Code).To(Equal(http.StatusOK))
+		etag := w.Header().Get("ETag")
+		Expect(decodeData(w)["version"]).To(Equal(1.0))
+
+		// Only staff may move an order between statuses
+		w = doRequestWithHeaders("PATCH", orderPath+"/status", map[string]interface{}{"status": "pending"}, token, map[string]string{"If-Match": etag})
+		Expect(w.Code).To(Equal(http.StatusForbidden))
+
+		w = doRequestWithHeaders("PATCH", orderPath+"/status", map[string]interface{}{"status": "pending"}, staffToken, map[string]string{"If-Match": etag})
+		Expect(w.Code).To(Equal(http.StatusOK), w.Body.String())
+		Expect(decodeData(w)["version"]).To(Equal(2.0))
+
+		w = doRequestWithHeaders("POST", orderPath+"/cancel", nil, token, map[string]string{"If-Match": etag})
+		Expect(w.Code).To(Equal(http.StatusPreconditionFailed))
+
+		w = doRequestWithHeaders("POST", orderPath+"/cancel", nil, token, map[string]string{"If-Match": `"v2"`})
+		Expect(w.Code).To(Equal(http.StatusOK), w.Body.String())
+	})
+})
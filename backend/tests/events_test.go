@@ -0,0 +1,189 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"shopease/internal/database"
+	"shopease/internal/events"
+	"shopease/internal/models"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Domain events", func() {
+	var (
+		mu           sync.Mutex
+		orderEvents  = map[uint][]string{}                  // Delivered event types by order
+		priceChanges = map[uint][]events.ItemPriceChanged{} // By item
+		registered   = map[uint]bool{}
+		failingUsers = map[uint]bool{} // Users whose OrderPlaced events are refused
+		refused      = map[uint]int{}  // Refused deliveries by order
+	)
+
+	events.Subscribe("test.orders", func(ctx context.Context, env events.Envelope, event events.OrderPlaced) error {
+		mu.Lock()
+		defer mu.Unlock()
+		if failingUsers[event.UserID] {
+			refused[event.OrderID]++
+			return errors.New("ledger unavailable")
+		}
+		orderEvents[event.OrderID] = append(orderEvents[event.OrderID], env.Type)
+		return nil
+	})
+	events.Subscribe("test.orders", func(ctx context.Context, env events.Envelope, event events.OrderCancelled) error {
+		mu.Lock()
+		defer mu.Unlock()
+		orderEvents[event.OrderID] = append(orderEvents[event.OrderID], env.Type)
+		return nil
+	})
+	events.Subscribe("test.prices", func(ctx context.Context, env events.Envelope, event events.ItemPriceChanged) error {
+		mu.Lock()
+		defer mu.Unlock()
+		priceChanges[event.ItemID] = append(priceChanges[event.ItemID], event)
+		return nil
+	})
+	events.Subscribe("test.users", func(ctx context.Context, env events.Envelope, event events.UserRegistered) error {
+		mu.Lock()
+		defer mu.Unlock()
+		registered[event.UserID] = true
+		return nil
+	})
+
+	var staffToken string
+
+	BeforeEach(func() {
+		staffToken, _ = registerWithRole(uniqueName("publisher"), models.UserRoleStaff)
+
+		events.Start()
+		DeferCleanup(events.Stop)
+	})
+
+	eventsFor := func(orderID uint) func() []string {
+		return func() []string {
+			mu.Lock()
+			defer mu.Unlock()
+			return append([]string(nil), orderEvents[orderID]...)
+		}
+	}
+
+	cancel := func(token string, orderID uint) {
+		w := doRequest("POST", fmt.Sprintf("/api/v1/orders/%d/cancel", orderID), nil, token)
+		Expect(w.Code).To(Equal(http.StatusOK), w.Body.String())
+	}
+
+	It("should deliver events for registrations, price changes and orders", func() {
+		customerToken, customerID := registerAndLogin(uniqueName("subscriber"))
+		Eventually(func() bool {
+			mu.Lock()
+			defer mu.Unlock()
+			return registered[customerID]
+		}, 5*time.Second).Should(BeTrue())
+
+		itemID := createItem(staffToken, map[string]interface{}{"name": "Evented Kettle", "price": 30})
+		w := doRequest("PUT", fmt.Sprintf("/api/v1/items/%d", itemID), map[string]interface{}{"price": 24}, staffToken)
+		Expect(w.Code).To(Equal(http.StatusOK), w.Body.String())
+		Eventually(func() []events.ItemPriceChanged {
+			mu.Lock()
+			defer mu.Unlock()
+			return priceChanges[itemID]
+		}, 5*time.Second).Should(Equal([]events.ItemPriceChanged{{ItemID: itemID, OldPrice: 30, NewPrice: 24}}))
+
+		orderID := placeOrder(customerToken, itemID, 2)
+		cancel(customerToken, orderID)
+		Eventually(eventsFor(orderID), 5*time.Second).Should(Equal([]string{"order.placed", "order.cancelled"}))
+
+		var outbox models.OutboxEvent
+		Expect(database.DB.Where("type = ? AND aggregate_id = ?", "order.placed", orderID).First(&outbox).Error).To(Succeed())
+		Expect(outbox.AggregateType).To(Equal(events.AggregateOrder))
+		Expect(outbox.DispatchedAt).NotTo(BeNil())
+		Expect(string(outbox.Payload)).To(MatchJSON(fmt.Sprintf(`{"order_id": %d, "user_id": %d, "total_amount": 48, "item_count": 1}`, orderID, customerID)))
+	})
+
+	It("should only keep events whose transaction commits", func() {
+		tx := database.DB.Begin()
+		Expect(events.Publish(tx, events.UserRegistered{UserID: 999999})).To(Succeed())
+		tx.Rollback()
+
+		var count int64
+		database.DB.Model(&models.OutboxEvent{}).Where("aggregate_type = ? AND aggregate_id = ?", events.AggregateUser, 999999).Count(&count)
+		Expect(count).To(BeZero())
+	})
+
+	It("should retry a failed delivery and hold back that aggregate's later events", func() {
+		itemID := createItem(staffToken, map[string]interface{}{"name": "Evented Teapot", "price": 12})
+
+		blockedToken, blockedID := registerAndLogin(uniqueName("blocked"))
+		mu.Lock()
+		failingUsers[blockedID] = true
+		mu.Unlock()
+
+		blockedOrder := placeOrder(blockedToken, itemID, 1)
+		cancel(blockedToken, blockedOrder)
+
+		// Other orders are unaffected
+		otherToken, _ := registerAndLogin(uniqueName("unblocked"))
+		otherOrder := placeOrder(otherToken, itemID, 1)
+		Eventually(eventsFor(otherOrder), 5*time.Second).Should(Equal([]string{"order.placed"}))
+
+		var outbox models.OutboxEvent
+		Expect(database.DB.Where("type = ? AND aggregate_id = ?", "order.placed", blockedOrder).First(&outbox).Error).To(Succeed())
+		Expect(outbox.DispatchedAt).To(BeNil())
+		Expect(outbox.Attempts).To(BeNumerically(">=", 1))
+		Expect(outbox.LastError).To(Equal("test.orders: ledger unavailable"))
+		Expect(outbox.NextAttemptAt).NotTo(BeNil())
+
+		// The cancellation waits for the placement to be delivered
+		Consistently(eventsFor(blockedOrder), 1500*time.Millisecond).Should(BeEmpty())
+
+		mu.Lock()
+		failingUsers[blockedID] = false
+		mu.Unlock()
+
+		Eventually(eventsFor(blockedOrder), 10*time.Second).Should(Equal([]string{"order.placed", "order.cancelled"}))
+		mu.Lock()
+		Expect(refused[blockedOrder]).To(BeNumerically(">=", 1))
+		mu.Unlock()
+
+		Expect(events.Backoff(2)).To(Equal(2 * events.Backoff(1)))
+	})
+	It("should give up on an event after too many attempts and release its aggregate", func() {
+		events.Start() // Already running, so a no-op
+
+		itemID := createItem(staffToken, map[string]interface{}{"name": "Evented Jug", "price": 9})
+		deadToken, deadID := registerAndLogin(uniqueName("deadletter"))
+		mu.Lock()
+		failingUsers[deadID] = true
+		mu.Unlock()
+
+		orderID := placeOrder(deadToken, itemID, 1)
+		cancel(deadToken, orderID)
+		Eventually(func() int {
+			mu.Lock()
+			defer mu.Unlock()
+			return refused[orderID]
+		}, 5*time.Second).Should(BeNumerically(">=", 1))
+
+		// Dispatch by hand from the last attempt
+		events.Stop()
+		Expect(database.DB.Model(&models.OutboxEvent{}).
+			Where("type = ? AND aggregate_id = ?", "order.placed", orderID).
+			Updates(map[string]interface{}{"attempts": events.MaxAttempts - 1, "next_attempt_at": nil}).Error).To(Succeed())
+		for i := 0; i < 2; i++ {
+			_, err := events.Dispatch(context.Background())
+			Expect(err).NotTo(HaveOccurred())
+		}
+
+		var outbox models.OutboxEvent
+		Expect(database.DB.Where("type = ? AND aggregate_id = ?", "order.placed", orderID).First(&outbox).Error).To(Succeed())
+		Expect(outbox.DeadAt).NotTo(BeNil())
+		Expect(outbox.DispatchedAt).To(BeNil())
+		Expect(outbox.Attempts).To(Equal(events.MaxAttempts))
+		Expect(eventsFor(orderID)()).To(Equal([]string{"order.cancelled"}))
+	})
+})
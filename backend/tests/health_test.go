@@ -0,0 +1,89 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"shopease/internal/database"
+	"shopease/internal/health"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Health probes", func() {
+	report := func(path string) (int, health.Report) {
+		w := doRequest("GET", path, nil, "")
+		var r health.Report
+		Expect(json.Unmarshal(w.Body.Bytes(), &r)).To(Succeed())
+		return w.Code, r
+	}
+
+	BeforeEach(func() {
+		sqlDB, err := database.DB.DB()
+		Expect(err).NotTo(HaveOccurred())
+		health.Register("database", health.DatabaseCheck(sqlDB), time.Second)
+		health.Register("schema", health.SchemaVersionCheck(database.CurrentSchemaVersion, database.SchemaVersion), time.Second)
+	})
+
+	AfterEach(func() {
+		for _, name := range []string{"database", "schema", "broken", "slow", "worker"} {
+			health.Unregister(name)
+		}
+		health.SetShuttingDown(false)
+	})
+
+	It("should report each check when ready", func() {
+		code, r := report("/readyz")
+		Expect(code).To(Equal(http.StatusOK))
+		Expect(r.Status).To(Equal(health.StatusOK))
+		Expect(r.Checks).To(HaveKeyWithValue("database", HaveField("Status", health.StatusOK)))
+		Expect(r.Checks).To(HaveKeyWithValue("schema", HaveField("Status", health.StatusOK)))
+
+		w := doRequest("GET", "/health", nil, "")
+		Expect(w.Code).To(Equal(http.StatusOK))
+		Expect(decodeData(w)).To(BeNil())
+		Expect(w.Body.String()).To(ContainSubstring(`"status":"healthy"`))
+	})
+
+	It("should fail readiness but not liveness when a check fails or times out", func() {
+		health.Register("broken", func(ctx context.Context) error { return errors.New("disk on fire") }, 0)
+		health.Register("slow", func(ctx context.Context) error {
+			<-ctx.Done()
+			return ctx.Err()
+		}, 50*time.Millisecond)
+
+		code, r := report("/readyz")
+		Expect(code).To(Equal(http.StatusServiceUnavailable))
+		Expect(r.Checks["broken"].Error).To(Equal("disk on fire"))
+		Expect(r.Checks["slow"].Status).To(Equal(health.StatusFail))
+		Expect(r.Checks["database"].Status).To(Equal(health.StatusOK))
+
+		Expect(doRequest("GET", "/health", nil, "").Code).To(Equal(http.StatusServiceUnavailable))
+		Expect(doRequest("GET", "/livez", nil, "").Code).To(Equal(http.StatusOK))
+	})
+
+	It("should flag workers that have not sent a heartbeat", func() {
+		var heartbeat health.Heartbeat
+		health.Register("worker", health.HeartbeatCheck(&heartbeat, time.Minute), 0)
+
+		code, _ := report("/readyz")
+		Expect(code).To(Equal(http.StatusServiceUnavailable))
+
+		heartbeat.Beat()
+		code, _ = report("/readyz")
+		Expect(code).To(Equal(http.StatusOK))
+	})
+
+	It("should fail readiness while shutting down", func() {
+		health.SetShuttingDown(true)
+
+		code, r := report("/readyz")
+		Expect(code).To(Equal(http.StatusServiceUnavailable))
+		Expect(r.Checks).To(HaveKey("shutdown"))
+		Expect(doRequest("GET", "/livez", nil, "").Code).To(Equal(http.StatusOK))
+	})
+})
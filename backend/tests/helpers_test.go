@@ -0,0 +1,110 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+
+	"shopease/internal/database"
+	"shopease/internal/models"
+
+	. "github.com/onsi/gomega"
+)
+
+// doRequest sends a JSON request through the router, authenticating with token if set
+func doRequest(method, path string, payload interface{}, token string) *httptest.ResponseRecorder {
+	return doRequestWithHeaders(method, path, payload, token, nil)
+}
+
+// doRequestWithHeaders is doRequest with extra request headers, such as If-Match
+func doRequestWithHeaders(method, path string, payload interface{}, token string, headers map[string]string) *httptest.ResponseRecorder {
+	var body *bytes.Buffer
+	if payload != nil {
+		raw, err := json.Marshal(payload)
+		Expect(err).NotTo(HaveOccurred())
+		body = bytes.NewBuffer(raw)
+	} else {
+		body = bytes.NewBuffer(nil)
+	}
+
+	req, _ := http.NewRequest(method, path, body)
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+// decodeData unmarshals the response envelope and returns its data field
+func decodeData(w *httptest.ResponseRecorder) map[string]interface{} {
+	var response map[string]interface{}
+	Expect(json.Unmarshal(w.Body.Bytes(), &response)).To(Succeed())
+
+	data, _ := response["data"].(map[string]interface{})
+	return data
+}
+
+// registerAndLogin creates a fresh user and returns its session token and ID
+func registerAndLogin(username string) (string, uint) {
+	w := doRequest("POST", "/api/v1/users", map[string]string{
+		"username": username,
+		"password": "password123",
+	}, "")
+	Expect(w.Code).To(Equal(http.StatusCreated), w.Body.String())
+	userID := uint(decodeData(w)["id"].(float64))
+
+	w = doRequest("POST", "/api/v1/users/login", map[string]string{
+		"username": username,
+		"password": "password123",
+	}, "")
+	Expect(w.Code).To(Equal(http.StatusOK), w.Body.String())
+
+	return decodeData(w)["token"].(string), userID
+}
+
+// registerWithRole creates a fresh user with the given role and returns its session token and ID
+func registerWithRole(username string, role models.UserRole) (string, uint) {
+	token, userID := registerAndLogin(username)
+	Expect(database.DB.Model(&models.User{}).Where("id = ?", userID).Update("role", role).Error).To(Succeed())
+	return token, userID
+}
+
+// createItem creates an item through the API as token's user and returns its ID
+func createItem(token string, body map[string]interface{}) uint {
+	w := doRequest("POST", "/api/v1/items", body, token)
+	Expect(w.Code).To(Equal(http.StatusCreated), w.Body.String())
+	return uint(decodeData(w)["id"].(float64))
+}
+
+// placeOrder adds an item to the user's cart, checks out and returns the order ID
+func placeOrder(token string, itemID uint, quantity int) uint {
+	w := doRequest("POST", "/api/v1/carts", map[string]interface{}{
+		"item_id":  itemID,
+		"quantity": quantity,
+	}, token)
+	Expect(w.Code).To(Equal(http.StatusOK), w.Body.String())
+	cartID := decodeData(w)["id"]
+
+	w = doRequest("POST", "/api/v1/orders", map[string]interface{}{
+		"cart_id": cartID,
+	}, token)
+	Expect(w.Code).To(Equal(http.StatusCreated), w.Body.String())
+
+	return uint(decodeData(w)["id"].(float64))
+}
+
+var nameCounter int
+
+// uniqueName returns a username that will not collide across specs
+func uniqueName(prefix string) string {
+	nameCounter++
+	return fmt.Sprintf("%s%d", prefix, nameCounter)
+}
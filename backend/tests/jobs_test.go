@@ -0,0 +1,179 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"shopease/internal/database"
+	"shopease/internal/jobs"
+	"shopease/internal/models"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// testJob is the payload of the job types registered by these specs
+type testJob struct {
+	Key string `json:"key"`
+}
+
+var _ = Describe("Background jobs", func() {
+	var (
+		mu      sync.Mutex
+		ran     = map[string]int{}  // Attempts seen by key
+		healthy = map[string]bool{} // Keys the flaky handler lets succeed
+	)
+
+	jobs.Register("test.record", func(ctx context.Context, payload testJob) error {
+		mu.Lock()
+		defer mu.Unlock()
+		ran[payload.Key]++
+		return nil
+	})
+	jobs.Register("test.flaky", func(ctx context.Context, payload testJob) error {
+		mu.Lock()
+		defer mu.Unlock()
+		ran[payload.Key]++
+		if !healthy[payload.Key] {
+			return errors.New("downstream unavailable")
+		}
+		return nil
+	})
+	jobs.Register("test.broken", func(ctx context.Context, payload testJob) error {
+		return jobs.Permanent(errors.New("payload can never work"))
+	})
+
+	var adminToken string
+
+	BeforeEach(func() {
+		adminToken, _ = registerWithRole(uniqueName("operator"), models.UserRoleAdmin)
+
+		jobs.Start(2)
+		DeferCleanup(jobs.Stop)
+	})
+
+	attempts := func(key string) func() int {
+		return func() int {
+			mu.Lock()
+			defer mu.Unlock()
+			return ran[key]
+		}
+	}
+
+	enqueue := func(kind, key string, opts jobs.Options) uint {
+		job, err := jobs.Enqueue(database.DB, kind, testJob{Key: key}, opts)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(job.State).To(Equal(models.JobQueued))
+		return job.ID
+	}
+
+	stateOf := func(id uint) func() models.JobState {
+		return func() models.JobState {
+			var job models.Job
+			Expect(database.DB.First(&job, id).Error).To(Succeed())
+			return job.State
+		}
+	}
+
+	It("should run a job with its decoded payload and record the outcome", func() {
+		key := uniqueName("record")
+		id := enqueue("test.record", key, jobs.Options{})
+
+		Eventually(stateOf(id), 5*time.Second).Should(Equal(models.JobSucceeded))
+		Expect(attempts(key)()).To(Equal(1))
+
+		w := doRequest("GET", fmt.Sprintf("/api/v1/admin/jobs/%d", id), nil, adminToken)
+		Expect(w.Code).To(Equal(http.StatusOK))
+		job := decodeData(w)
+		Expect(job["type"]).To(Equal("test.record"))
+		Expect(job["attempts"]).To(Equal(1.0))
+		Expect(job).NotTo(HaveKey("payload"))
+		Expect(job["finished_at"]).NotTo(BeNil())
+
+		w = doRequest("GET", "/api/v1/admin/jobs?type=test.record&state=succeeded", nil, adminToken)
+		Expect(w.Code).To(Equal(http.StatusOK))
+		Expect(w.Body.String()).To(ContainSubstring(fmt.Sprintf(`"id":%d,`, id)))
+
+		Expect(doRequest("GET", "/api/v1/admin/jobs?state=stuck", nil, adminToken).Code).To(Equal(http.StatusBadRequest))
+	})
+
+	It("should reject job types without a handler", func() {
+		_, err := jobs.Enqueue(database.DB, "test.unregistered", testJob{}, jobs.Options{})
+		Expect(errors.Is(err, jobs.ErrUnknownType)).To(BeTrue())
+	})
+
+	It("should back off between attempts, then dead-letter the job until an operator retries it", func() {
+		key := uniqueName("flaky")
+		id := enqueue("test.flaky", key, jobs.Options{MaxAttempts: 2})
+
+		// The first failure schedules another attempt after the back-off
+		Eventually(attempts(key), 5*time.Second).Should(Equal(1))
+		var job models.Job
+		Eventually(func() string {
+			Expect(database.DB.First(&job, id).Error).To(Succeed())
+			return job.LastError
+		}).Should(Equal("downstream unavailable"))
+		Expect(job.State).To(Equal(models.JobQueued))
+		Expect(job.RunAt).To(BeTemporally("~", time.Now().Add(jobs.Backoff(1)), 2*time.Second))
+		Expect(jobs.Backoff(2)).To(Equal(2 * jobs.Backoff(1)))
+
+		// Retrying a waiting job runs it now, and its second failure is its last
+		w := doRequest("POST", fmt.Sprintf("/api/v1/admin/jobs/%d/retry", id), nil, adminToken)
+		Expect(w.Code).To(Equal(http.StatusOK), w.Body.String())
+		Eventually(stateOf(id), 5*time.Second).Should(Equal(models.JobDead))
+		Expect(attempts(key)()).To(Equal(2))
+
+		Consistently(attempts(key), 1500*time.Millisecond).Should(Equal(2))
+
+		mu.Lock()
+		healthy[key] = true
+		mu.Unlock()
+
+		// A dead job gets a fresh set of attempts
+		w = doRequest("POST", fmt.Sprintf("/api/v1/admin/jobs/%d/retry", id), nil, adminToken)
+		Expect(w.Code).To(Equal(http.StatusOK), w.Body.String())
+		Expect(decodeData(w)["attempts"]).To(BeZero())
+		Eventually(stateOf(id), 5*time.Second).Should(Equal(models.JobSucceeded))
+
+		// Finished jobs can be neither retried nor cancelled
+		Expect(doRequest("POST", fmt.Sprintf("/api/v1/admin/jobs/%d/retry", id), nil, adminToken).Code).To(Equal(http.StatusConflict))
+		Expect(doRequest("POST", fmt.Sprintf("/api/v1/admin/jobs/%d/cancel", id), nil, adminToken).Code).To(Equal(http.StatusConflict))
+
+		var events int64
+		database.DB.Model(&models.AuditEvent{}).Where("action = ? AND target_type = ? AND target_id = ?", "job.retry", "job", id).Count(&events)
+		Expect(events).To(Equal(int64(2)))
+	})
+
+	It("should send permanent failures straight to the dead state", func() {
+		id := enqueue("test.broken", uniqueName("broken"), jobs.Options{})
+
+		Eventually(stateOf(id), 5*time.Second).Should(Equal(models.JobDead))
+		var job models.Job
+		Expect(database.DB.First(&job, id).Error).To(Succeed())
+		Expect(job.Attempts).To(Equal(1))
+		Expect(job.LastError).To(Equal("payload can never work"))
+	})
+
+	It("should hold scheduled jobs until they are due and let them be cancelled", func() {
+		key := uniqueName("scheduled")
+		id := enqueue("test.record", key, jobs.Options{Delay: time.Hour})
+
+		Consistently(stateOf(id), 1500*time.Millisecond).Should(Equal(models.JobQueued))
+
+		w := doRequest("POST", fmt.Sprintf("/api/v1/admin/jobs/%d/cancel", id), nil, adminToken)
+		Expect(w.Code).To(Equal(http.StatusOK), w.Body.String())
+		Expect(decodeData(w)["state"]).To(Equal(string(models.JobCancelled)))
+		Expect(attempts(key)()).To(BeZero())
+
+		Expect(doRequest("POST", "/api/v1/admin/jobs/999999/cancel", nil, adminToken).Code).To(Equal(http.StatusNotFound))
+	})
+
+	It("should restrict the jobs API to admins", func() {
+		customerToken, _ := registerAndLogin(uniqueName("customer"))
+		Expect(doRequest("GET", "/api/v1/admin/jobs", nil, customerToken).Code).To(Equal(http.StatusForbidden))
+	})
+})
@@ -0,0 +1,109 @@
+package tests
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"log"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+
+	"shopease/internal/logging"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Request logging", func() {
+	var output *bytes.Buffer
+	var previous *slog.Logger
+
+	// entries returns the JSON log lines with the given message
+	entries := func(msg string) []map[string]interface{} {
+		var found []map[string]interface{}
+		scanner := bufio.NewScanner(bytes.NewReader(output.Bytes()))
+		for scanner.Scan() {
+			var entry map[string]interface{}
+			if json.Unmarshal(scanner.Bytes(), &entry) == nil && entry["msg"] == msg {
+				found = append(found, entry)
+			}
+		}
+		return found
+	}
+
+	BeforeEach(func() {
+		previous = slog.Default()
+		output = &bytes.Buffer{}
+		_, err := logging.Setup("debug", "json", output)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		slog.SetDefault(previous)
+		log.SetOutput(os.Stderr)
+		log.SetFlags(log.LstdFlags)
+	})
+
+	It("should log one structured line per request with the request ID", func() {
+		token, userID := registerAndLogin(uniqueName("logged"))
+		output.Reset()
+
+		req := httptest.NewRequest("GET", "/api/v1/items/1", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		req.Header.Set("X-Request-ID", "trace-abc-123")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		Expect(w.Code).To(Equal(http.StatusOK))
+		Expect(w.Header().Get("X-Request-ID")).To(Equal("trace-abc-123"))
+
+		requests := entries("request")
+		Expect(requests).To(HaveLen(1))
+		entry := requests[0]
+		Expect(entry["request_id"]).To(Equal("trace-abc-123"))
+		Expect(entry["route"]).To(Equal("/api/v1/items/:id"))
+		Expect(entry["path"]).To(Equal("/api/v1/items/1"))
+		Expect(entry["status"]).To(BeEquivalentTo(200))
+		Expect(entry["bytes"]).To(BeNumerically(">", 0))
+		Expect(entry).To(HaveKey("latency_ms"))
+		Expect(entry).To(HaveKey("client_ip"))
+		Expect(entry).NotTo(HaveKey("user_id")) // Public route, no auth middleware ran
+
+		w = doRequest("GET", "/api/v1/users/me", nil, token)
+		Expect(w.Code).To(Equal(http.StatusOK))
+		requests = entries("request")
+		Expect(requests[len(requests)-1]["user_id"]).To(BeEquivalentTo(userID))
+
+		// SQL goes through the same logger at debug level
+		Expect(entries("query")).NotTo(BeEmpty())
+	})
+
+	It("should log SQL without its parameters", func() {
+		username := uniqueName("unlogged")
+		registerAndLogin(username)
+
+		queries := entries("query")
+		Expect(queries).NotTo(BeEmpty())
+		Expect(queries).To(ContainElement(HaveKeyWithValue("sql", ContainSubstring("username = ?"))))
+		Expect(output.String()).NotTo(ContainSubstring(username))
+	})
+
+	It("should replace missing or malformed request IDs", func() {
+		req := httptest.NewRequest("GET", "/health", nil)
+		req.Header.Set("X-Request-ID", "bad id\nwith newline")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		Expect(w.Header().Get("X-Request-ID")).To(MatchRegexp(`^[0-9a-f]{32}$`))
+	})
+
+	It("should log client errors as warnings", func() {
+		doRequest("GET", "/api/v1/items/999999", nil, "")
+
+		requests := entries("request")
+		Expect(requests).NotTo(BeEmpty())
+		Expect(requests[len(requests)-1]["level"]).To(Equal("WARN"))
+	})
+})
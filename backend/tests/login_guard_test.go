@@ -0,0 +1,128 @@
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"time"
+
+	"shopease/internal/config"
+	"shopease/internal/database"
+	"shopease/internal/models"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Login brute-force protection", func() {
+	var username string
+	var userID uint
+
+	login := func(password string) int {
+		return doRequest("POST", "/api/v1/users/login", map[string]string{
+			"username": username,
+			"password": password,
+		}, "").Code
+	}
+
+	// age moves the username's recorded attempts into the past, as if the back-off had elapsed
+	age := func(d time.Duration) {
+		var attempts []models.LoginAttempt
+		database.DB.Where("username = ?", username).Find(&attempts)
+		for _, attempt := range attempts {
+			database.DB.Model(&attempt).Update("created_at", attempt.CreatedAt.Add(-d))
+		}
+	}
+
+	BeforeEach(func() {
+		config.AppConfig.LoginBackoffThreshold = 2
+		config.AppConfig.LoginMaxFailures = 4
+		config.AppConfig.LoginLockoutMinutes = 15
+
+		username = uniqueName("guarded")
+		token, id := registerAndLogin(username)
+		userID = id
+		Expect(doRequest("POST", "/api/v1/users/logout", nil, token).Code).To(Equal(http.StatusOK))
+	})
+
+	AfterEach(func() {
+		config.AppConfig.LoginBackoffThreshold = 0
+		config.AppConfig.LoginMaxFailures = 0
+		config.AppConfig.LoginLockoutMinutes = 0
+		config.AppConfig.LoginIPMaxFailures = 0
+	})
+
+	It("should give unknown users and wrong passwords the same response", func() {
+		wrong := doRequest("POST", "/api/v1/users/login", map[string]string{"username": username, "password": "wrong-password"}, "")
+		unknown := doRequest("POST", "/api/v1/users/login", map[string]string{"username": uniqueName("nobody"), "password": "wrong-password"}, "")
+
+		Expect(unknown.Code).To(Equal(wrong.Code))
+		Expect(unknown.Body.String()).To(Equal(wrong.Body.String()))
+	})
+
+	It("should back off, lock out, and allow an admin to unlock", func() {
+		Expect(login("wrong-password")).To(Equal(http.StatusBadRequest))
+		Expect(login("wrong-password")).To(Equal(http.StatusBadRequest))
+
+		// Back-off applies even to the correct password
+		w := doRequest("POST", "/api/v1/users/login", map[string]string{"username": username, "password": "password123"}, "")
+		Expect(w.Code).To(Equal(http.StatusTooManyRequests))
+		Expect(w.Header().Get("Retry-After")).NotTo(BeEmpty())
+
+		age(time.Minute)
+		Expect(login("wrong-password")).To(Equal(http.StatusBadRequest))
+		age(time.Minute)
+		Expect(login("wrong-password")).To(Equal(http.StatusBadRequest))
+
+		// Locked for the full lockout period
+		age(5 * time.Minute)
+		Expect(login("password123")).To(Equal(http.StatusTooManyRequests))
+
+		adminToken, _ := registerWithRole(uniqueName("admin"), models.UserRoleAdmin)
+
+		w = doRequest("GET", "/api/v1/users/login-attempts?outcome=failure&username="+username, nil, adminToken)
+		Expect(w.Code).To(Equal(http.StatusOK))
+		var response map[string]interface{}
+		Expect(json.Unmarshal(w.Body.Bytes(), &response)).To(Succeed())
+		Expect(response["total_items"]).To(BeEquivalentTo(4))
+
+		w = doRequest("POST", fmt.Sprintf("/api/v1/users/%d/unlock", userID), nil, adminToken)
+		Expect(w.Code).To(Equal(http.StatusOK), w.Body.String())
+
+		Expect(login("password123")).To(Equal(http.StatusOK))
+	})
+
+	It("should throttle by the connecting address, ignoring forged X-Forwarded-For", func() {
+		config.AppConfig.LoginIPMaxFailures = 3
+		const remoteIP = "203.0.113.41"
+
+		// Each attempt names a different user and claims a different forwarded address
+		attempt := func(i int) int {
+			body := fmt.Sprintf(`{"username": %q, "password": "wrong-password"}`, uniqueName("sprayed"))
+			req := httptest.NewRequest("POST", "/api/v1/users/login", strings.NewReader(body))
+			req.RemoteAddr = remoteIP + ":40000"
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("X-Forwarded-For", fmt.Sprintf("198.51.100.%d", i))
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			return w.Code
+		}
+
+		for i := 1; i <= 3; i++ {
+			Expect(attempt(i)).To(Equal(http.StatusBadRequest))
+		}
+		Expect(attempt(4)).To(Equal(http.StatusTooManyRequests))
+
+		var forged int64
+		Expect(database.DB.Model(&models.LoginAttempt{}).Where("ip LIKE ?", "198.51.100.%").Count(&forged).Error).To(Succeed())
+		Expect(forged).To(BeZero())
+	})
+
+	It("should only let admins unlock users", func() {
+		token, _ := registerAndLogin(uniqueName("customer"))
+		w := doRequest("POST", fmt.Sprintf("/api/v1/users/%d/unlock", userID), nil, token)
+		Expect(w.Code).To(Equal(http.StatusForbidden))
+	})
+})
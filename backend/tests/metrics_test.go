@@ -0,0 +1,44 @@
+package tests
+
+import (
+	"net/http"
+
+	"shopease/internal/config"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Metrics", func() {
+	AfterEach(func() {
+		config.AppConfig.MetricsToken = ""
+	})
+
+	It("should expose request, business, database and runtime metrics", func() {
+		doRequest("GET", "/api/v1/items/1", nil, "")
+		doRequest("POST", "/api/v1/users/login", map[string]string{"username": uniqueName("nobody"), "password": "wrong"}, "")
+
+		w := doRequest("GET", "/metrics", nil, "")
+		Expect(w.Code).To(Equal(http.StatusOK))
+		Expect(w.Header().Get("Content-Type")).To(HavePrefix("text/plain"))
+
+		body := w.Body.String()
+		Expect(body).To(ContainSubstring(`shopease_http_requests_total{method="GET",route="/api/v1/items/:id",status="200"}`))
+		Expect(body).To(ContainSubstring(`shopease_http_request_duration_seconds_bucket{method="GET",route="/api/v1/items/:id",status="200",le="0.005"}`))
+		Expect(body).To(ContainSubstring(`shopease_login_failures_total{reason="invalid_credentials"}`))
+		Expect(body).To(ContainSubstring("shopease_orders_placed_total"))
+		Expect(body).To(ContainSubstring("shopease_cart_adds_total"))
+		Expect(body).To(ContainSubstring("go_goroutines"))
+		Expect(body).To(ContainSubstring("go_sql_open_connections"))
+	})
+
+	It("should require the metrics token when configured", func() {
+		config.AppConfig.MetricsToken = "scrape-secret"
+
+		w := doRequest("GET", "/metrics", nil, "")
+		Expect(w.Code).To(Equal(http.StatusUnauthorized))
+
+		w = doRequest("GET", "/metrics", nil, "scrape-secret")
+		Expect(w.Code).To(Equal(http.StatusOK))
+	})
+})
@@ -0,0 +1,195 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sync"
+	"time"
+
+	"shopease/internal/database"
+	"shopease/internal/events"
+	"shopease/internal/jobs"
+	"shopease/internal/models"
+	"shopease/internal/notify"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// captureMailer records sent messages for assertions
+type captureMailer struct {
+	mu   sync.Mutex
+	sent []notify.Message
+}
+
+func (m *captureMailer) Send(ctx context.Context, msg notify.Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sent = append(m.sent, msg)
+	return nil
+}
+
+func (m *captureMailer) subjectsFor(to string) []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	subjects := []string{}
+	for _, msg := range m.sent {
+		if msg.To == to {
+			subjects = append(subjects, msg.Subject)
+		}
+	}
+	return subjects
+}
+
+// tokenFor waits for an email with the given subject and extracts the 64-character code from it
+func (m *captureMailer) tokenFor(to, subject string) string {
+	var token string
+	Eventually(func() string {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		for _, msg := range m.sent {
+			if msg.To == to && msg.Subject == subject {
+				token = regexp.MustCompile(`[0-9a-f]{64}`).FindString(msg.HTMLBody)
+			}
+		}
+		return token
+	}).ShouldNot(BeEmpty())
+	return token
+}
+
+var _ = Describe("Email notifications", func() {
+	var mailer *captureMailer
+
+	BeforeEach(func() {
+		mailer = &captureMailer{}
+		notify.SetMailer(mailer)
+		jobs.Start(1)
+		events.Start()
+	})
+
+	AfterEach(func() {
+		events.Stop()
+		jobs.Stop()
+		notify.SetMailer(notify.LogMailer{})
+	})
+
+	It("should render every template", func() {
+		msg, err := notify.Render(notify.TemplateWelcome, "a@example.com", notify.WelcomeData{Username: "O'Brien"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(msg.Subject).To(Equal("Welcome to ShopEase, O'Brien!"))
+		Expect(msg.HTMLBody).To(ContainSubstring("O&#39;Brien"))
+	})
+
+	It("should send welcome and order emails", func() {
+		username := uniqueName("mailed")
+		email := username + "@example.com"
+
+		w := doRequest("POST", "/api/v1/users", map[string]string{
+			"username": username,
+			"password": "password123",
+			"email":    email,
+		}, "")
+		Expect(w.Code).To(Equal(http.StatusCreated))
+
+		w = doRequest("POST", "/api/v1/users/login", map[string]string{
+			"username": username,
+			"password": "password123",
+		}, "")
+		token := decodeData(w)["token"].(string)
+
+		orderID := placeOrder(token, 1, 1)
+		Expect(orderID).NotTo(BeZero())
+
+		Eventually(func() []string { return mailer.subjectsFor(email) }).Should(ContainElements(
+			"Welcome to ShopEase, "+username+"!",
+			MatchRegexp(`^Order #\d+ confirmed$`),
+		))
+	})
+	It("should email a cancellation once, however often its event is delivered", func() {
+		username := uniqueName("cancelled")
+		email := username + "@example.com"
+		token, userID := registerAndLogin(username)
+		Expect(database.DB.Model(&models.User{}).Where("id = ?", userID).Update("email", email).Error).To(Succeed())
+
+		orderID := placeOrder(token, 1, 1)
+		w := doRequest("POST", fmt.Sprintf("/api/v1/orders/%d/cancel", orderID), nil, token)
+		Expect(w.Code).To(Equal(http.StatusOK), w.Body.String())
+
+		subject := fmt.Sprintf("Order #%d cancelled", orderID)
+		Eventually(func() []string { return mailer.subjectsFor(email) }, 5*time.Second).Should(ContainElement(subject))
+
+		// Deliver the cancellation again, as after a crash before it was recorded
+		var event models.OutboxEvent
+		Expect(database.DB.Where("type = ? AND aggregate_id = ?", "order.cancelled", orderID).First(&event).Error).To(Succeed())
+		Expect(database.DB.Model(&event).Update("dispatched_at", nil).Error).To(Succeed())
+		Eventually(func() *time.Time {
+			Expect(database.DB.First(&event, event.ID).Error).To(Succeed())
+			return event.DispatchedAt
+		}, 5*time.Second).ShouldNot(BeNil())
+
+		var queued int64
+		key := fmt.Sprintf("event:%d", event.ID)
+		Expect(database.DB.Model(&models.Job{}).Where(&models.Job{Type: notify.MailJob, Key: &key}).Count(&queued).Error).To(Succeed())
+		Expect(queued).To(Equal(int64(1)))
+		Consistently(func() []string { return mailer.subjectsFor(email) }, time.Second).Should(HaveLen(2)) // Confirmation and cancellation
+	})
+
+	It("should keep failed deliveries queued for retry", func() {
+		notify.SetMailer(failingMailer{})
+		email := uniqueName("bounced") + "@example.com"
+		_, userID := registerAndLogin(uniqueName("bounced"))
+		Expect(database.DB.Model(&models.User{}).Where("id = ?", userID).Update("email", email).Error).To(Succeed())
+		Expect(notify.Send(database.DB, notify.Mail{Template: notify.TemplateWelcome, UserID: userID})).To(Succeed())
+
+		var job models.Job
+		Expect(database.DB.Where("type = ?", notify.MailJob).Order("id DESC").First(&job).Error).To(Succeed())
+		Expect(string(job.Payload)).To(MatchJSON(fmt.Sprintf(`{"template": "welcome", "user_id": %d}`, userID)))
+		Eventually(func() string {
+			Expect(database.DB.First(&job, job.ID).Error).To(Succeed())
+			return job.LastError
+		}, 5*time.Second).Should(Equal("smtp unavailable"))
+		Expect(job.State).To(Equal(models.JobQueued))
+		Expect(job.Attempts).To(Equal(1))
+	})
+
+	It("should queue reset mail without the address or code, and hide payloads from admins", func() {
+		jobs.Stop()
+		username := uniqueName("forgetful")
+		email := username + "@example.com"
+		w := doRequest("POST", "/api/v1/users", map[string]string{
+			"username": username,
+			"password": "password123",
+			"email":    email,
+		}, "")
+		Expect(w.Code).To(Equal(http.StatusCreated))
+
+		w = doRequest("POST", "/api/v1/users/password/forgot", map[string]string{"username": username}, "")
+		Expect(w.Code).To(Equal(http.StatusOK))
+
+		var job models.Job
+		Expect(database.DB.Where("type = ?", notify.MailJob).Order("id DESC").First(&job).Error).To(Succeed())
+		Expect(string(job.Payload)).To(ContainSubstring(`"template":"password_reset"`))
+		Expect(string(job.Payload)).NotTo(ContainSubstring(email))
+
+		adminToken, _ := registerWithRole(uniqueName("jobadmin"), models.UserRoleAdmin)
+		w = doRequest("GET", fmt.Sprintf("/api/v1/admin/jobs/%d", job.ID), nil, adminToken)
+		Expect(w.Code).To(Equal(http.StatusOK))
+		Expect(decodeData(w)).NotTo(HaveKey("payload"))
+
+		// The code is issued when the mail is delivered
+		jobs.Start(1)
+		code := mailer.tokenFor(email, "Reset your ShopEase password")
+		Expect(string(job.Payload)).NotTo(ContainSubstring(code))
+	})
+})
+
+// failingMailer fails every delivery
+type failingMailer struct{}
+
+func (failingMailer) Send(ctx context.Context, msg notify.Message) error {
+	return errors.New("smtp unavailable")
+}
@@ -0,0 +1,136 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"shopease/internal/models"
+	"shopease/internal/pricing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Item pricing", func() {
+	var (
+		staffToken string
+		itemID     uint
+	)
+
+	BeforeEach(func() {
+		staffToken, _ = registerWithRole(uniqueName("pricer"), models.UserRoleStaff)
+		itemID = createItem(staffToken, map[string]interface{}{"name": "Priced Kettle", "price": 10})
+	})
+
+	// history fetches the price history, as staff if token is set
+	history := func(token string) []models.ItemPriceResponse {
+		w := doRequest("GET", fmt.Sprintf("/api/v1/items/%d/price-history", itemID), nil, token)
+		Expect(w.Code).To(Equal(http.StatusOK), w.Body.String())
+
+		var response struct {
+			Data []models.ItemPriceResponse `json:"data"`
+		}
+		Expect(json.Unmarshal(w.Body.Bytes(), &response)).To(Succeed())
+		return response.Data
+	}
+
+	// item fetches the item as customers see it
+	item := func() models.ItemResponse {
+		w := doRequest("GET", fmt.Sprintf("/api/v1/items/%d", itemID), nil, "")
+		Expect(w.Code).To(Equal(http.StatusOK))
+
+		var response struct {
+			Data models.ItemResponse `json:"data"`
+		}
+		Expect(json.Unmarshal(w.Body.Bytes(), &response)).To(Succeed())
+		return response.Data
+	}
+
+	schedule := func(body map[string]interface{}) *httptest.ResponseRecorder {
+		return doRequest("POST", fmt.Sprintf("/api/v1/items/%d/prices", itemID), body, staffToken)
+	}
+
+	It("should keep the old price in the history when the price is updated", func() {
+		w := doRequest("PUT", fmt.Sprintf("/api/v1/items/%d", itemID), map[string]interface{}{"price": 8}, staffToken)
+		Expect(w.Code).To(Equal(http.StatusOK), w.Body.String())
+		Expect(decodeData(w)["price"]).To(Equal(8.0))
+
+		entries := history("")
+		Expect(entries).To(HaveLen(2))
+		Expect(entries[0].Price).To(Equal(8.0))
+		Expect(entries[0].Status).To(Equal("current"))
+		Expect(entries[1].Price).To(Equal(10.0))
+		Expect(entries[1].Status).To(Equal("ended"))
+		Expect(entries[1].EffectiveUntil).NotTo(BeNil())
+
+		// Unchanged prices add nothing
+		w = doRequest("PUT", fmt.Sprintf("/api/v1/items/%d", itemID), map[string]interface{}{"price": 8, "name": "Renamed Kettle"}, staffToken)
+		Expect(w.Code).To(Equal(http.StatusOK))
+		Expect(history("")).To(HaveLen(2))
+	})
+
+	It("should show a sale with its compare-at price until the sale ends", func() {
+		ends := time.Now().Add(time.Hour)
+		w := schedule(map[string]interface{}{"kind": "sale", "price": 7.5, "effective_until": ends})
+		Expect(w.Code).To(Equal(http.StatusCreated), w.Body.String())
+
+		onSale := item()
+		Expect(onSale.Price).To(Equal(7.5))
+		Expect(onSale.CompareAtPrice).NotTo(BeNil())
+		Expect(*onSale.CompareAtPrice).To(Equal(10.0))
+
+		changed, err := pricing.Activate(context.Background(), time.Now(), ends.Add(time.Minute))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(changed).To(BeNumerically(">=", 1))
+
+		after := item()
+		Expect(after.Price).To(Equal(10.0))
+		Expect(after.CompareAtPrice).To(BeNil())
+	})
+
+	It("should apply a scheduled price when it falls due and hide it from customers until then", func() {
+		starts := time.Now().Add(time.Hour)
+		w := schedule(map[string]interface{}{"price": 12, "effective_from": starts})
+		Expect(w.Code).To(Equal(http.StatusCreated), w.Body.String())
+		Expect(decodeData(w)["status"]).To(Equal("scheduled"))
+
+		Expect(item().Price).To(Equal(10.0))
+		Expect(history("")).To(HaveLen(1))
+		Expect(history(staffToken)).To(HaveLen(2))
+
+		changed, err := pricing.Activate(context.Background(), time.Now(), starts.Add(time.Minute))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(changed).To(BeNumerically(">=", 1))
+		Expect(item().Price).To(Equal(12.0))
+	})
+
+	It("should cancel only prices that have not taken effect", func() {
+		w := schedule(map[string]interface{}{"price": 15, "effective_from": time.Now().Add(24 * time.Hour)})
+		Expect(w.Code).To(Equal(http.StatusCreated), w.Body.String())
+		priceID := uint(decodeData(w)["id"].(float64))
+
+		w = doRequest("DELETE", fmt.Sprintf("/api/v1/items/%d/prices/%d", itemID, priceID), nil, staffToken)
+		Expect(w.Code).To(Equal(http.StatusOK), w.Body.String())
+
+		entries := history(staffToken)
+		Expect(entries).To(HaveLen(1))
+		Expect(entries[0].EffectiveUntil).To(BeNil()) // Reopened
+
+		w = doRequest("DELETE", fmt.Sprintf("/api/v1/items/%d/prices/%d", itemID, entries[0].ID), nil, staffToken)
+		Expect(w.Code).To(Equal(http.StatusConflict))
+	})
+
+	It("should reject invalid schedules and non-staff users", func() {
+		Expect(schedule(map[string]interface{}{"price": 9, "effective_from": time.Now().Add(-time.Hour)}).Code).To(Equal(http.StatusBadRequest))
+		Expect(schedule(map[string]interface{}{"price": 9, "effective_until": time.Now().Add(time.Hour)}).Code).To(Equal(http.StatusBadRequest))
+		Expect(schedule(map[string]interface{}{"kind": "sale", "price": 9, "compare_at_price": 8}).Code).To(Equal(http.StatusBadRequest))
+
+		customerToken, _ := registerAndLogin(uniqueName("bargain"))
+		w := doRequest("POST", fmt.Sprintf("/api/v1/items/%d/prices", itemID), map[string]interface{}{"price": 1}, customerToken)
+		Expect(w.Code).To(Equal(http.StatusForbidden))
+	})
+})
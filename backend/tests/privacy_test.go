@@ -0,0 +1,190 @@
+package tests
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"shopease/internal/audit"
+	"shopease/internal/config"
+	"shopease/internal/database"
+	"shopease/internal/models"
+	"shopease/internal/notify"
+	"shopease/internal/privacy"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Privacy requests", func() {
+	const itemID = 3
+
+	var (
+		username string
+		token    string
+		userID   uint
+	)
+
+	BeforeEach(func() {
+		username = uniqueName("subject")
+		token, userID = registerAndLogin(username)
+	})
+
+	Describe("GET /users/me/export", func() {
+		It("should export the user's data as JSON or a ZIP of JSON files", func() {
+			orderID := placeOrder(token, itemID, 2)
+			w := doRequest("POST", "/api/v1/users/favorites", map[string]interface{}{"itemId": itemID}, token)
+			Expect(w.Code).To(Equal(http.StatusOK), w.Body.String())
+
+			w = doRequest("GET", "/api/v1/users/me/export", nil, token)
+			Expect(w.Code).To(Equal(http.StatusOK), w.Body.String())
+			Expect(w.Header().Get("Content-Disposition")).To(ContainSubstring("attachment"))
+
+			var bundle privacy.Bundle
+			Expect(json.Unmarshal(w.Body.Bytes(), &bundle)).To(Succeed())
+			Expect(bundle.Profile.Username).To(Equal(username))
+			Expect(bundle.Orders).To(HaveLen(1))
+			Expect(bundle.Orders[0].ID).To(Equal(orderID))
+			Expect(bundle.Wishlists).To(HaveLen(1))
+			Expect(bundle.Wishlists[0].Items).To(HaveLen(1))
+			Expect(bundle.LoginHistory).NotTo(BeEmpty())
+
+			w = doRequest("GET", "/api/v1/users/me/export?format=zip", nil, token)
+			Expect(w.Code).To(Equal(http.StatusOK))
+			Expect(w.Header().Get("Content-Type")).To(Equal("application/zip"))
+
+			archive, err := zip.NewReader(bytes.NewReader(w.Body.Bytes()), int64(w.Body.Len()))
+			Expect(err).NotTo(HaveOccurred())
+			files := map[string][]byte{}
+			for _, file := range archive.File {
+				r, err := file.Open()
+				Expect(err).NotTo(HaveOccurred())
+				files[file.Name], err = io.ReadAll(r)
+				Expect(err).NotTo(HaveOccurred())
+				r.Close()
+			}
+			Expect(files).To(HaveKey("orders.json"))
+			Expect(files).To(HaveKey("cart.json"))
+			Expect(string(files["profile.json"])).To(ContainSubstring(username))
+
+			w = doRequest("GET", "/api/v1/users/me/export?format=xml", nil, token)
+			Expect(w.Code).To(Equal(http.StatusBadRequest))
+		})
+	})
+
+	Describe("DELETE /users/me", func() {
+		AfterEach(func() {
+			config.AppConfig.AccountPurgeDays = 0
+		})
+
+		It("should require the password", func() {
+			w := doRequest("DELETE", "/api/v1/users/me", map[string]string{"password": "wrong-password"}, token)
+			Expect(w.Code).To(Equal(http.StatusBadRequest))
+
+			w = doRequest("GET", "/api/v1/users/me", nil, token)
+			Expect(w.Code).To(Equal(http.StatusOK))
+		})
+
+		It("should anonymise the account at once and keep order financials", func() {
+			config.AppConfig.AccountPurgeDays = 30
+
+			orderID := placeOrder(token, itemID, 1)
+			Expect(database.DB.Model(&models.Order{}).Where("id = ?", orderID).Update("note", "Leave with the neighbour").Error).To(Succeed())
+			var before models.Order
+			Expect(database.DB.First(&before, orderID).Error).To(Succeed())
+			Expect(notify.Send(database.DB, notify.Mail{Template: notify.TemplateOrderShipped, UserID: userID, OrderID: orderID})).To(Succeed())
+			Expect(queuedMailFor(userID)).NotTo(BeZero())
+
+			w := doRequest("DELETE", "/api/v1/users/me", map[string]string{"password": "password123"}, token)
+			Expect(w.Code).To(Equal(http.StatusOK), w.Body.String())
+			Expect(decodeData(w)).To(HaveKey("purge_at"))
+
+			// The session and the credentials stop working, and the username is free again
+			w = doRequest("GET", "/api/v1/users/me", nil, token)
+			Expect(w.Code).To(Equal(http.StatusUnauthorized))
+			w = doRequest("POST", "/api/v1/users/login", map[string]string{"username": username, "password": "password123"}, "")
+			Expect(w.Code).To(Equal(http.StatusBadRequest))
+
+			var user models.User
+			Expect(database.DB.Unscoped().First(&user, userID).Error).To(Succeed())
+			Expect(user.DeletedAt.Valid).To(BeTrue())
+			Expect(user.Username).NotTo(Equal(username))
+			Expect(user.Email).To(BeEmpty())
+			Expect(user.PurgeAt).NotTo(BeNil())
+			Expect(*user.PurgeAt).To(BeTemporally(">", time.Now().AddDate(0, 0, 29)))
+
+			var order models.Order
+			Expect(database.DB.First(&order, orderID).Error).To(Succeed())
+			Expect(order.Note).To(BeEmpty())
+			Expect(order.TotalAmount).To(Equal(before.TotalAmount))
+			Expect(order.UserID).To(Equal(userID))
+
+			var carts int64
+			Expect(database.DB.Unscoped().Model(&models.Cart{}).Where("user_id = ?", userID).Count(&carts).Error).To(Succeed())
+			Expect(carts).To(BeZero())
+			Expect(queuedMailFor(userID)).To(BeZero())
+
+			var events int64
+			Expect(database.DB.Model(&models.AuditEvent{}).
+				Where("action = ? AND target_id = ?", audit.ActionAccountDeleted, userID).Count(&events).Error).To(Succeed())
+			Expect(events).To(BeEquivalentTo(1))
+
+			// Not purged until the grace period ends
+			_, err := privacy.Purge(context.Background(), time.Now())
+			Expect(err).NotTo(HaveOccurred())
+			Expect(database.DB.Unscoped().First(&models.User{}, userID).Error).To(Succeed())
+
+			registerAndLogin(username)
+		})
+
+		It("should purge the account and its reviews after the grace period", func() {
+			orderID := placeOrder(token, itemID, 1)
+			staffToken, _ := registerWithRole(uniqueName("dispatcher"), models.UserRoleStaff)
+			w := doRequest("PATCH", fmt.Sprintf("/api/v1/orders/%d/status", orderID), map[string]string{"status": "delivered"}, staffToken)
+			Expect(w.Code).To(Equal(http.StatusOK))
+
+			var before models.Item
+			Expect(database.DB.First(&before, itemID).Error).To(Succeed())
+			w = doRequest("POST", fmt.Sprintf("/api/v1/items/%d/reviews", itemID), map[string]interface{}{"rating": 1}, token)
+			Expect(w.Code).To(Equal(http.StatusCreated), w.Body.String())
+			reviewID := uint(decodeData(w)["id"].(float64))
+
+			w = doRequest("DELETE", "/api/v1/users/me", map[string]string{"password": "password123"}, token)
+			Expect(w.Code).To(Equal(http.StatusOK), w.Body.String())
+
+			purged, err := privacy.Purge(context.Background(), time.Now().Add(time.Minute))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(purged).To(BeNumerically(">=", 1))
+
+			Expect(database.DB.Unscoped().First(&models.User{}, userID).Error).To(HaveOccurred())
+			Expect(database.DB.Unscoped().First(&models.Review{}, reviewID).Error).To(HaveOccurred())
+			Expect(database.DB.First(&models.Order{}, orderID).Error).To(Succeed())
+
+			var after models.Item
+			Expect(database.DB.First(&after, itemID).Error).To(Succeed())
+			Expect(after.ReviewCount).To(Equal(before.ReviewCount))
+			Expect(after.RatingSum).To(Equal(before.RatingSum))
+		})
+	})
+})
+
+// queuedMailFor counts the unsent mail jobs addressed to a user
+func queuedMailFor(userID uint) int {
+	var queued []models.Job
+	Expect(database.DB.Where("type = ? AND state = ?", notify.MailJob, models.JobQueued).Find(&queued).Error).To(Succeed())
+
+	count := 0
+	for _, job := range queued {
+		var mail notify.Mail
+		Expect(json.Unmarshal(job.Payload, &mail)).To(Succeed())
+		if mail.UserID == userID {
+			count++
+		}
+	}
+	return count
+}
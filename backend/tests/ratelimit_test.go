@@ -0,0 +1,141 @@
+package tests
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"time"
+
+	"shopease/internal/config"
+	"shopease/internal/middleware"
+	"shopease/internal/ratelimit"
+
+	"github.com/gin-gonic/gin"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Rate limiting", func() {
+	BeforeEach(func() {
+		config.AppConfig.RateLimitEnabled = true
+		// A stopped clock keeps buckets from refilling while the specs run
+		now := time.Now()
+		middleware.SetRateLimitStore(ratelimit.NewMemoryStoreWithClock(func() time.Time { return now }))
+	})
+
+	AfterEach(func() {
+		config.AppConfig.RateLimitEnabled = false
+		middleware.SetRateLimitStore(ratelimit.NewMemoryStore())
+	})
+
+	It("should apply the strict login policy and report it in headers", func() {
+		credentials := map[string]string{"username": uniqueName("nobody"), "password": "password123"}
+
+		var w *httptest.ResponseRecorder
+		for i := 0; i < middleware.LoginRateLimit.Requests; i++ {
+			w = doRequest("POST", "/api/v1/users/login", credentials, "")
+			Expect(w.Code).To(Equal(http.StatusBadRequest))
+		}
+		Expect(w.Header().Get("RateLimit-Limit")).To(Equal("10"))
+		Expect(w.Header().Get("RateLimit-Remaining")).To(Equal("0"))
+		Expect(w.Header().Get("RateLimit-Policy")).To(Equal("10;w=60"))
+
+		w = doRequest("POST", "/api/v1/users/login", credentials, "")
+		Expect(w.Code).To(Equal(http.StatusTooManyRequests))
+		Expect(w.Header().Get("Retry-After")).To(Equal("6"))
+
+		// Other routes have their own budgets
+		w = doRequest("GET", "/api/v1/items", nil, "")
+		Expect(w.Code).To(Equal(http.StatusOK))
+		Expect(w.Header().Get("RateLimit-Limit")).To(Equal("600"))
+	})
+
+	It("should only give configured API keys their own budget", func() {
+		config.AppConfig.APIKeys = "partner-key, other-key"
+		defer func() { config.AppConfig.APIKeys = "" }()
+
+		keyFor := func(apiKey string) string {
+			c, _ := gin.CreateTestContext(httptest.NewRecorder())
+			c.Request = httptest.NewRequest("GET", "/api/v1/items", nil)
+			c.Request.Header.Set(middleware.APIKeyHeader, apiKey)
+			return middleware.KeyByAPIKey(c)
+		}
+
+		Expect(keyFor("partner-key")).To(HavePrefix("key:"))
+		Expect(keyFor("other-key")).NotTo(Equal(keyFor("partner-key")))
+		// Made-up keys share the caller's IP budget
+		Expect(keyFor("made-up")).To(Equal("ip:192.0.2.1"))
+		Expect(keyFor("")).To(Equal("ip:192.0.2.1"))
+	})
+
+	It("should not limit anything when disabled", func() {
+		config.AppConfig.RateLimitEnabled = false
+
+		w := doRequest("GET", "/api/v1/items", nil, "")
+		Expect(w.Code).To(Equal(http.StatusOK))
+		Expect(w.Header().Get("RateLimit-Limit")).To(BeEmpty())
+	})
+
+	Describe("MemoryStore", func() {
+		policy := ratelimit.Policy{Name: "test", Requests: 2, Period: time.Second}
+
+		var (
+			now   time.Time
+			store *ratelimit.MemoryStore
+		)
+
+		BeforeEach(func() {
+			now = time.Now()
+			store = ratelimit.NewMemoryStoreWithClock(func() time.Time { return now })
+		})
+
+		It("should refill tokens over time", func() {
+			Expect(store.Take("a", policy)).To(HaveField("Allowed", true))
+			Expect(store.Take("a", policy)).To(HaveField("Allowed", true))
+
+			result, err := store.Take("a", policy)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.Allowed).To(BeFalse())
+			Expect(result.RetryAfter).To(Equal(500 * time.Millisecond))
+
+			// Keys are independent
+			Expect(store.Take("b", policy)).To(HaveField("Allowed", true))
+
+			now = now.Add(500 * time.Millisecond)
+			Expect(store.Take("a", policy)).To(HaveField("Allowed", true))
+		})
+
+		It("should evict idle keys", func() {
+			store.Take("a", policy)
+			store.Take("b", policy)
+			Expect(store.Len()).To(Equal(2))
+
+			now = now.Add(2 * time.Minute)
+			store.Take("c", policy)
+			Expect(store.Len()).To(Equal(1))
+		})
+
+		It("should be safe for concurrent use", func() {
+			bulk := ratelimit.Policy{Name: "bulk", Requests: 100, Period: time.Hour}
+
+			var wg sync.WaitGroup
+			var mu sync.Mutex
+			allowed := 0
+			for i := 0; i < 200; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					result, _ := store.Take("shared", bulk)
+					if result.Allowed {
+						mu.Lock()
+						allowed++
+						mu.Unlock()
+					}
+				}()
+			}
+			wg.Wait()
+
+			Expect(allowed).To(Equal(100))
+		})
+	})
+})
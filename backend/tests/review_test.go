@@ -0,0 +1,97 @@
+package tests
+
+import (
+	"fmt"
+	"net/http"
+
+	"shopease/internal/database"
+	"shopease/internal/models"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Review API", func() {
+	const itemID = 2
+
+	var buyerToken, clerkToken string
+
+	BeforeEach(func() {
+		var buyerID uint
+		buyerToken, buyerID = registerAndLogin(uniqueName("buyer"))
+		Expect(buyerID).NotTo(BeZero())
+		clerkToken, _ = registerWithRole(uniqueName("clerk"), models.UserRoleStaff)
+	})
+
+	deliver := func(orderID uint) {
+		w := doRequest("PATCH", fmt.Sprintf("/api/v1/orders/%d/status", orderID), map[string]string{
+			"status": "delivered",
+		}, clerkToken)
+		Expect(w.Code).To(Equal(http.StatusOK))
+	}
+
+	Describe("POST /items/:id/reviews", func() {
+		It("should reject users who have not received the item", func() {
+			w := doRequest("POST", fmt.Sprintf("/api/v1/items/%d/reviews", itemID), map[string]interface{}{
+				"rating": 5,
+			}, buyerToken)
+
+			Expect(w.Code).To(Equal(http.StatusForbidden))
+		})
+
+		It("should accept one review from a verified buyer and update the item rating", func() {
+			var before models.Item
+			Expect(database.DB.First(&before, itemID).Error).To(Succeed())
+
+			deliver(placeOrder(buyerToken, itemID, 1))
+
+			w := doRequest("POST", fmt.Sprintf("/api/v1/items/%d/reviews", itemID), map[string]interface{}{
+				"rating": 4,
+				"title":  "Solid",
+			}, buyerToken)
+			Expect(w.Code).To(Equal(http.StatusCreated), w.Body.String())
+
+			var after models.Item
+			Expect(database.DB.First(&after, itemID).Error).To(Succeed())
+			Expect(after.ReviewCount).To(Equal(before.ReviewCount + 1))
+			Expect(after.RatingCount4).To(Equal(before.RatingCount4 + 1))
+
+			w = doRequest("POST", fmt.Sprintf("/api/v1/items/%d/reviews", itemID), map[string]interface{}{
+				"rating": 1,
+			}, buyerToken)
+			Expect(w.Code).To(Equal(http.StatusConflict))
+		})
+	})
+
+	Describe("PATCH /reviews/:id/status", func() {
+		It("should require staff and remove rejected reviews from the aggregates", func() {
+			deliver(placeOrder(buyerToken, itemID, 1))
+
+			w := doRequest("POST", fmt.Sprintf("/api/v1/items/%d/reviews", itemID), map[string]interface{}{
+				"rating": 2,
+			}, buyerToken)
+			Expect(w.Code).To(Equal(http.StatusCreated))
+			reviewID := uint(decodeData(w)["id"].(float64))
+
+			w = doRequest("PATCH", fmt.Sprintf("/api/v1/reviews/%d/status", reviewID), map[string]string{
+				"status": "rejected",
+			}, buyerToken)
+			Expect(w.Code).To(Equal(http.StatusForbidden))
+
+			staffToken, _ := registerWithRole(uniqueName("staff"), models.UserRoleStaff)
+
+			var before models.Item
+			Expect(database.DB.First(&before, itemID).Error).To(Succeed())
+
+			w = doRequest("PATCH", fmt.Sprintf("/api/v1/reviews/%d/status", reviewID), map[string]string{
+				"status": "rejected",
+			}, staffToken)
+			Expect(w.Code).To(Equal(http.StatusOK), w.Body.String())
+
+			var after models.Item
+			Expect(database.DB.First(&after, itemID).Error).To(Succeed())
+			Expect(after.ReviewCount).To(Equal(before.ReviewCount - 1))
+			Expect(after.RatingCount2).To(Equal(before.RatingCount2 - 1))
+		})
+	})
+})
@@ -0,0 +1,178 @@
+package tests
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"shopease/internal/database"
+	"shopease/internal/models"
+	"shopease/internal/seed"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Seeding", func() {
+	ctx := context.Background()
+
+	// writeFixture saves a fixture, substituting the given placeholders
+	writeFixture := func(name, body string, replacements ...string) string {
+		path := filepath.Join(GinkgoT().TempDir(), name)
+		Expect(os.WriteFile(path, []byte(strings.NewReplacer(replacements...).Replace(body)), 0o600)).To(Succeed())
+		return path
+	}
+
+	It("should re-apply a profile without duplicating anything", func() {
+		var before int64
+		Expect(database.DB.Model(&models.Item{}).Count(&before).Error).To(Succeed())
+
+		result, err := seed.ApplyProfile(ctx, "test")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(*result).To(Equal(seed.Result{ItemsUpdated: 20}))
+
+		var after int64
+		Expect(database.DB.Model(&models.Item{}).Count(&after).Error).To(Succeed())
+		Expect(after).To(Equal(before))
+
+		_, err = seed.ApplyProfile(ctx, "staging")
+		Expect(err).To(MatchError(ContainSubstring("unknown seed profile")))
+	})
+
+	It("should upsert users, items and orders from fixture files", func() {
+		username, sku := uniqueName("seeded"), uniqueName("SEED-")
+		fixture := `
+version: 1
+categories: [Garden]
+users:
+  - username: USER
+    email: USER@example.com
+    password: garden123
+    role: ROLE
+items:
+  - sku: SKU
+    name: Watering Can
+    price: PRICE
+    category: Garden
+orders:
+  - ref: REF
+    user: USER
+    status: delivered
+    days_ago: 10
+    items:
+      - sku: SKU
+        quantity: 3
+`
+		replacements := []string{"USER", username, "SKU", sku, "REF", "ref-" + sku}
+
+		loaded, err := seed.LoadFile(writeFixture("garden.yaml", fixture, append(replacements, "ROLE", "customer", "PRICE", "10")...))
+		Expect(err).NotTo(HaveOccurred())
+		result, err := seed.Apply(ctx, loaded)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(*result).To(Equal(seed.Result{UsersCreated: 1, ItemsCreated: 1, OrdersCreated: 1}))
+
+		w := doRequest("POST", "/api/v1/users/login", map[string]string{"username": username, "password": "garden123"}, "")
+		Expect(w.Code).To(Equal(http.StatusOK))
+
+		var order models.Order
+		Expect(database.DB.Preload("OrderItems").Joins("User").Where("User.username = ?", username).First(&order).Error).To(Succeed())
+		Expect(order.Status).To(Equal(models.OrderStatusDelivered))
+		Expect(order.TotalAmount).To(Equal(30.0))
+		Expect(order.OrderItems).To(HaveLen(1))
+
+		// Re-applying updates users and items in place and leaves orders alone
+		loaded, err = seed.LoadFile(writeFixture("garden.yaml", fixture, append(replacements, "ROLE", "staff", "PRICE", "12")...))
+		Expect(err).NotTo(HaveOccurred())
+		result, err = seed.Apply(ctx, loaded)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(*result).To(Equal(seed.Result{UsersUpdated: 1, ItemsUpdated: 1}))
+
+		var user models.User
+		Expect(database.DB.Where("username = ?", username).First(&user).Error).To(Succeed())
+		Expect(user.Role).To(Equal(models.UserRoleStaff))
+		Expect(user.CheckPassword("garden123")).To(BeTrue())
+
+		var item models.Item
+		Expect(database.DB.Where("sku = ?", sku).First(&item).Error).To(Succeed())
+		Expect(item.Price).To(Equal(12.0))
+
+		var orders int64
+		Expect(database.DB.Model(&models.Order{}).Where("user_id = ?", user.ID).Count(&orders).Error).To(Succeed())
+		Expect(orders).To(BeEquivalentTo(1))
+	})
+
+	It("should accept JSON fixtures", func() {
+		sku := uniqueName("JSON-")
+		loaded, err := seed.LoadFile(writeFixture("items.json", `{"version": 1, "items": [{"sku": "SKU", "name": "Json Mug", "price": 4.5, "is_active": false}]}`, "SKU", sku))
+		Expect(err).NotTo(HaveOccurred())
+		_, err = seed.Apply(ctx, loaded)
+		Expect(err).NotTo(HaveOccurred())
+
+		var item models.Item
+		Expect(database.DB.Where("sku = ?", sku).First(&item).Error).To(Succeed())
+		Expect(item.IsActive).To(BeFalse())
+	})
+
+	It("should reject invalid fixtures and apply nothing", func() {
+		_, err := seed.Parse([]byte("version: 1\nitems:\n  - sku: X\n    name: X\n    colour: red\n"))
+		Expect(err).To(MatchError(ContainSubstring("field colour not found")))
+		_, err = seed.Parse([]byte("version: 99\n"))
+		Expect(err).To(MatchError(ContainSubstring("newer than this build supports")))
+		_, err = seed.Parse([]byte("items: []\n"))
+		Expect(err).To(MatchError("missing version"))
+
+		undeclared, err := seed.Parse([]byte("version: 1\ncategories: [Home]\nitems:\n  - sku: X\n    name: X\n    category: Garden\n"))
+		Expect(err).NotTo(HaveOccurred())
+		_, err = seed.Apply(ctx, undeclared)
+		Expect(err).To(MatchError(ContainSubstring(`category "Garden" is not declared`)))
+
+		username := uniqueName("orphan")
+		broken, err := seed.Parse([]byte(strings.ReplaceAll(`
+version: 1
+users:
+  - username: USER
+    password: password123
+orders:
+  - ref: ref-USER
+    user: USER
+    items:
+      - sku: NO-SUCH-SKU
+        quantity: 1
+`, "USER", username)))
+		Expect(err).NotTo(HaveOccurred())
+		_, err = seed.Apply(ctx, broken)
+		Expect(err).To(MatchError(ContainSubstring(`unknown item "NO-SUCH-SKU"`)))
+
+		var count int64
+		Expect(database.DB.Model(&models.User{}).Where("username = ?", username).Count(&count).Error).To(Succeed())
+		Expect(count).To(BeZero())
+	})
+
+	It("should generate the same fake data for the same seed", func() {
+		opts := seed.FakeOptions{Seed: 42, Users: 3, Items: 5, Orders: 8}
+		first, err := seed.Fake(opts)
+		Expect(err).NotTo(HaveOccurred())
+		second, err := seed.Fake(opts)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(second.Items).To(Equal(first.Items))
+		Expect(second.Orders).To(Equal(first.Orders))
+
+		opts.Seed = 43
+		other, err := seed.Fake(opts)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(other.Items).NotTo(Equal(first.Items))
+
+		_, err = seed.Fake(seed.FakeOptions{Orders: 1})
+		Expect(err).To(HaveOccurred())
+
+		result, err := seed.Apply(ctx, first)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result.UsersCreated + result.UsersUpdated).To(Equal(3))
+		Expect(result.ItemsCreated + result.ItemsUpdated).To(Equal(5))
+
+		w := doRequest("POST", "/api/v1/users/login", map[string]string{"username": first.Users[0].Username, "password": seed.FakePassword}, "")
+		Expect(w.Code).To(Equal(http.StatusOK))
+	})
+})
@@ -0,0 +1,103 @@
+package tests
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"time"
+
+	"shopease/internal/config"
+	"shopease/internal/server"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("HTTP server", func() {
+	var ln net.Listener
+	var started chan struct{}
+
+	// serve runs a server whose handler takes the given time, returning Serve's result channel
+	serve := func(ctx context.Context, work, drain time.Duration) <-chan error {
+		started = make(chan struct{}, 1)
+		handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			started <- struct{}{}
+			select {
+			case <-time.After(work):
+				io.WriteString(w, "done")
+			case <-r.Context().Done():
+			}
+		})
+
+		var err error
+		ln, err = net.Listen("tcp", "127.0.0.1:0")
+		Expect(err).NotTo(HaveOccurred())
+
+		result := make(chan error, 1)
+		go func() {
+			result <- server.Serve(ctx, server.New(ln.Addr().String(), handler), ln, drain)
+		}()
+		return result
+	}
+
+	AfterEach(func() {
+		config.AppConfig.HTTPReadTimeout = 0
+		config.AppConfig.HTTPWriteTimeout = 0
+	})
+
+	It("should apply the configured timeouts", func() {
+		config.AppConfig.HTTPReadTimeout = 15
+		config.AppConfig.HTTPWriteTimeout = 30
+
+		srv := server.New(":0", router)
+		Expect(srv.ReadTimeout).To(Equal(15 * time.Second))
+		Expect(srv.WriteTimeout).To(Equal(30 * time.Second))
+		Expect(srv.IdleTimeout).To(BeZero())
+	})
+
+	It("should let in-flight requests finish before returning", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		result := serve(ctx, 300*time.Millisecond, 5*time.Second)
+
+		body := make(chan string, 1)
+		go func() {
+			defer GinkgoRecover()
+			resp, err := http.Get("http://" + ln.Addr().String())
+			Expect(err).NotTo(HaveOccurred())
+			defer resp.Body.Close()
+			b, _ := io.ReadAll(resp.Body)
+			body <- string(b)
+		}()
+
+		Eventually(started).Should(Receive())
+		cancel()
+
+		Consistently(result, 100*time.Millisecond).ShouldNot(Receive())
+		Eventually(body).Should(Receive(Equal("done")))
+		Eventually(result).Should(Receive(BeNil()))
+
+		// The listener no longer accepts connections
+		_, err := http.Get("http://" + ln.Addr().String())
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should cut off requests that outlive the drain deadline", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		result := serve(ctx, time.Minute, 100*time.Millisecond)
+
+		go func() {
+			resp, err := http.Get("http://" + ln.Addr().String())
+			if err == nil {
+				resp.Body.Close()
+			}
+		}()
+
+		Eventually(started).Should(Receive())
+		cancel()
+
+		var err error
+		Eventually(result, 2*time.Second).Should(Receive(&err))
+		Expect(err).To(MatchError(context.DeadlineExceeded))
+	})
+})
@@ -0,0 +1,95 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+
+	"shopease/internal/tracing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var _ = Describe("Tracing", func() {
+	var recorder *tracetest.SpanRecorder
+	var previous trace.TracerProvider
+
+	// attributeOf returns the value of key on span, or nil
+	attributeOf := func(span sdktrace.ReadOnlySpan, key attribute.Key) interface{} {
+		for _, kv := range span.Attributes() {
+			if kv.Key == key {
+				return kv.Value.AsInterface()
+			}
+		}
+		return nil
+	}
+
+	BeforeEach(func() {
+		_, err := tracing.Setup(context.Background(), tracing.Options{Exporter: tracing.ExporterNone})
+		Expect(err).NotTo(HaveOccurred())
+
+		previous = otel.GetTracerProvider()
+		recorder = tracetest.NewSpanRecorder()
+		otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	})
+
+	AfterEach(func() {
+		otel.SetTracerProvider(previous)
+	})
+
+	It("should continue incoming traces and trace checkout queries", func() {
+		token, userID := registerAndLogin(uniqueName("traced"))
+		w := doRequest("POST", "/api/v1/carts", map[string]interface{}{"item_id": 1, "quantity": 1}, token)
+		Expect(w.Code).To(Equal(http.StatusOK))
+		cartID := decodeData(w)["id"]
+
+		const traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+		body, _ := json.Marshal(map[string]interface{}{"cart_id": cartID})
+		req := httptest.NewRequest("POST", "/api/v1/orders", bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+token)
+		req.Header.Set("traceparent", "00-"+traceID+"-00f067aa0ba902b7-01")
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		Expect(w.Code).To(Equal(http.StatusCreated), w.Body.String())
+		orderID := decodeData(w)["id"]
+
+		var server sdktrace.ReadOnlySpan
+		var queries, transactions int
+		for _, span := range recorder.Ended() {
+			if span.SpanContext().TraceID().String() != traceID {
+				continue
+			}
+			switch {
+			case span.Name() == "POST /api/v1/orders":
+				server = span
+			case span.Name() == "checkout transaction":
+				transactions++
+			case span.SpanKind() == trace.SpanKindClient:
+				queries++
+			}
+		}
+
+		Expect(server).NotTo(BeNil())
+		Expect(server.Parent().SpanID().String()).To(Equal("00f067aa0ba902b7"))
+		Expect(attributeOf(server, "http.route")).To(Equal("/api/v1/orders"))
+		Expect(attributeOf(server, "http.response.status_code")).To(BeEquivalentTo(http.StatusCreated))
+		Expect(attributeOf(server, "enduser.id")).To(BeEquivalentTo(userID))
+		Expect(attributeOf(server, "order.id")).To(BeEquivalentTo(orderID))
+		Expect(transactions).To(Equal(1))
+		Expect(queries).To(BeNumerically(">=", 3))
+	})
+
+	It("should reject unknown exporters", func() {
+		_, err := tracing.Setup(context.Background(), tracing.Options{Exporter: "carrier-pigeon"})
+		Expect(err).To(HaveOccurred())
+	})
+})
@@ -0,0 +1,146 @@
+package tests
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"shopease/internal/config"
+	"shopease/internal/database"
+	"shopease/internal/models"
+	"shopease/internal/utils"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Two-factor authentication", func() {
+	var username, token, secret string
+	var userID uint
+	var recoveryCodes []interface{}
+
+	// totpCode returns the code for a time step offset from now, so each login uses a fresh step
+	totpCode := func(steps int) string {
+		code, err := utils.GenerateTOTPCode(secret, time.Now().Add(time.Duration(steps)*30*time.Second))
+		Expect(err).NotTo(HaveOccurred())
+		return code
+	}
+
+	login := func() map[string]interface{} {
+		w := doRequest("POST", "/api/v1/users/login", map[string]string{
+			"username": username,
+			"password": "password123",
+		}, "")
+		Expect(w.Code).To(Equal(http.StatusOK), w.Body.String())
+		return decodeData(w)
+	}
+
+	BeforeEach(func() {
+		username = uniqueName("twofactor")
+		token, userID = registerAndLogin(username)
+
+		w := doRequest("POST", "/api/v1/users/2fa/setup", nil, token)
+		Expect(w.Code).To(Equal(http.StatusOK), w.Body.String())
+		setup := decodeData(w)
+		secret = setup["secret"].(string)
+		Expect(setup["otpauth_uri"]).To(HavePrefix("otpauth://totp/"))
+
+		w = doRequest("POST", "/api/v1/users/2fa/confirm", map[string]string{"code": totpCode(-1)}, token)
+		Expect(w.Code).To(Equal(http.StatusOK), w.Body.String())
+		recoveryCodes = decodeData(w)["recovery_codes"].([]interface{})
+		Expect(recoveryCodes).To(HaveLen(models.RecoveryCodeCount))
+
+		w = doRequest("POST", "/api/v1/users/logout", nil, token)
+		Expect(w.Code).To(Equal(http.StatusOK))
+	})
+
+	AfterEach(func() {
+		config.AppConfig.RequireStaff2FA = false
+	})
+
+	It("should require a second step before issuing a session", func() {
+		data := login()
+		Expect(data["two_factor_required"]).To(BeTrue())
+		Expect(data).NotTo(HaveKey("token"))
+		challenge := data["challenge_token"].(string)
+
+		// The challenge token is not a session token
+		w := doRequest("GET", "/api/v1/users/me", nil, challenge)
+		Expect(w.Code).To(Equal(http.StatusUnauthorized))
+
+		w = doRequest("POST", "/api/v1/users/login/2fa", map[string]string{
+			"challenge_token": challenge,
+			"code":            "000000",
+		}, "")
+		Expect(w.Code).To(Equal(http.StatusBadRequest))
+
+		w = doRequest("POST", "/api/v1/users/login/2fa", map[string]string{
+			"challenge_token": challenge,
+			"code":            totpCode(0),
+		}, "")
+		Expect(w.Code).To(Equal(http.StatusOK), w.Body.String())
+		session := decodeData(w)["token"].(string)
+
+		w = doRequest("GET", "/api/v1/users/me", nil, session)
+		Expect(w.Code).To(Equal(http.StatusOK))
+		Expect(decodeData(w)["two_factor_enabled"]).To(BeTrue())
+
+		// The same code cannot be replayed
+		doRequest("POST", "/api/v1/users/logout", nil, session)
+		w = doRequest("POST", "/api/v1/users/login/2fa", map[string]string{
+			"challenge_token": login()["challenge_token"].(string),
+			"code":            totpCode(0),
+		}, "")
+		Expect(w.Code).To(Equal(http.StatusBadRequest))
+	})
+
+	It("should accept each recovery code exactly once", func() {
+		recovery := recoveryCodes[0].(string)
+
+		w := doRequest("POST", "/api/v1/users/login/2fa", map[string]string{
+			"challenge_token": login()["challenge_token"].(string),
+			"recovery_code":   recovery,
+		}, "")
+		Expect(w.Code).To(Equal(http.StatusOK), w.Body.String())
+		doRequest("POST", "/api/v1/users/logout", nil, decodeData(w)["token"].(string))
+
+		w = doRequest("POST", "/api/v1/users/login/2fa", map[string]string{
+			"challenge_token": login()["challenge_token"].(string),
+			"recovery_code":   recovery,
+		}, "")
+		Expect(w.Code).To(Equal(http.StatusBadRequest))
+
+		var stored []models.RecoveryCode
+		database.DB.Where("user_id = ?", userID).Find(&stored)
+		for _, code := range stored {
+			Expect(code.CodeHash).NotTo(ContainSubstring(recovery))
+		}
+	})
+
+	It("should block staff routes for staff without 2FA when required", func() {
+		config.AppConfig.RequireStaff2FA = true
+
+		staffToken, _ := registerWithRole(uniqueName("staff"), models.UserRoleStaff)
+
+		w := doRequest("GET", "/api/v1/reviews", nil, staffToken)
+		Expect(w.Code).To(Equal(http.StatusForbidden))
+
+		customerToken, _ := registerAndLogin(uniqueName("customer"))
+		orderPath := fmt.Sprintf("/api/v1/orders/%d/status", placeOrder(customerToken, 1, 1))
+		w = doRequest("PATCH", orderPath, map[string]string{"status": "shipped"}, staffToken)
+		Expect(w.Code).To(Equal(http.StatusForbidden))
+
+		Expect(database.DB.Model(&models.User{}).Where("id = ?", userID).Update("role", models.UserRoleStaff).Error).To(Succeed())
+		w = doRequest("POST", "/api/v1/users/login/2fa", map[string]string{
+			"challenge_token": login()["challenge_token"].(string),
+			"code":            totpCode(1),
+		}, "")
+		Expect(w.Code).To(Equal(http.StatusOK), w.Body.String())
+
+		enrolledToken := decodeData(w)["token"].(string)
+		w = doRequest("GET", "/api/v1/reviews", nil, enrolledToken)
+		Expect(w.Code).To(Equal(http.StatusOK))
+		w = doRequest("PATCH", orderPath, map[string]string{"status": "shipped"}, enrolledToken)
+		Expect(w.Code).To(Equal(http.StatusOK), w.Body.String())
+	})
+})
@@ -0,0 +1,127 @@
+package tests
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+
+	"shopease/internal/database"
+	"shopease/internal/models"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Wishlist API", func() {
+	var token string
+
+	BeforeEach(func() {
+		token, _ = registerAndLogin(uniqueName("wisher"))
+	})
+
+	Describe("GET /users/favorites", func() {
+		It("should expose the default wishlist", func() {
+			w := doRequest("POST", "/api/v1/users/favorites", map[string]interface{}{"itemId": 3}, token)
+			Expect(w.Code).To(Equal(http.StatusOK))
+
+			w = doRequest("GET", "/api/v1/wishlists", nil, token)
+			Expect(w.Code).To(Equal(http.StatusOK))
+			Expect(w.Body.String()).To(ContainSubstring(`"is_default":true`))
+			Expect(w.Body.String()).To(ContainSubstring(`"item_id":3`))
+
+			w = doRequest("GET", "/api/v1/users/favorites", nil, token)
+			Expect(w.Code).To(Equal(http.StatusOK))
+			Expect(w.Body.String()).To(ContainSubstring(`"id":3`))
+		})
+	})
+
+	Describe("GET /wishlists", func() {
+		It("should create one default list for concurrent first requests", func() {
+			token, userID := registerAndLogin(uniqueName("eager"))
+
+			var wg sync.WaitGroup
+			for i := 0; i < 4; i++ {
+				wg.Add(1)
+				go func() {
+					defer GinkgoRecover()
+					defer wg.Done()
+					Expect(doRequest("GET", "/api/v1/wishlists", nil, token).Code).To(Equal(http.StatusOK))
+				}()
+			}
+			wg.Wait()
+
+			var defaults int64
+			Expect(database.DB.Model(&models.Wishlist{}).Where("user_id = ? AND is_default = ?", userID, true).Count(&defaults).Error).To(Succeed())
+			Expect(defaults).To(Equal(int64(1)))
+
+			// The database itself refuses a second default
+			extra := models.Wishlist{UserID: userID, Name: "Second", IsDefault: true}
+			Expect(database.DB.Create(&extra).Error).To(HaveOccurred())
+		})
+	})
+
+	Describe("moving items between lists", func() {
+		It("should move an item and keep the source list ordered", func() {
+			w := doRequest("POST", "/api/v1/wishlists", map[string]string{"name": "Birthday"}, token)
+			Expect(w.Code).To(Equal(http.StatusCreated))
+			birthdayID := uint(decodeData(w)["id"].(float64))
+
+			w = doRequest("POST", "/api/v1/wishlists", map[string]string{"name": "Office"}, token)
+			Expect(w.Code).To(Equal(http.StatusCreated))
+			officeID := uint(decodeData(w)["id"].(float64))
+
+			for _, itemID := range []int{4, 5, 6} {
+				w = doRequest("POST", fmt.Sprintf("/api/v1/wishlists/%d/items", birthdayID), map[string]interface{}{"item_id": itemID}, token)
+				Expect(w.Code).To(Equal(http.StatusOK))
+			}
+
+			w = doRequest("POST", fmt.Sprintf("/api/v1/wishlists/%d/items/4/move", birthdayID), map[string]interface{}{
+				"target_wishlist_id": officeID,
+			}, token)
+			Expect(w.Code).To(Equal(http.StatusOK), w.Body.String())
+
+			w = doRequest("GET", fmt.Sprintf("/api/v1/wishlists/%d", birthdayID), nil, token)
+			items := decodeData(w)["items"].([]interface{})
+			Expect(items).To(HaveLen(2))
+			Expect(items[0].(map[string]interface{})["item_id"]).To(BeEquivalentTo(5))
+			Expect(items[0].(map[string]interface{})["position"]).To(BeEquivalentTo(0))
+		})
+	})
+
+	Describe("POST /wishlists/:id/share", func() {
+		It("should give public access until revoked", func() {
+			w := doRequest("POST", "/api/v1/wishlists", map[string]string{"name": "Public"}, token)
+			wishlistID := uint(decodeData(w)["id"].(float64))
+
+			w = doRequest("POST", fmt.Sprintf("/api/v1/wishlists/%d/share", wishlistID), nil, token)
+			Expect(w.Code).To(Equal(http.StatusOK))
+			shareToken := decodeData(w)["share_token"].(string)
+
+			w = doRequest("GET", "/api/v1/wishlists/shared/"+shareToken, nil, "")
+			Expect(w.Code).To(Equal(http.StatusOK))
+
+			w = doRequest("DELETE", fmt.Sprintf("/api/v1/wishlists/%d/share", wishlistID), nil, token)
+			Expect(w.Code).To(Equal(http.StatusOK))
+
+			w = doRequest("GET", "/api/v1/wishlists/shared/"+shareToken, nil, "")
+			Expect(w.Code).To(Equal(http.StatusNotFound))
+		})
+	})
+
+	Describe("POST /wishlists/:id/cart", func() {
+		It("should move every item into the cart", func() {
+			w := doRequest("POST", "/api/v1/wishlists", map[string]string{"name": "Checkout"}, token)
+			wishlistID := uint(decodeData(w)["id"].(float64))
+
+			doRequest("POST", fmt.Sprintf("/api/v1/wishlists/%d/items", wishlistID), map[string]interface{}{"item_id": 7}, token)
+			doRequest("POST", fmt.Sprintf("/api/v1/wishlists/%d/items", wishlistID), map[string]interface{}{"item_id": 8}, token)
+
+			w = doRequest("POST", fmt.Sprintf("/api/v1/wishlists/%d/cart", wishlistID), nil, token)
+			Expect(w.Code).To(Equal(http.StatusOK), w.Body.String())
+			Expect(decodeData(w)["moved_item_ids"]).To(HaveLen(2))
+
+			w = doRequest("GET", "/api/v1/carts/my", nil, token)
+			Expect(decodeData(w)["items"]).To(HaveLen(2))
+		})
+	})
+})